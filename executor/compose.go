@@ -0,0 +1,371 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/palantir/stacktrace"
+
+	"github.com/sumup-oss/go-pkgs/os"
+)
+
+// ComposeOptions select the compose project all Compose commands operate on.
+type ComposeOptions struct {
+	// ProjectName overrides the project name, which defaults to the name of the project directory.
+	ProjectName string
+	// ProjectDir is the directory relative paths of the compose files are resolved from.
+	ProjectDir string
+	// Files are the compose files, merged in order. Empty uses compose's default, e.g "compose.yaml".
+	Files []string
+	// Profiles enable services of these profiles, in addition to the services without profiles.
+	Profiles []string
+	// Env are "KEY=VALUE" variables, e.g for interpolation in the compose files.
+	Env []string
+}
+
+type ComposeUpOptions struct {
+	// Services limits the command to these services and their dependencies. Empty means all services.
+	Services []string
+	// Build builds the images before starting the containers.
+	Build         bool
+	ForceRecreate bool
+	RemoveOrphans bool
+	// Wait waits until the services are running and healthy,
+	// for at most WaitTimeout rounded up to whole seconds, when positive.
+	Wait        bool
+	WaitTimeout time.Duration
+}
+
+type ComposeDownOptions struct {
+	// Volumes removes the named volumes of the project and the anonymous volumes of its containers.
+	Volumes       bool
+	RemoveOrphans bool
+	// Timeout to stop the containers before killing them, rounded up to whole seconds.
+	// A zero Timeout uses compose's default.
+	Timeout time.Duration
+}
+
+type ComposeLogsOptions struct {
+	// Tail limits the output to the last lines of each container, when positive.
+	Tail int
+	// Since limits the output to logs newer than a timestamp, e.g "2019-06-01T10:00:00Z", or a duration, e.g "10m".
+	Since      string
+	Timestamps bool
+}
+
+type ComposeExecOptions struct {
+	// Env are "KEY=VALUE" variables.
+	Env     []string
+	User    string
+	Workdir string
+	// Index of the container, when the service has several replicas. Zero uses the first one.
+	Index int
+}
+
+type ComposeBuildOptions struct {
+	// Services limits the command to these services. Empty means all services.
+	Services []string
+	// BuildArgs are "KEY=VALUE" build arguments.
+	BuildArgs []string
+	Pull      bool
+	NoCache   bool
+}
+
+// ComposePublisher is a port a service container publishes.
+type ComposePublisher struct {
+	URL           string `json:"URL"`
+	TargetPort    int    `json:"TargetPort"`
+	PublishedPort int    `json:"PublishedPort"`
+	Protocol      string `json:"Protocol"`
+}
+
+// ComposeContainer is a container of a service, parsed from `docker compose ps --format=json`.
+type ComposeContainer struct {
+	ID      string `json:"ID"`
+	Name    string `json:"Name"`
+	Image   string `json:"Image"`
+	Command string `json:"Command"`
+	Project string `json:"Project"`
+	Service string `json:"Service"`
+	// State is "created", "running", "paused", "restarting", "removing", "exited" or "dead".
+	State string `json:"State"`
+	// Health is "starting", "healthy", "unhealthy", or empty when the container has no health check.
+	Health     string              `json:"Health"`
+	ExitCode   int                 `json:"ExitCode"`
+	Publishers []*ComposePublisher `json:"Publishers"`
+}
+
+// Compose executes `docker compose` commands of a single project.
+type Compose struct {
+	binaryPath      string
+	commandExecutor os.CommandExecutor
+	options         *ComposeOptions
+}
+
+// NewCompose creates a Compose for the project selected by `options`, which may be nil.
+func NewCompose(executor os.CommandExecutor, options *ComposeOptions) *Compose {
+	if options == nil {
+		options = &ComposeOptions{}
+	}
+
+	return &Compose{
+		binaryPath:      "docker",
+		commandExecutor: executor,
+		options:         options,
+	}
+}
+
+// Up creates and starts the containers of the project in the background.
+func (compose *Compose) Up(options *ComposeUpOptions) error {
+	args := []string{"up", "--detach"}
+
+	if options == nil {
+		options = &ComposeUpOptions{}
+	}
+
+	if options.Build {
+		args = append(args, "--build")
+	}
+
+	if options.ForceRecreate {
+		args = append(args, "--force-recreate")
+	}
+
+	if options.RemoveOrphans {
+		args = append(args, "--remove-orphans")
+	}
+
+	if options.Wait {
+		args = append(args, "--wait")
+
+		if options.WaitTimeout > 0 {
+			args = append(args, fmt.Sprintf("--wait-timeout=%d", ceilSeconds(options.WaitTimeout)))
+		}
+	}
+
+	args = append(args, options.Services...)
+
+	_, err := compose.execute(args)
+	return err
+}
+
+// Down stops and removes the containers and networks of the project.
+func (compose *Compose) Down(options *ComposeDownOptions) error {
+	args := []string{"down"}
+
+	if options != nil {
+		if options.Volumes {
+			args = append(args, "--volumes")
+		}
+
+		if options.RemoveOrphans {
+			args = append(args, "--remove-orphans")
+		}
+
+		if options.Timeout > 0 {
+			args = append(args, fmt.Sprintf("--timeout=%d", ceilSeconds(options.Timeout)))
+		}
+	}
+
+	_, err := compose.execute(args)
+	return err
+}
+
+// Ps returns the containers of `services`, or of all services when empty.
+// When `all` is true, stopped containers are included.
+func (compose *Compose) Ps(all bool, services []string) ([]*ComposeContainer, error) {
+	args := []string{"ps", "--format=json"}
+	if all {
+		args = append(args, "--all")
+	}
+
+	args = append(args, services...)
+
+	stdout, err := compose.execute(args)
+	if err != nil {
+		return nil, err
+	}
+
+	containers, err := parseComposePsOutput(stdout)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "json decode command `docker compose ps` output failed")
+	}
+
+	return containers, nil
+}
+
+// Logs returns the output of the containers of `services`, or of all services when empty.
+func (compose *Compose) Logs(services []string, options *ComposeLogsOptions) (string, error) {
+	args := []string{"logs", "--no-color"}
+
+	if options != nil {
+		if options.Tail > 0 {
+			args = append(args, fmt.Sprintf("--tail=%d", options.Tail))
+		}
+
+		if options.Since != "" {
+			args = append(args, fmt.Sprintf("--since=%s", options.Since))
+		}
+
+		if options.Timestamps {
+			args = append(args, "--timestamps")
+		}
+	}
+
+	args = append(args, services...)
+
+	stdout, err := compose.execute(args)
+	if err != nil {
+		return "", err
+	}
+
+	return string(stdout), nil
+}
+
+// Exec runs `command` in the running container of `service` and returns its stdout.
+func (compose *Compose) Exec(service string, command []string, options *ComposeExecOptions) (string, error) {
+	// NOTE: Without a TTY, since the output is captured rather than attached to a terminal.
+	args := []string{"exec", "--no-TTY"}
+
+	if options != nil {
+		if options.User != "" {
+			args = append(args, fmt.Sprintf("--user=%s", options.User))
+		}
+
+		if options.Workdir != "" {
+			args = append(args, fmt.Sprintf("--workdir=%s", options.Workdir))
+		}
+
+		if options.Index > 0 {
+			args = append(args, fmt.Sprintf("--index=%d", options.Index))
+		}
+
+		for _, variable := range options.Env {
+			args = append(args, fmt.Sprintf("--env=%s", variable))
+		}
+	}
+
+	args = append(args, service)
+	args = append(args, command...)
+
+	stdout, err := compose.execute(args)
+	if err != nil {
+		return "", err
+	}
+
+	return string(stdout), nil
+}
+
+// Build builds the images of the project's services.
+func (compose *Compose) Build(options *ComposeBuildOptions) error {
+	args := []string{"build"}
+
+	if options == nil {
+		options = &ComposeBuildOptions{}
+	}
+
+	if options.Pull {
+		args = append(args, "--pull")
+	}
+
+	if options.NoCache {
+		args = append(args, "--no-cache")
+	}
+
+	for _, buildArg := range options.BuildArgs {
+		args = append(args, fmt.Sprintf("--build-arg=%s", buildArg))
+	}
+
+	args = append(args, options.Services...)
+
+	_, err := compose.execute(args)
+	return err
+}
+
+func (compose *Compose) execute(args []string) ([]byte, error) {
+	stdout, stderr, err := compose.commandExecutor.Execute(
+		compose.binaryPath,
+		append(compose.projectArgs(), args...),
+		compose.env(),
+		"",
+	)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Stderr: %s, Stdout: %s", stderr, stdout)
+	}
+
+	return stdout, nil
+}
+
+// projectArgs returns the `docker compose` arguments selecting the project, which precede the command.
+func (compose *Compose) projectArgs() []string {
+	args := []string{"compose"}
+
+	if compose.options.ProjectName != "" {
+		args = append(args, fmt.Sprintf("--project-name=%s", compose.options.ProjectName))
+	}
+
+	if compose.options.ProjectDir != "" {
+		args = append(args, fmt.Sprintf("--project-directory=%s", compose.options.ProjectDir))
+	}
+
+	for _, file := range compose.options.Files {
+		args = append(args, fmt.Sprintf("--file=%s", file))
+	}
+
+	for _, profile := range compose.options.Profiles {
+		args = append(args, fmt.Sprintf("--profile=%s", profile))
+	}
+
+	return args
+}
+
+func (compose *Compose) env() []string {
+	if len(compose.options.Env) < 1 {
+		return nil
+	}
+
+	return extendEnv(nil, compose.options.Env)
+}
+
+// parseComposePsOutput parses both the JSON array of compose before v2.21 and the JSON lines of later versions.
+func parseComposePsOutput(output []byte) ([]*ComposeContainer, error) {
+	output = bytes.TrimSpace(output)
+	if len(output) == 0 {
+		return []*ComposeContainer{}, nil
+	}
+
+	var containers []*ComposeContainer
+	if output[0] == '[' {
+		err := json.Unmarshal(output, &containers)
+		return containers, err
+	}
+
+	for _, line := range splitLines(output) {
+		var container ComposeContainer
+
+		err := json.Unmarshal([]byte(line), &container)
+		if err != nil {
+			return nil, err
+		}
+
+		containers = append(containers, &container)
+	}
+
+	return containers, nil
+}
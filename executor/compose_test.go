@@ -0,0 +1,306 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sumup-oss/go-pkgs/os/ostest"
+)
+
+func TestCompose_Up(t *testing.T) {
+	t.Run("it selects the project and starts the services in the background", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{
+				"compose",
+				"--project-name=dev",
+				"--project-directory=/src/app",
+				"--file=compose.yaml",
+				"--file=compose.override.yaml",
+				"--profile=tools",
+				"up",
+				"--detach",
+				"--build",
+				"--remove-orphans",
+				"--wait",
+				"--wait-timeout=60",
+				"api",
+				"db",
+			},
+			[]string(nil),
+			"",
+		).Return([]byte{}, []byte{}, nil)
+
+		composeInstance := NewCompose(
+			executorArg,
+			&ComposeOptions{
+				ProjectName: "dev",
+				ProjectDir:  "/src/app",
+				Files:       []string{"compose.yaml", "compose.override.yaml"},
+				Profiles:    []string{"tools"},
+			},
+		)
+		err := composeInstance.Up(
+			&ComposeUpOptions{
+				Services:      []string{"api", "db"},
+				Build:         true,
+				RemoveOrphans: true,
+				Wait:          true,
+				WaitTimeout:   time.Minute,
+			},
+		)
+		require.Nil(t, err)
+		executorArg.AssertExpectations(t)
+	})
+
+	t.Run("it passes the environment for interpolation", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"compose", "up", "--detach"},
+			mock.MatchedBy(func(env []string) bool {
+				return len(env) > 0 && env[len(env)-1] == "APP_VERSION=v1"
+			}),
+			"",
+		).Return([]byte{}, []byte{}, nil)
+
+		composeInstance := NewCompose(executorArg, &ComposeOptions{Env: []string{"APP_VERSION=v1"}})
+		err := composeInstance.Up(nil)
+		require.Nil(t, err)
+	})
+
+	t.Run("when compose fails, it returns error", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"compose", "up", "--detach"},
+			[]string(nil),
+			"",
+		).Return([]byte{}, []byte("no configuration file provided: not found"), errors.New("exit status 1"))
+
+		composeInstance := NewCompose(executorArg, nil)
+		err := composeInstance.Up(nil)
+		require.NotNil(t, err)
+		assert.Contains(t, err.Error(), "no configuration file provided")
+	})
+}
+
+func TestCompose_Down(t *testing.T) {
+	t.Run("it removes the containers and volumes of the project", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"compose", "--project-name=dev", "down", "--volumes", "--remove-orphans", "--timeout=5"},
+			[]string(nil),
+			"",
+		).Return([]byte{}, []byte{}, nil)
+
+		composeInstance := NewCompose(executorArg, &ComposeOptions{ProjectName: "dev"})
+		err := composeInstance.Down(
+			&ComposeDownOptions{Volumes: true, RemoveOrphans: true, Timeout: 5 * time.Second},
+		)
+		require.Nil(t, err)
+		executorArg.AssertExpectations(t)
+	})
+
+	t.Run("it rounds a sub-second timeout up to a second", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"compose", "down", "--timeout=1"},
+			[]string(nil),
+			"",
+		).Return([]byte{}, []byte{}, nil)
+
+		composeInstance := NewCompose(executorArg, nil)
+		err := composeInstance.Down(&ComposeDownOptions{Timeout: 100 * time.Millisecond})
+		require.Nil(t, err)
+		executorArg.AssertExpectations(t)
+	})
+}
+
+func TestCompose_Ps(t *testing.T) {
+	t.Run("it parses the JSON lines of the containers", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"compose", "ps", "--format=json", "--all", "db"},
+			[]string(nil),
+			"",
+		).Return(
+			[]byte(
+				`{"ID":"4f1c0e2d9a7b","Name":"dev-db-1","Image":"postgres:11","Project":"dev","Service":"db",`+
+					`"State":"running","Health":"healthy","ExitCode":0,`+
+					`"Publishers":[{"URL":"0.0.0.0","TargetPort":5432,"PublishedPort":15432,"Protocol":"tcp"}]}`+"\n",
+			),
+			[]byte{},
+			nil,
+		)
+
+		composeInstance := NewCompose(executorArg, nil)
+		actual, err := composeInstance.Ps(true, []string{"db"})
+		require.Nil(t, err)
+		require.Len(t, actual, 1)
+
+		assert.Equal(t, "dev-db-1", actual[0].Name)
+		assert.Equal(t, "db", actual[0].Service)
+		assert.Equal(t, "running", actual[0].State)
+		assert.Equal(t, "healthy", actual[0].Health)
+		assert.Equal(
+			t,
+			[]*ComposePublisher{{URL: "0.0.0.0", TargetPort: 5432, PublishedPort: 15432, Protocol: "tcp"}},
+			actual[0].Publishers,
+		)
+	})
+
+	t.Run("it parses the JSON array of older compose versions", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"compose", "ps", "--format=json"},
+			[]string(nil),
+			"",
+		).Return(
+			[]byte(`[{"Name":"dev-api-1","Service":"api","State":"running"},{"Name":"dev-db-1","Service":"db","State":"exited"}]`),
+			[]byte{},
+			nil,
+		)
+
+		composeInstance := NewCompose(executorArg, nil)
+		actual, err := composeInstance.Ps(false, nil)
+		require.Nil(t, err)
+		require.Len(t, actual, 2)
+		assert.Equal(t, "api", actual[0].Service)
+		assert.Equal(t, "exited", actual[1].State)
+	})
+
+	t.Run("when there are no containers, it returns an empty slice", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"compose", "ps", "--format=json"},
+			[]string(nil),
+			"",
+		).Return([]byte("\n"), []byte{}, nil)
+
+		composeInstance := NewCompose(executorArg, nil)
+		actual, err := composeInstance.Ps(false, nil)
+		require.Nil(t, err)
+		assert.Equal(t, []*ComposeContainer{}, actual)
+	})
+}
+
+func TestCompose_Logs(t *testing.T) {
+	t.Run("it returns the output of the services", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"compose", "logs", "--no-color", "--tail=10", "--since=10m", "api"},
+			[]string(nil),
+			"",
+		).Return([]byte("dev-api-1  | listening on :8080\n"), []byte{}, nil)
+
+		composeInstance := NewCompose(executorArg, nil)
+		actual, err := composeInstance.Logs([]string{"api"}, &ComposeLogsOptions{Tail: 10, Since: "10m"})
+		require.Nil(t, err)
+		assert.Equal(t, "dev-api-1  | listening on :8080\n", actual)
+	})
+}
+
+func TestCompose_Exec(t *testing.T) {
+	t.Run("it runs the command without a TTY and returns its output", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{
+				"compose",
+				"exec",
+				"--no-TTY",
+				"--user=postgres",
+				"--index=2",
+				"--env=PGDATABASE=app",
+				"db",
+				"psql",
+				"-c",
+				"SELECT 1",
+			},
+			[]string(nil),
+			"",
+		).Return([]byte("1\n"), []byte{}, nil)
+
+		composeInstance := NewCompose(executorArg, nil)
+		actual, err := composeInstance.Exec(
+			"db",
+			[]string{"psql", "-c", "SELECT 1"},
+			&ComposeExecOptions{User: "postgres", Index: 2, Env: []string{"PGDATABASE=app"}},
+		)
+		require.Nil(t, err)
+		assert.Equal(t, "1\n", actual)
+	})
+}
+
+func TestCompose_Build(t *testing.T) {
+	t.Run("it builds the services", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"compose", "--file=compose.yaml", "build", "--pull", "--no-cache", "--build-arg=VERSION=v1", "api"},
+			[]string(nil),
+			"",
+		).Return([]byte{}, []byte{}, nil)
+
+		composeInstance := NewCompose(executorArg, &ComposeOptions{Files: []string{"compose.yaml"}})
+		err := composeInstance.Build(
+			&ComposeBuildOptions{
+				Services:  []string{"api"},
+				BuildArgs: []string{"VERSION=v1"},
+				Pull:      true,
+				NoCache:   true,
+			},
+		)
+		require.Nil(t, err)
+		executorArg.AssertExpectations(t)
+	})
+}
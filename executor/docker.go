@@ -17,9 +17,12 @@ package executor
 import (
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net"
+	stdOs "os"
 	"regexp"
 	"runtime"
+	"strings"
 
 	"github.com/palantir/stacktrace"
 
@@ -32,18 +35,50 @@ var (
 )
 
 type DockerBuildOptions struct {
-	Hosts      map[string]string
-	BuildArgs  []string
-	File       string
-	Tag        string
+	Hosts     map[string]string
+	BuildArgs []string
+	File      string
+	Tag       *ImageRef
+	// Tags are additional tags of the image.
+	Tags       []*ImageRef
 	Target     string
 	ContextDir string
+	Labels     map[string]string
+	// CacheFrom are images used as cache sources, e.g the previously pushed image or a registry cache export.
+	CacheFrom []*ImageRef
+	// CacheTo are BuildKit cache exports, e.g "type=inline". Requires buildx.
+	CacheTo []string
+	// Platforms are the target platforms, e.g "linux/amd64". Requires buildx and Push or Load.
+	Platforms []string
+	// Secrets are BuildKit secrets, e.g "id=npmrc,src=/home/ci/.npmrc".
+	Secrets []string
+	// SSH are BuildKit SSH agent sockets or keys, e.g "default".
+	SSH     []string
+	NoCache bool
+	// Pull always pulls newer versions of the base images.
+	Pull bool
+	// Network is the network mode of RUN instructions, e.g "host".
+	Network string
+	// IIDFile is the file the built image ID is written to.
+	IIDFile string
+	// Buildx builds with `docker buildx build`, which is implied by Platforms and CacheTo.
+	Buildx bool
+	// Push pushes the image after building it. Requires buildx.
+	Push bool
+	// Load loads the image into the docker images after building it. Requires buildx.
+	Load bool
 }
 
 type DockerNetwork struct {
-	Name string `json:"Name"`
-	ID   string `json:"Id"`
-	IPAМ struct {
+	Name     string            `json:"Name"`
+	ID       string            `json:"Id"`
+	Driver   string            `json:"Driver"`
+	Scope    string            `json:"Scope"`
+	Internal bool              `json:"Internal"`
+	Labels   map[string]string `json:"Labels"`
+	// Containers are the connected containers, keyed by container ID.
+	Containers map[string]*DockerNetworkContainer `json:"Containers"`
+	IPAМ       struct {
 		Driver string `json:"Driver"`
 		Config []struct {
 			Subnet  string `json:"Subnet"`
@@ -55,6 +90,8 @@ type DockerNetwork struct {
 type Docker struct {
 	binaryPath      string
 	commandExecutor os.CommandExecutor
+	// configDir is the DOCKER_CONFIG directory, or empty for docker's default.
+	configDir string
 }
 
 func NewDocker(executor os.CommandExecutor) *Docker {
@@ -64,20 +101,107 @@ func NewDocker(executor os.CommandExecutor) *Docker {
 	}
 }
 
-func (docker *Docker) Push(image string) error {
-	args := []string{"push", image}
-	stdout, stderr, err := docker.commandExecutor.Execute("docker", args, nil, "")
+// Push pushes the tag of `image`. References without a tag, e.g image IDs or digests, can't be pushed.
+func (docker *Docker) Push(image *ImageRef) error {
+	err := validatePushImageRef(image)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"push", image.String()}
+	stdout, stderr, err := docker.commandExecutor.Execute("docker", args, docker.env(nil), "")
 	return stacktrace.Propagate(err, "Stderr: %s, Stdout: %s", stderr, stdout)
 }
 
-func (docker *Docker) Pull(image string) error {
-	args := []string{"pull", image}
-	stdout, stderr, err := docker.commandExecutor.Execute("docker", args, nil, "")
+func (docker *Docker) Pull(image *ImageRef) error {
+	args := []string{"pull", image.String()}
+	stdout, stderr, err := docker.commandExecutor.Execute("docker", args, docker.env(nil), "")
 	return stacktrace.Propagate(err, "Stderr: %s, Stdout: %s", stderr, stdout)
 }
 
 func (docker *Docker) Build(options *DockerBuildOptions) error {
-	args := []string{"build", "-f", options.File, "--tag", options.Tag}
+	args, env, err := dockerBuildArgs(options)
+	if err != nil {
+		return err
+	}
+
+	stdout, stderr, err := docker.commandExecutor.Execute("docker", args, docker.env(env), "")
+	return stacktrace.Propagate(err, "Stderr: %s, Stdout: %s", stderr, stdout)
+}
+
+// validatePushImageRef returns error for references without a tag,
+// since the daemon pushes every tag of the repository when the tag is empty.
+func validatePushImageRef(image *ImageRef) error {
+	if image.Name() == "" {
+		return stacktrace.NewError("can't push image ID %s", image)
+	}
+
+	if image.Tag == "" {
+		return stacktrace.NewError("can't push image %s without a tag", image)
+	}
+
+	return nil
+}
+
+// BuildImage builds like Build and returns the ID of the built image, read from `options.IIDFile`.
+// When `options.IIDFile` is empty, a temporary file is used.
+// NOTE: The file is accessed directly, rather than through the command executor,
+// since it's written by the `docker` process on the local filesystem.
+func (docker *Docker) BuildImage(options *DockerBuildOptions) (string, error) {
+	buildOptions := *options
+
+	if buildOptions.IIDFile == "" {
+		iidFile, err := ioutil.TempFile("", "docker-iid")
+		if err != nil {
+			return "", stacktrace.Propagate(err, "failed to create image ID file")
+		}
+
+		_ = iidFile.Close()
+		defer stdOs.Remove(iidFile.Name())
+
+		buildOptions.IIDFile = iidFile.Name()
+	}
+
+	err := docker.Build(&buildOptions)
+	if err != nil {
+		return "", err
+	}
+
+	imageID, err := ioutil.ReadFile(buildOptions.IIDFile)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "failed to read image ID file %s", buildOptions.IIDFile)
+	}
+
+	return strings.TrimSpace(string(imageID)), nil
+}
+
+// dockerBuildArgs returns the arguments and env of `docker build`, or `docker buildx build`.
+// The env enables BuildKit when secrets or SSH are used without buildx and is nil otherwise.
+// It returns error for options that would be ignored or build no usable image.
+func dockerBuildArgs(options *DockerBuildOptions) ([]string, []string, error) {
+	buildx := options.Buildx || len(options.Platforms) > 0 || len(options.CacheTo) > 0
+
+	if !buildx && (options.Push || options.Load) {
+		return nil, nil, stacktrace.NewError("docker build options Push and Load require buildx")
+	}
+
+	// NOTE: buildx keeps images built for other platforms only in its build cache.
+	if len(options.Platforms) > 0 && !options.Push && !options.Load {
+		return nil, nil, stacktrace.NewError("docker build option Platforms requires Push or Load")
+	}
+
+	args := []string{"build", "-f", options.File}
+	if buildx {
+		args = append([]string{"buildx"}, args...)
+	}
+
+	if options.Tag != nil {
+		args = append(args, "--tag", options.Tag.String())
+	}
+
+	for _, tag := range options.Tags {
+		args = append(args, "--tag", tag.String())
+	}
 
 	if options.Target != "" {
 		args = append(args, "--target", options.Target)
@@ -95,20 +219,65 @@ func (docker *Docker) Build(options *DockerBuildOptions) error {
 		}
 	}
 
+	args = append(args, dockerLabelArgs(options.Labels)...)
+
+	for _, cacheFrom := range options.CacheFrom {
+		args = append(args, fmt.Sprintf("--cache-from=%s", cacheFrom.String()))
+	}
+
+	for _, cacheTo := range options.CacheTo {
+		args = append(args, fmt.Sprintf("--cache-to=%s", cacheTo))
+	}
+
+	if len(options.Platforms) > 0 {
+		args = append(args, fmt.Sprintf("--platform=%s", strings.Join(options.Platforms, ",")))
+	}
+
+	for _, secret := range options.Secrets {
+		args = append(args, fmt.Sprintf("--secret=%s", secret))
+	}
+
+	for _, ssh := range options.SSH {
+		args = append(args, fmt.Sprintf("--ssh=%s", ssh))
+	}
+
+	if options.NoCache {
+		args = append(args, "--no-cache")
+	}
+
+	if options.Pull {
+		args = append(args, "--pull")
+	}
+
+	if options.Network != "" {
+		args = append(args, fmt.Sprintf("--network=%s", options.Network))
+	}
+
+	if options.IIDFile != "" {
+		args = append(args, fmt.Sprintf("--iidfile=%s", options.IIDFile))
+	}
+
+	if options.Push {
+		args = append(args, "--push")
+	}
+
+	if options.Load {
+		args = append(args, "--load")
+	}
+
 	args = append(args, options.ContextDir)
-	stdout, stderr, err := docker.commandExecutor.Execute("docker", args, nil, "")
-	return stacktrace.Propagate(err, "Stderr: %s, Stdout: %s", stderr, stdout)
-}
 
-func (docker *Docker) Tag(oldImage, newImage string) error {
-	args := []string{"tag", oldImage, newImage}
-	stdout, stderr, err := docker.commandExecutor.Execute("docker", args, nil, "")
-	return stacktrace.Propagate(err, "Stderr: %s, Stdout: %s", stderr, stdout)
+	var env []string
+	if !buildx && (len(options.Secrets) > 0 || len(options.SSH) > 0) {
+		env = extendEnv(nil, []string{"DOCKER_BUILDKIT=1"})
+	}
+
+	return args, env, nil
 }
 
-func (docker *Docker) Login(username, password, registryUrl string) error {
-	args := []string{"login", "-u", username, "-p", password, registryUrl}
-	stdout, stderr, err := docker.commandExecutor.Execute("docker", args, nil, "")
+func (docker *Docker) Tag(oldImage, newImage *ImageRef) error {
+	args := []string{"tag", oldImage.String(), newImage.String()}
+	stdout, stderr, err := docker.commandExecutor.Execute("docker", args, docker.env(nil), "")
 	return stacktrace.Propagate(err, "Stderr: %s, Stdout: %s", stderr, stdout)
 }
 
@@ -116,7 +285,7 @@ func (docker *Docker) NetworkInspect(name string) (*DockerNetwork, error) {
 	stdout, _, err := docker.commandExecutor.Execute(
 		docker.binaryPath,
 		[]string{"network", "inspect", name},
-		nil,
+		docker.env(nil),
 		"",
 	)
 
@@ -147,25 +316,9 @@ func (docker *Docker) NetworkGateway(name string) (string, error) {
 		return "", stacktrace.NewError("failed to read docker network configuration")
 	}
 
-	if len(network.IPAМ.Config) == 0 {
-		return "", stacktrace.NewError("no IPAM configuration found for docker network")
-	}
-
-	var gatewayIP string
-	// NOTE: Docker has some confirmed regression in terms of setting a `Gateway`.
-	// There are scenarios where it might have a bridge with an IP which is the gateway of
-	// the docker network, but nothing except subnet CIDR set in the configuration.
-	if network.IPAМ.Config[0].Gateway == "" {
-		ip, _, err := net.ParseCIDR(network.IPAМ.Config[0].Subnet)
-		if err != nil {
-			return "", stacktrace.Propagate(err, "failed to parse IP CIDR for docker network CIDR")
-		}
-
-		ipv4 := ip.To4()
-		ipv4[3]++
-		gatewayIP = ipv4.String()
-	} else {
-		gatewayIP = network.IPAМ.Config[0].Gateway
+	gatewayIP, err := dockerNetworkGatewayIP(network)
+	if err != nil {
+		return "", err
 	}
 
 	// NOTE: Prefer network isolation wherever possible by avoiding binding on the docker gateway.
@@ -220,3 +373,27 @@ func (docker *Docker) NetworkGateway(name string) (string, error) {
 
 	return gatewayIP, nil
 }
+
+// dockerNetworkGatewayIP returns the gateway of the first IPAM configuration of `network`.
+func dockerNetworkGatewayIP(network *DockerNetwork) (string, error) {
+	if len(network.IPAМ.Config) == 0 {
+		return "", stacktrace.NewError("no IPAM configuration found for docker network")
+	}
+
+	// NOTE: Docker has some confirmed regression in terms of setting a `Gateway`.
+	// There are scenarios where it might have a bridge with an IP which is the gateway of
+	// the docker network, but nothing except subnet CIDR set in the configuration.
+	if network.IPAМ.Config[0].Gateway != "" {
+		return network.IPAМ.Config[0].Gateway, nil
+	}
+
+	ip, _, err := net.ParseCIDR(network.IPAМ.Config[0].Subnet)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "failed to parse IP CIDR for docker network CIDR")
+	}
+
+	ipv4 := ip.To4()
+	ipv4[3]++
+
+	return ipv4.String(), nil
+}
@@ -0,0 +1,716 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	stdOs "os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/palantir/stacktrace"
+)
+
+const (
+	DefaultDockerHost = "unix:///var/run/docker.sock"
+
+	dockerHostEnv = "DOCKER_HOST"
+	// dockerAPIUnixHost is the placeholder host of requests sent over a Unix socket.
+	dockerAPIUnixHost = "docker"
+)
+
+type DockerAPIOptions struct {
+	// Host is the address of the docker daemon, e.g "unix:///var/run/docker.sock" or "tcp://127.0.0.1:2375".
+	// It defaults to DOCKER_HOST and then to DefaultDockerHost.
+	Host string
+	// APIVersion pins the version of the API, e.g "1.41". When empty, the daemon's version is used.
+	APIVersion string
+	// Auths are the registry credentials used to pull and push, keyed by registry host, e.g "ghcr.io".
+	Auths map[string]*DockerRegistryAuth
+}
+
+// DockerAPIError is returned by DockerAPI methods when the daemon responds with an error status.
+type DockerAPIError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Message    string
+}
+
+// Error returns the error message.
+func (err *DockerAPIError) Error() string {
+	return fmt.Sprintf("docker API %s %s failed with status %d: %s", err.Method, err.Path, err.StatusCode, err.Message)
+}
+
+// DockerAPI speaks the Docker Engine HTTP API, instead of executing the `docker` binary.
+type DockerAPI struct {
+	scheme     string
+	host       string
+	apiVersion string
+	auths      map[string]*DockerRegistryAuth
+	httpClient *http.Client
+}
+
+// NewDockerAPI creates a DockerAPI instance. `options` is optional.
+func NewDockerAPI(options *DockerAPIOptions) (*DockerAPI, error) {
+	if options == nil {
+		options = &DockerAPIOptions{}
+	}
+
+	host := options.Host
+	if host == "" {
+		host = stdOs.Getenv(dockerHostEnv)
+	}
+
+	if host == "" {
+		host = DefaultDockerHost
+	}
+
+	hostURL, err := url.Parse(host)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "failed to parse docker host %s", host)
+	}
+
+	api := &DockerAPI{
+		scheme:     "http",
+		apiVersion: options.APIVersion,
+		auths:      options.Auths,
+	}
+
+	switch hostURL.Scheme {
+	case "unix":
+		socketPath := hostURL.Path
+		dialer := &net.Dialer{}
+
+		api.host = dockerAPIUnixHost
+		api.httpClient = &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					return dialer.DialContext(ctx, "unix", socketPath)
+				},
+			},
+		}
+	case "tcp", "http":
+		api.host = hostURL.Host
+		api.httpClient = &http.Client{}
+	default:
+		return nil, stacktrace.NewError("unsupported docker host %s", host)
+	}
+
+	return api, nil
+}
+
+// Pull pulls `image`, using the credentials of its registry.
+func (api *DockerAPI) Pull(image *ImageRef) error {
+	if image.Name() == "" {
+		return stacktrace.NewError("can't pull image ID %s", image)
+	}
+
+	tag := image.Tag
+	if image.Digest != "" {
+		tag = image.Digest
+	}
+
+	header, err := api.registryAuthHeader(image.Registry)
+	if err != nil {
+		return err
+	}
+
+	response, err := api.do(
+		http.MethodPost,
+		"/images/create",
+		url.Values{"fromImage": {image.Name()}, "tag": {tag}},
+		nil,
+		header,
+	)
+	if err != nil {
+		return stacktrace.Propagate(err, "failed to pull %s", image)
+	}
+	defer response.Body.Close()
+
+	_, err = readDockerAPIStream(response.Body, nil)
+	return stacktrace.Propagate(err, "failed to pull %s", image)
+}
+
+// Push pushes `image`, using the credentials of its registry.
+func (api *DockerAPI) Push(image *ImageRef) error {
+	_, err := api.PushWithProgress(image, nil)
+	return err
+}
+
+// PushWithProgress pushes like Push, calling `progress` with the events of the streamed messages.
+// It returns the digest of the pushed image.
+func (api *DockerAPI) PushWithProgress(image *ImageRef, progress DockerProgressFunc) (string, error) {
+	err := validatePushImageRef(image)
+	if err != nil {
+		return "", err
+	}
+
+	header, err := api.registryAuthHeader(image.Registry)
+	if err != nil {
+		return "", err
+	}
+
+	response, err := api.do(
+		http.MethodPost,
+		fmt.Sprintf("/images/%s/push", image.Name()),
+		url.Values{"tag": {image.Tag}},
+		nil,
+		header,
+	)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "failed to push %s", image)
+	}
+	defer response.Body.Close()
+
+	digest, err := readDockerAPIStream(response.Body, progress)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "failed to push %s", image)
+	}
+
+	return digest, nil
+}
+
+func (api *DockerAPI) Tag(oldImage, newImage *ImageRef) error {
+	return api.call(
+		http.MethodPost,
+		fmt.Sprintf("/images/%s/tag", oldImage),
+		url.Values{"repo": {newImage.Name()}, "tag": {newImage.Tag}},
+		nil,
+		nil,
+	)
+}
+
+// ImageInspect returns the metadata of the local `image`.
+func (api *DockerAPI) ImageInspect(image *ImageRef) (*DockerImage, error) {
+	var result DockerImage
+
+	err := api.call(http.MethodGet, fmt.Sprintf("/images/%s/json", image), nil, nil, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// ImageExists reports whether `image` exists locally.
+func (api *DockerAPI) ImageExists(image *ImageRef) (bool, error) {
+	_, err := api.ImageInspect(image)
+	if isDockerAPINotFound(err) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// ImageRemove removes the local `images`. When `force` is true, images used by stopped containers are removed too.
+func (api *DockerAPI) ImageRemove(images []*ImageRef, force bool) error {
+	for _, image := range images {
+		err := api.call(
+			http.MethodDelete,
+			fmt.Sprintf("/images/%s", image),
+			url.Values{"force": {strconv.FormatBool(force)}},
+			nil,
+			nil,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Inspect returns the configuration and state of `container`.
+func (api *DockerAPI) Inspect(container string) (*DockerContainer, error) {
+	var result DockerContainer
+
+	err := api.call(http.MethodGet, fmt.Sprintf("/containers/%s/json", container), nil, nil, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// Stop stops `container`, killing it after `timeout`, rounded up to whole seconds.
+// A zero `timeout` uses docker's default.
+func (api *DockerAPI) Stop(container string, timeout time.Duration) error {
+	query := url.Values{}
+	if timeout > 0 {
+		query.Set("t", strconv.Itoa(ceilSeconds(timeout)))
+	}
+
+	return api.call(http.MethodPost, fmt.Sprintf("/containers/%s/stop", container), query, nil, nil)
+}
+
+// Remove removes `container`.
+// When `force` is true, a running container is killed. When `volumes` is true, its anonymous volumes are removed.
+func (api *DockerAPI) Remove(container string, force, volumes bool) error {
+	return api.call(
+		http.MethodDelete,
+		fmt.Sprintf("/containers/%s", container),
+		url.Values{"force": {strconv.FormatBool(force)}, "v": {strconv.FormatBool(volumes)}},
+		nil,
+		nil,
+	)
+}
+
+func (api *DockerAPI) NetworkInspect(name string) (*DockerNetwork, error) {
+	var result DockerNetwork
+
+	err := api.call(http.MethodGet, fmt.Sprintf("/networks/%s", name), nil, nil, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// NetworkGateway returns the local address the host routes the gateway of the network `name` from,
+// or the gateway itself when there's no such route.
+func (api *DockerAPI) NetworkGateway(name string) (string, error) {
+	network, err := api.NetworkInspect(name)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "failed to read docker network configuration")
+	}
+
+	gatewayIP, err := dockerNetworkGatewayIP(network)
+	if err != nil {
+		return "", err
+	}
+
+	// NOTE: Like the `ip route get <docker bridge ip>` of Docker.NetworkGateway,
+	// but without parsing command output. Dialing UDP sends no packets, it only selects the route.
+	conn, err := net.Dial("udp", net.JoinHostPort(gatewayIP, "9"))
+	if err != nil {
+		return gatewayIP, nil
+	}
+	defer conn.Close()
+
+	return conn.LocalAddr().(*net.UDPAddr).IP.String(), nil
+}
+
+// NetworkCreate creates a network and returns its ID. `options.Name` is required.
+func (api *DockerAPI) NetworkCreate(options *DockerNetworkCreateOptions) (string, error) {
+	if options == nil || options.Name == "" {
+		return "", stacktrace.NewError("network name is required")
+	}
+
+	request := map[string]interface{}{
+		"Name":           options.Name,
+		"CheckDuplicate": true,
+		"Internal":       options.Internal,
+		"Attachable":     options.Attachable,
+	}
+
+	if options.Driver != "" {
+		request["Driver"] = options.Driver
+	}
+
+	if len(options.Labels) > 0 {
+		request["Labels"] = options.Labels
+	}
+
+	if options.Subnet != "" || options.Gateway != "" {
+		ipamConfig := map[string]string{}
+		if options.Subnet != "" {
+			ipamConfig["Subnet"] = options.Subnet
+		}
+
+		if options.Gateway != "" {
+			ipamConfig["Gateway"] = options.Gateway
+		}
+
+		request["IPAM"] = map[string]interface{}{"Config": []map[string]string{ipamConfig}}
+	}
+
+	var result struct {
+		ID string `json:"Id"`
+	}
+
+	err := api.call(http.MethodPost, "/networks/create", nil, request, &result)
+	if err != nil {
+		return "", err
+	}
+
+	return result.ID, nil
+}
+
+// NetworkRemove removes the network `name`.
+func (api *DockerAPI) NetworkRemove(name string) error {
+	return api.call(http.MethodDelete, fmt.Sprintf("/networks/%s", name), nil, nil, nil)
+}
+
+// NetworkConnect connects `container` to the network `name`.
+func (api *DockerAPI) NetworkConnect(name, container string, options *DockerNetworkConnectOptions) error {
+	endpointConfig := map[string]interface{}{}
+
+	if options != nil {
+		if len(options.Aliases) > 0 {
+			endpointConfig["Aliases"] = options.Aliases
+		}
+
+		if options.IP != "" {
+			endpointConfig["IPAMConfig"] = map[string]string{"IPv4Address": options.IP}
+		}
+	}
+
+	return api.call(
+		http.MethodPost,
+		fmt.Sprintf("/networks/%s/connect", name),
+		nil,
+		map[string]interface{}{"Container": container, "EndpointConfig": endpointConfig},
+		nil,
+	)
+}
+
+// NetworkDisconnect disconnects `container` from the network `name`.
+// When `force` is true, the container is disconnected even when it's not running.
+func (api *DockerAPI) NetworkDisconnect(name, container string, force bool) error {
+	return api.call(
+		http.MethodPost,
+		fmt.Sprintf("/networks/%s/disconnect", name),
+		nil,
+		map[string]interface{}{"Container": container, "Force": force},
+		nil,
+	)
+}
+
+// NetworkList returns the networks matching `filters`, e.g "label=ci.job=42" or "driver=bridge".
+// The networks are inspected one by one, since the listed networks have no Containers.
+func (api *DockerAPI) NetworkList(filters []string) ([]*DockerNetwork, error) {
+	query, err := dockerAPIFilters(filters)
+	if err != nil {
+		return nil, err
+	}
+
+	var listed []*DockerNetwork
+
+	err = api.call(http.MethodGet, "/networks", query, nil, &listed)
+	if err != nil {
+		return nil, err
+	}
+
+	networks := make([]*DockerNetwork, 0, len(listed))
+
+	for _, network := range listed {
+		inspected, err := api.NetworkInspect(network.ID)
+		if isDockerAPINotFound(err) {
+			// NOTE: Removed since it was listed.
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		networks = append(networks, inspected)
+	}
+
+	return networks, nil
+}
+
+// VolumeCreate creates a volume and returns its name.
+func (api *DockerAPI) VolumeCreate(options *DockerVolumeCreateOptions) (string, error) {
+	if options == nil {
+		options = &DockerVolumeCreateOptions{}
+	}
+
+	request := map[string]interface{}{
+		"Name":       options.Name,
+		"Driver":     options.Driver,
+		"DriverOpts": options.DriverOpts,
+		"Labels":     options.Labels,
+	}
+
+	var result DockerVolume
+
+	err := api.call(http.MethodPost, "/volumes/create", nil, request, &result)
+	if err != nil {
+		return "", err
+	}
+
+	return result.Name, nil
+}
+
+// VolumeInspect returns the volume `name`.
+func (api *DockerAPI) VolumeInspect(name string) (*DockerVolume, error) {
+	var result DockerVolume
+
+	err := api.call(http.MethodGet, fmt.Sprintf("/volumes/%s", name), nil, nil, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// VolumeList returns the volumes matching `filters`, e.g "label=ci.job=42" or "dangling=true".
+func (api *DockerAPI) VolumeList(filters []string) ([]*DockerVolume, error) {
+	query, err := dockerAPIFilters(filters)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Volumes []*DockerVolume `json:"Volumes"`
+	}
+
+	err = api.call(http.MethodGet, "/volumes", query, nil, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Volumes == nil {
+		return make([]*DockerVolume, 0), nil
+	}
+
+	return result.Volumes, nil
+}
+
+// VolumeRemove removes the volume `name`.
+// When `force` is true, no error is returned when the volume does not exist.
+func (api *DockerAPI) VolumeRemove(name string, force bool) error {
+	err := api.call(
+		http.MethodDelete,
+		fmt.Sprintf("/volumes/%s", name),
+		url.Values{"force": {strconv.FormatBool(force)}},
+		nil,
+		nil,
+	)
+	if force && isDockerAPINotFound(err) {
+		return nil
+	}
+
+	return err
+}
+
+// call sends a request with `body` encoded as JSON, when not nil,
+// and decodes the JSON response into `result`, when not nil.
+func (api *DockerAPI) call(method, path string, query url.Values, body, result interface{}) error {
+	response, err := api.do(method, path, query, body, nil)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if result == nil {
+		return nil
+	}
+
+	err = json.NewDecoder(response.Body).Decode(result)
+	if err != nil {
+		return stacktrace.Propagate(err, "json decode docker API %s %s response failed", method, path)
+	}
+
+	return nil
+}
+
+// do sends a request and returns the response, which must be closed, when its status is successful.
+// Otherwise, it returns a DockerAPIError.
+func (api *DockerAPI) do(method, path string, query url.Values, body interface{}, header http.Header) (*http.Response, error) {
+	var bodyReader io.Reader
+
+	if body != nil {
+		content, err := json.Marshal(body)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "json encode docker API %s %s request failed", method, path)
+		}
+
+		bodyReader = bytes.NewReader(content)
+	}
+
+	requestPath := path
+	if api.apiVersion != "" {
+		requestPath = fmt.Sprintf("/v%s%s", api.apiVersion, path)
+	}
+
+	requestURL := &url.URL{Scheme: api.scheme, Host: api.host, Path: requestPath, RawQuery: query.Encode()}
+
+	request, err := http.NewRequest(method, requestURL.String(), bodyReader)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "failed to create docker API %s %s request", method, path)
+	}
+
+	for key, values := range header {
+		request.Header[key] = values
+	}
+
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	response, err := api.httpClient.Do(request)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "docker API %s %s request failed", method, path)
+	}
+
+	if response.StatusCode < http.StatusBadRequest {
+		return response, nil
+	}
+
+	defer response.Body.Close()
+
+	apiError := &DockerAPIError{Method: method, Path: path, StatusCode: response.StatusCode}
+
+	content, _ := ioutil.ReadAll(response.Body)
+
+	var message struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(content, &message) == nil && message.Message != "" {
+		apiError.Message = message.Message
+	} else {
+		apiError.Message = strings.TrimSpace(string(content))
+	}
+
+	return nil, apiError
+}
+
+// registryAuthHeader returns the `X-Registry-Auth` header with the credentials of `registry`.
+// The header is required by pushes, so it's empty credentials when there are none.
+func (api *DockerAPI) registryAuthHeader(registry string) (http.Header, error) {
+	authConfig := map[string]string{}
+
+	auth := api.auths[registry]
+	if auth == nil && dockerConfigAuthKey(registry) == dockerHubAuthKey {
+		auth = api.auths[DefaultImageRegistry]
+	}
+
+	if auth != nil {
+		authConfig["serveraddress"] = registry
+
+		if auth.IdentityToken != "" {
+			authConfig["identitytoken"] = auth.IdentityToken
+		} else {
+			authConfig["username"] = auth.Username
+			authConfig["password"] = auth.Password
+		}
+	}
+
+	content, err := json.Marshal(authConfig)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "json encode docker registry auth failed")
+	}
+
+	return http.Header{"X-Registry-Auth": {base64.URLEncoding.EncodeToString(content)}}, nil
+}
+
+// readDockerAPIStream reads the JSON messages streamed by pulls and pushes,
+// which report failures in a message, after the successful response status.
+// It calls `progress`, when not nil, with the events of the messages and returns the last reported digest.
+func readDockerAPIStream(body io.Reader, progress DockerProgressFunc) (string, error) {
+	decoder := json.NewDecoder(body)
+
+	var digest string
+
+	for {
+		var message struct {
+			Status         string `json:"status"`
+			ID             string `json:"id"`
+			Error          string `json:"error"`
+			ProgressDetail struct {
+				Current int64 `json:"current"`
+				Total   int64 `json:"total"`
+			} `json:"progressDetail"`
+			Aux struct {
+				Digest string `json:"Digest"`
+			} `json:"aux"`
+		}
+
+		err := decoder.Decode(&message)
+		if err == io.EOF {
+			return digest, nil
+		}
+
+		if err != nil {
+			return "", stacktrace.Propagate(err, "json decode docker API stream failed")
+		}
+
+		if message.Error != "" {
+			return "", stacktrace.NewError("%s", message.Error)
+		}
+
+		event := &DockerProgressEvent{Type: DockerProgressLog, Message: message.Status}
+
+		switch {
+		case message.Aux.Digest != "":
+			digest = message.Aux.Digest
+			event.Type = DockerProgressDigest
+			event.Digest = message.Aux.Digest
+		case message.ID != "" && message.Status != "":
+			event.Type = DockerProgressLayer
+			event.LayerID = message.ID
+			event.Status = message.Status
+			event.Current = message.ProgressDetail.Current
+			event.Total = message.ProgressDetail.Total
+			event.Message = fmt.Sprintf("%s: %s", message.ID, message.Status)
+		case message.Status == "":
+			continue
+		}
+
+		if progress != nil {
+			progress(event)
+		}
+	}
+}
+
+// dockerAPIFilters returns the `filters` query of list endpoints, from CLI style filters, e.g "label=ci.job=42".
+func dockerAPIFilters(filters []string) (url.Values, error) {
+	query := url.Values{}
+	if len(filters) == 0 {
+		return query, nil
+	}
+
+	filtersMap := make(map[string]map[string]bool)
+
+	for _, filter := range filters {
+		parts := strings.SplitN(filter, "=", 2)
+		if len(parts) != 2 {
+			return nil, stacktrace.NewError("invalid filter %s, expected key=value", filter)
+		}
+
+		if filtersMap[parts[0]] == nil {
+			filtersMap[parts[0]] = make(map[string]bool)
+		}
+
+		filtersMap[parts[0]][parts[1]] = true
+	}
+
+	content, err := json.Marshal(filtersMap)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "json encode docker API filters failed")
+	}
+
+	query.Set("filters", string(content))
+
+	return query, nil
+}
+
+func isDockerAPINotFound(err error) bool {
+	apiError, ok := stacktrace.RootCause(err).(*DockerAPIError)
+	return ok && apiError.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,445 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	stdOs "os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFakeDockerAPI serves `handler` on a Unix socket and returns a DockerAPI connected to it.
+func newFakeDockerAPI(t *testing.T, options *DockerAPIOptions, handler http.HandlerFunc) *DockerAPI {
+	tmpDir, err := ioutil.TempDir("", "docker-api")
+	require.Nil(t, err)
+
+	socketPath := filepath.Join(tmpDir, "docker.sock")
+
+	listener, err := net.Listen("unix", socketPath)
+	require.Nil(t, err)
+
+	server := httptest.NewUnstartedServer(handler)
+	server.Listener = listener
+	server.Start()
+
+	t.Cleanup(func() {
+		server.Close()
+		_ = stdOs.RemoveAll(tmpDir)
+	})
+
+	if options == nil {
+		options = &DockerAPIOptions{}
+	}
+
+	options.Host = "unix://" + socketPath
+
+	api, err := NewDockerAPI(options)
+	require.Nil(t, err)
+
+	return api
+}
+
+func TestNewDockerAPI(t *testing.T) {
+	t.Run("when the host is a tcp address, it sends requests over http", func(t *testing.T) {
+		actual, err := NewDockerAPI(&DockerAPIOptions{Host: "tcp://127.0.0.1:2375"})
+		require.Nil(t, err)
+		assert.Equal(t, "http", actual.scheme)
+		assert.Equal(t, "127.0.0.1:2375", actual.host)
+	})
+
+	t.Run("when the host scheme is not supported, it returns error", func(t *testing.T) {
+		actual, err := NewDockerAPI(&DockerAPIOptions{Host: "ssh://ci@example.com"})
+		require.NotNil(t, err)
+		assert.Nil(t, actual)
+		assert.Contains(t, err.Error(), "unsupported docker host")
+	})
+}
+
+func TestDockerAPI_Pull(t *testing.T) {
+	t.Run("it pulls the image with the credentials of its registry", func(t *testing.T) {
+		var actualAuth map[string]string
+
+		api := newFakeDockerAPI(
+			t,
+			&DockerAPIOptions{
+				APIVersion: "1.41",
+				Auths:      map[string]*DockerRegistryAuth{"ghcr.io": {Username: "example", Password: "examplePass"}},
+			},
+			func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodPost, r.Method)
+				assert.Equal(t, "/v1.41/images/create", r.URL.Path)
+				assert.Equal(t, "ghcr.io/sumup-oss/app", r.URL.Query().Get("fromImage"))
+				assert.Equal(t, "v1", r.URL.Query().Get("tag"))
+
+				content, err := base64.URLEncoding.DecodeString(r.Header.Get("X-Registry-Auth"))
+				require.Nil(t, err)
+				require.Nil(t, json.Unmarshal(content, &actualAuth))
+
+				_, _ = fmt.Fprintln(w, `{"status":"Pulling from sumup-oss/app","id":"v1"}`)
+				_, _ = fmt.Fprintln(w, `{"status":"Status: Downloaded newer image for ghcr.io/sumup-oss/app:v1"}`)
+			},
+		)
+
+		err := api.Pull(MustParseImageRef("ghcr.io/sumup-oss/app:v1"))
+		require.Nil(t, err)
+		assert.Equal(
+			t,
+			map[string]string{"username": "example", "password": "examplePass", "serveraddress": "ghcr.io"},
+			actualAuth,
+		)
+	})
+
+	t.Run("when the stream reports an error, it returns error", func(t *testing.T) {
+		api := newFakeDockerAPI(
+			t,
+			nil,
+			func(w http.ResponseWriter, r *http.Request) {
+				_, _ = fmt.Fprintln(w, `{"status":"Pulling from library/missing","id":"latest"}`)
+				_, _ = fmt.Fprintln(w, `{"errorDetail":{"message":"manifest unknown"},"error":"manifest unknown"}`)
+			},
+		)
+
+		err := api.Pull(MustParseImageRef("missing"))
+		require.NotNil(t, err)
+		assert.Contains(t, err.Error(), "manifest unknown")
+	})
+}
+
+func TestDockerAPI_PushWithProgress(t *testing.T) {
+	t.Run("it emits the layer progress and returns the pushed digest", func(t *testing.T) {
+		api := newFakeDockerAPI(
+			t,
+			nil,
+			func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodPost, r.Method)
+				assert.Equal(t, "/images/docker.io/example/app/push", r.URL.Path)
+				assert.Equal(t, "v1", r.URL.Query().Get("tag"))
+				assert.NotEmpty(t, r.Header.Get("X-Registry-Auth"))
+
+				_, _ = fmt.Fprintln(w, `{"status":"The push refers to repository [docker.io/example/app]"}`)
+				_, _ = fmt.Fprintln(w, `{"status":"Preparing","progressDetail":{},"id":"5f70bf18a086"}`)
+				_, _ = fmt.Fprintln(w, `{"status":"Pushing","progressDetail":{"current":512,"total":2048},"id":"5f70bf18a086"}`)
+				_, _ = fmt.Fprintln(w, `{"status":"Pushed","progressDetail":{},"id":"5f70bf18a086"}`)
+				_, _ = fmt.Fprintln(w, `{"progressDetail":{},"aux":{"Tag":"v1","Digest":"`+testPushDigest+`","Size":1570}}`)
+			},
+		)
+
+		var actualEvents []*DockerProgressEvent
+
+		actual, err := api.PushWithProgress(
+			MustParseImageRef("example/app:v1"),
+			func(event *DockerProgressEvent) { actualEvents = append(actualEvents, event) },
+		)
+		require.Nil(t, err)
+		assert.Equal(t, testPushDigest, actual)
+		require.Len(t, actualEvents, 5)
+
+		assert.Equal(t, DockerProgressLog, actualEvents[0].Type)
+		assert.Equal(
+			t,
+			&DockerProgressEvent{
+				Type:    DockerProgressLayer,
+				LayerID: "5f70bf18a086",
+				Status:  "Pushing",
+				Current: 512,
+				Total:   2048,
+				Message: "5f70bf18a086: Pushing",
+			},
+			actualEvents[2],
+		)
+		assert.Equal(t, DockerProgressDigest, actualEvents[4].Type)
+		assert.Equal(t, testPushDigest, actualEvents[4].Digest)
+	})
+
+	t.Run("when the image has a digest without a tag, it returns error without pushing", func(t *testing.T) {
+		api := newFakeDockerAPI(
+			t,
+			nil,
+			func(w http.ResponseWriter, r *http.Request) {
+				t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			},
+		)
+
+		actual, err := api.PushWithProgress(MustParseImageRef("example/app@"+testPushDigest), nil)
+		require.NotNil(t, err)
+		assert.Equal(t, "", actual)
+		assert.Contains(t, err.Error(), "without a tag")
+	})
+}
+
+func TestDockerAPI_ImageExists(t *testing.T) {
+	t.Run("when the image does not exist, it returns false", func(t *testing.T) {
+		api := newFakeDockerAPI(
+			t,
+			nil,
+			func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/images/docker.io/library/missing:latest/json", r.URL.Path)
+
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = fmt.Fprint(w, `{"message":"No such image: missing:latest"}`)
+			},
+		)
+
+		actual, err := api.ImageExists(MustParseImageRef("missing"))
+		require.Nil(t, err)
+		assert.False(t, actual)
+	})
+
+	t.Run("when the daemon fails, it returns error", func(t *testing.T) {
+		api := newFakeDockerAPI(
+			t,
+			nil,
+			func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+				_, _ = fmt.Fprint(w, `{"message":"fake error"}`)
+			},
+		)
+
+		actual, err := api.ImageExists(MustParseImageRef("alpine"))
+		require.NotNil(t, err)
+		assert.False(t, actual)
+
+		apiError, ok := err.(*DockerAPIError)
+		require.True(t, ok)
+		assert.Equal(t, http.StatusInternalServerError, apiError.StatusCode)
+		assert.Equal(t, "fake error", apiError.Message)
+	})
+}
+
+func TestDockerAPI_Inspect(t *testing.T) {
+	t.Run("it parses the container", func(t *testing.T) {
+		api := newFakeDockerAPI(
+			t,
+			nil,
+			func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/containers/git-server/json", r.URL.Path)
+
+				_, _ = fmt.Fprint(
+					w,
+					`{"Id":"4f1c0e2d9a7b","Name":"/git-server","State":{"Status":"running","Running":true},`+
+						`"NetworkSettings":{"Ports":{"22/tcp":[{"HostIp":"0.0.0.0","HostPort":"2222"}]}}}`,
+				)
+			},
+		)
+
+		actual, err := api.Inspect("git-server")
+		require.Nil(t, err)
+		assert.Equal(t, "4f1c0e2d9a7b", actual.ID)
+		assert.True(t, actual.State.Running)
+		assert.Equal(t, "2222", actual.HostPort("22"))
+	})
+}
+
+func TestDockerAPI_Stop(t *testing.T) {
+	t.Run("it passes the timeout in seconds", func(t *testing.T) {
+		api := newFakeDockerAPI(
+			t,
+			nil,
+			func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodPost, r.Method)
+				assert.Equal(t, "/containers/git-server/stop", r.URL.Path)
+				assert.Equal(t, "30", r.URL.Query().Get("t"))
+
+				w.WriteHeader(http.StatusNoContent)
+			},
+		)
+
+		err := api.Stop("git-server", 30*time.Second)
+		require.Nil(t, err)
+	})
+
+	t.Run("it rounds the timeout up to whole seconds", func(t *testing.T) {
+		api := newFakeDockerAPI(
+			t,
+			nil,
+			func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "2", r.URL.Query().Get("t"))
+
+				w.WriteHeader(http.StatusNoContent)
+			},
+		)
+
+		err := api.Stop("git-server", 1500*time.Millisecond)
+		require.Nil(t, err)
+	})
+}
+
+func TestDockerAPI_NetworkCreate(t *testing.T) {
+	t.Run("it sends the options and returns the network id", func(t *testing.T) {
+		var actualRequest map[string]interface{}
+
+		api := newFakeDockerAPI(
+			t,
+			nil,
+			func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodPost, r.Method)
+				assert.Equal(t, "/networks/create", r.URL.Path)
+				require.Nil(t, json.NewDecoder(r.Body).Decode(&actualRequest))
+
+				w.WriteHeader(http.StatusCreated)
+				_, _ = fmt.Fprint(w, `{"Id":"9c1e5a0d3f2b","Warning":""}`)
+			},
+		)
+
+		actual, err := api.NetworkCreate(
+			&DockerNetworkCreateOptions{
+				Name:    "ci-42",
+				Driver:  "bridge",
+				Subnet:  "172.28.0.0/16",
+				Gateway: "172.28.0.1",
+				Labels:  map[string]string{"ci.job": "42"},
+			},
+		)
+		require.Nil(t, err)
+		assert.Equal(t, "9c1e5a0d3f2b", actual)
+		assert.Equal(
+			t,
+			map[string]interface{}{
+				"Name":           "ci-42",
+				"Driver":         "bridge",
+				"CheckDuplicate": true,
+				"Internal":       false,
+				"Attachable":     false,
+				"Labels":         map[string]interface{}{"ci.job": "42"},
+				"IPAM": map[string]interface{}{
+					"Config": []interface{}{map[string]interface{}{"Subnet": "172.28.0.0/16", "Gateway": "172.28.0.1"}},
+				},
+			},
+			actualRequest,
+		)
+	})
+
+	t.Run("when the name is missing, it returns error without creating", func(t *testing.T) {
+		api := newFakeDockerAPI(
+			t,
+			nil,
+			func(w http.ResponseWriter, r *http.Request) {
+				t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			},
+		)
+
+		for _, options := range []*DockerNetworkCreateOptions{nil, {Driver: "bridge"}} {
+			actual, err := api.NetworkCreate(options)
+			require.NotNil(t, err)
+			assert.Equal(t, "", actual)
+			assert.Contains(t, err.Error(), "network name is required")
+		}
+	})
+}
+
+func TestDockerAPI_NetworkGateway(t *testing.T) {
+	t.Run("it returns the local address routing to the gateway", func(t *testing.T) {
+		api := newFakeDockerAPI(
+			t,
+			nil,
+			func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/networks/ci-42", r.URL.Path)
+
+				_, _ = fmt.Fprint(w, `{"Name":"ci-42","Id":"9c1e5a0d3f2b","IPAM":{"Config":[{"Subnet":"127.0.0.0/8"}]}}`)
+			},
+		)
+
+		actual, err := api.NetworkGateway("ci-42")
+		require.Nil(t, err)
+		assert.Equal(t, "127.0.0.1", actual)
+	})
+}
+
+func TestDockerAPI_NetworkList(t *testing.T) {
+	t.Run("it passes the filters as json and inspects the listed networks", func(t *testing.T) {
+		api := newFakeDockerAPI(
+			t,
+			nil,
+			func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/networks":
+					assert.JSONEq(t, `{"label":{"ci.job=42":true},"driver":{"bridge":true}}`, r.URL.Query().Get("filters"))
+
+					_, _ = fmt.Fprint(
+						w,
+						`[{"Name":"ci-42","Id":"9c1e5a0d3f2b","Driver":"bridge","Labels":{"ci.job":"42"}},`+
+							`{"Name":"ci-42-removed","Id":"7a3d1b9e4c6f","Driver":"bridge"}]`,
+					)
+				case "/networks/9c1e5a0d3f2b":
+					_, _ = fmt.Fprint(
+						w,
+						`{"Name":"ci-42","Id":"9c1e5a0d3f2b","Driver":"bridge","Labels":{"ci.job":"42"},`+
+							`"Containers":{"4f1c0e2d9a7b":{"Name":"git-server","IPv4Address":"172.28.0.10/16"}}}`,
+					)
+				default:
+					w.WriteHeader(http.StatusNotFound)
+					_, _ = fmt.Fprint(w, `{"message":"network 7a3d1b9e4c6f not found"}`)
+				}
+			},
+		)
+
+		actual, err := api.NetworkList([]string{"label=ci.job=42", "driver=bridge"})
+		require.Nil(t, err)
+		require.Len(t, actual, 1)
+		assert.Equal(t, "ci-42", actual[0].Name)
+		require.Contains(t, actual[0].Containers, "4f1c0e2d9a7b")
+		assert.Equal(t, "git-server", actual[0].Containers["4f1c0e2d9a7b"].Name)
+	})
+}
+
+func TestDockerAPI_VolumeList(t *testing.T) {
+	t.Run("it returns the listed volumes", func(t *testing.T) {
+		api := newFakeDockerAPI(
+			t,
+			nil,
+			func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/volumes", r.URL.Path)
+
+				_, _ = fmt.Fprint(w, `{"Volumes":[{"Name":"ci-42-cache","Driver":"local"}],"Warnings":null}`)
+			},
+		)
+
+		actual, err := api.VolumeList(nil)
+		require.Nil(t, err)
+		require.Len(t, actual, 1)
+		assert.Equal(t, "ci-42-cache", actual[0].Name)
+	})
+}
+
+func TestDockerAPI_VolumeRemove(t *testing.T) {
+	t.Run("when forced and the volume does not exist, it returns nil", func(t *testing.T) {
+		api := newFakeDockerAPI(
+			t,
+			nil,
+			func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodDelete, r.Method)
+				assert.Equal(t, "/volumes/missing", r.URL.Path)
+				assert.Equal(t, "true", r.URL.Query().Get("force"))
+
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = fmt.Fprint(w, `{"message":"get missing: no such volume"}`)
+			},
+		)
+
+		err := api.VolumeRemove("missing", true)
+		require.Nil(t, err)
+	})
+}
@@ -0,0 +1,185 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	stdOs "os"
+	"path/filepath"
+	"strings"
+
+	"github.com/palantir/stacktrace"
+
+	"github.com/sumup-oss/go-pkgs/os"
+)
+
+const (
+	dockerConfigEnv  = "DOCKER_CONFIG"
+	dockerConfigFile = "config.json"
+	// dockerHubAuthKey is the key docker stores Docker Hub credentials under.
+	dockerHubAuthKey = "https://index.docker.io/v1/"
+)
+
+type DockerRegistryAuth struct {
+	Username string
+	Password string
+	// IdentityToken is an OAuth refresh token, used instead of Username and Password.
+	IdentityToken string
+}
+
+type DockerConfigOptions struct {
+	// Auths are keyed by registry host, e.g "ghcr.io". "docker.io" is Docker Hub.
+	Auths map[string]*DockerRegistryAuth
+	// CredsStore is the credential helper of all registries,
+	// e.g "ecr-login" for `docker-credential-ecr-login`.
+	CredsStore string
+	// CredHelpers are credential helpers keyed by registry host, taking precedence over CredsStore.
+	CredHelpers map[string]string
+}
+
+type dockerConfigAuth struct {
+	Auth          string `json:"auth,omitempty"`
+	IdentityToken string `json:"identitytoken,omitempty"`
+}
+
+type dockerConfig struct {
+	Auths       map[string]*dockerConfigAuth `json:"auths"`
+	CredsStore  string                       `json:"credsStore,omitempty"`
+	CredHelpers map[string]string            `json:"credHelpers,omitempty"`
+}
+
+// NewDockerWithConfigDir creates a Docker instance that uses `configDir` as DOCKER_CONFIG,
+// so that logins and credentials don't touch the user's docker config.
+func NewDockerWithConfigDir(executor os.CommandExecutor, configDir string) *Docker {
+	docker := NewDocker(executor)
+	docker.configDir = configDir
+
+	return docker
+}
+
+// NewDockerWithConfig creates a Docker instance with a temporary DOCKER_CONFIG directory,
+// holding the registry auths and credential helpers of `options`.
+// The returned function removes the directory and must be called once the instance is no longer used.
+func NewDockerWithConfig(executor os.CommandExecutor, options *DockerConfigOptions) (*Docker, func() error, error) {
+	if options == nil {
+		options = &DockerConfigOptions{}
+	}
+
+	configDir, err := ioutil.TempDir("", "docker-config")
+	if err != nil {
+		return nil, nil, stacktrace.Propagate(err, "failed to create docker config dir")
+	}
+
+	cleanup := func() error {
+		return stacktrace.Propagate(stdOs.RemoveAll(configDir), "failed to remove docker config dir %s", configDir)
+	}
+
+	config := &dockerConfig{
+		Auths:       make(map[string]*dockerConfigAuth),
+		CredsStore:  options.CredsStore,
+		CredHelpers: options.CredHelpers,
+	}
+
+	for registry, auth := range options.Auths {
+		configAuth := &dockerConfigAuth{IdentityToken: auth.IdentityToken}
+		if auth.Username != "" || auth.Password != "" {
+			configAuth.Auth = base64.StdEncoding.EncodeToString([]byte(auth.Username + ":" + auth.Password))
+		}
+
+		config.Auths[dockerConfigAuthKey(registry)] = configAuth
+	}
+
+	content, err := json.Marshal(config)
+	if err != nil {
+		_ = cleanup()
+
+		return nil, nil, stacktrace.Propagate(err, "failed to encode docker config")
+	}
+
+	// NOTE: The file holds credentials, so it's readable only by the current user,
+	// like the temporary directory it's in.
+	configPath := filepath.Join(configDir, dockerConfigFile)
+
+	err = ioutil.WriteFile(configPath, content, 0600)
+	if err != nil {
+		_ = cleanup()
+
+		return nil, nil, stacktrace.Propagate(err, "failed to write docker config %s", configPath)
+	}
+
+	return NewDockerWithConfigDir(executor, configDir), cleanup, nil
+}
+
+// ConfigDir returns the DOCKER_CONFIG directory, or an empty string for docker's default.
+func (docker *Docker) ConfigDir() string {
+	return docker.configDir
+}
+
+// Login logs in to `registryUrl`, or Docker Hub when it's empty.
+// The password is passed through stdin, so the command executor must implement os.StdinExecutor.
+func (docker *Docker) Login(username, password, registryUrl string) error {
+	stdinExecutor, ok := docker.commandExecutor.(os.StdinExecutor)
+	if !ok {
+		return stacktrace.NewError("command executor does not support stdin")
+	}
+
+	args := []string{"login", fmt.Sprintf("--username=%s", username), "--password-stdin"}
+	if registryUrl != "" {
+		args = append(args, registryUrl)
+	}
+
+	stdout, stderr, err := stdinExecutor.ExecuteWithStdin(
+		docker.binaryPath,
+		args,
+		docker.env(nil),
+		"",
+		strings.NewReader(password),
+	)
+
+	return stacktrace.Propagate(err, "Stderr: %s, Stdout: %s", stderr, stdout)
+}
+
+// Logout removes the credentials of `registryUrl`, or Docker Hub when it's empty.
+func (docker *Docker) Logout(registryUrl string) error {
+	args := []string{"logout"}
+	if registryUrl != "" {
+		args = append(args, registryUrl)
+	}
+
+	stdout, stderr, err := docker.commandExecutor.Execute(docker.binaryPath, args, docker.env(nil), "")
+	return stacktrace.Propagate(err, "Stderr: %s, Stdout: %s", stderr, stdout)
+}
+
+// env returns `env` with DOCKER_CONFIG when the instance has a config dir.
+// It returns `env` unchanged otherwise, so that nil keeps inheriting the environment of the current process.
+func (docker *Docker) env(env []string) []string {
+	if docker.configDir == "" {
+		return env
+	}
+
+	return extendEnv(env, []string{fmt.Sprintf("%s=%s", dockerConfigEnv, docker.configDir)})
+}
+
+func dockerConfigAuthKey(registry string) string {
+	switch registry {
+	case "", DefaultImageRegistry, "index.docker.io", "registry-1.docker.io":
+		return dockerHubAuthKey
+	default:
+		return registry
+	}
+}
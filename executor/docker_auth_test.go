@@ -0,0 +1,170 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	stdOs "os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sumup-oss/go-pkgs/os/ostest"
+)
+
+func TestDocker_Login(t *testing.T) {
+	t.Run("when log-in does not fail, it passes the password through stdin", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+		usernameArg := "example"
+		passwordArg := "examplePass"
+		registryUrlArg := "exampleRegistry"
+
+		executorArg.On(
+			"ExecuteWithStdin",
+			"docker",
+			[]string{"login", "--username=example", "--password-stdin", registryUrlArg},
+			[]string(nil),
+			"",
+			passwordArg,
+		).Return([]byte("Login Succeeded\n"), []byte{}, nil)
+
+		dockerInstance := NewDocker(executorArg)
+		actual := dockerInstance.Login(usernameArg, passwordArg, registryUrlArg)
+		require.Nil(t, actual)
+		executorArg.AssertNotCalled(t, "Execute", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("when log-in fails, it returns error", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+		usernameArg := "example"
+		passwordArg := "examplePass"
+
+		fakeError := errors.New("fake error")
+		fakeStdout := []byte("fake stdout")
+		fakeStderr := []byte("fake stderr")
+		executorArg.On(
+			"ExecuteWithStdin",
+			"docker",
+			[]string{"login", "--username=example", "--password-stdin"},
+			[]string(nil),
+			"",
+			passwordArg,
+		).Return(fakeStdout, fakeStderr, fakeError)
+
+		dockerInstance := NewDocker(executorArg)
+		actual := dockerInstance.Login(usernameArg, passwordArg, "")
+		require.NotNil(t, actual)
+		assert.Contains(t, actual.Error(), fakeError.Error())
+		assert.Contains(t, actual.Error(), string(fakeStdout))
+		assert.Contains(t, actual.Error(), string(fakeStderr))
+		assert.NotContains(t, actual.Error(), passwordArg)
+	})
+}
+
+func TestDocker_Logout(t *testing.T) {
+	t.Run("it logs out of the registry", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"logout", "ghcr.io"},
+			[]string(nil),
+			"",
+		).Return([]byte("Removing login credentials for ghcr.io\n"), []byte{}, nil)
+
+		dockerInstance := NewDocker(executorArg)
+		actual := dockerInstance.Logout("ghcr.io")
+		require.Nil(t, actual)
+	})
+}
+
+func TestNewDockerWithConfig(t *testing.T) {
+	t.Run("it writes the auths and credential helpers to a temporary DOCKER_CONFIG", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		dockerInstance, cleanup, err := NewDockerWithConfig(
+			executorArg,
+			&DockerConfigOptions{
+				Auths: map[string]*DockerRegistryAuth{
+					"docker.io": {Username: "example", Password: "examplePass"},
+					"ghcr.io":   {IdentityToken: "exampleToken"},
+				},
+				CredHelpers: map[string]string{"123456789012.dkr.ecr.eu-west-1.amazonaws.com": "ecr-login"},
+			},
+		)
+		require.Nil(t, err)
+
+		configDir := dockerInstance.ConfigDir()
+		configPath := filepath.Join(configDir, "config.json")
+
+		fileInfo, err := stdOs.Stat(configPath)
+		require.Nil(t, err)
+		assert.Equal(t, stdOs.FileMode(0600), fileInfo.Mode().Perm())
+
+		content, err := ioutil.ReadFile(configPath)
+		require.Nil(t, err)
+
+		var actualConfig map[string]interface{}
+		require.Nil(t, json.Unmarshal(content, &actualConfig))
+		assert.Equal(
+			t,
+			map[string]interface{}{
+				"auths": map[string]interface{}{
+					"https://index.docker.io/v1/": map[string]interface{}{"auth": "ZXhhbXBsZTpleGFtcGxlUGFzcw=="},
+					"ghcr.io":                     map[string]interface{}{"identitytoken": "exampleToken"},
+				},
+				"credHelpers": map[string]interface{}{"123456789012.dkr.ecr.eu-west-1.amazonaws.com": "ecr-login"},
+			},
+			actualConfig,
+		)
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"pull", "ghcr.io/sumup-oss/app:v1"},
+			mock.MatchedBy(func(env []string) bool {
+				return len(env) > 0 && env[len(env)-1] == "DOCKER_CONFIG="+configDir
+			}),
+			"",
+		).Return([]byte{}, []byte{}, nil)
+
+		err = dockerInstance.Pull(MustParseImageRef("ghcr.io/sumup-oss/app:v1"))
+		require.Nil(t, err)
+
+		require.Nil(t, cleanup())
+
+		_, err = stdOs.Stat(configDir)
+		assert.True(t, stdOs.IsNotExist(err))
+	})
+
+	t.Run("when options are nil, it writes an empty config", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		dockerInstance, cleanup, err := NewDockerWithConfig(executorArg, nil)
+		require.Nil(t, err)
+
+		defer cleanup()
+
+		content, err := ioutil.ReadFile(filepath.Join(dockerInstance.ConfigDir(), "config.json"))
+		require.Nil(t, err)
+		assert.JSONEq(t, `{"auths":{}}`, string(content))
+	})
+}
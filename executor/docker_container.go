@@ -0,0 +1,414 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/palantir/stacktrace"
+)
+
+// DockerPortMapping publishes ContainerPort on HostPort of HostIP.
+// An empty HostPort lets docker pick a free port, an empty Protocol means "tcp".
+type DockerPortMapping struct {
+	HostIP        string
+	HostPort      string
+	ContainerPort string
+	Protocol      string
+}
+
+// DockerVolumeMount mounts Source, a host path or a named volume, at Target in the container.
+type DockerVolumeMount struct {
+	Source   string
+	Target   string
+	ReadOnly bool
+}
+
+type DockerRunOptions struct {
+	Image *ImageRef
+	Name  string
+	// Command overrides the image's CMD.
+	Command []string
+	// Entrypoint overrides the image's ENTRYPOINT.
+	Entrypoint string
+	// Env are "KEY=VALUE" variables.
+	Env     []string
+	Ports   []*DockerPortMapping
+	Volumes []*DockerVolumeMount
+	Network string
+	Labels  map[string]string
+	User    string
+	Workdir string
+	// Detach runs the container in the background.
+	Detach bool
+	// Remove removes the container when it exits.
+	Remove      bool
+	Interactive bool
+	TTY         bool
+}
+
+type DockerLogsOptions struct {
+	// Tail limits the output to the last lines, when positive.
+	Tail int
+	// Since limits the output to logs newer than a timestamp, e.g "2019-06-01T10:00:00Z", or a duration, e.g "10m".
+	Since      string
+	Timestamps bool
+}
+
+type DockerExecOptions struct {
+	// Env are "KEY=VALUE" variables.
+	Env     []string
+	User    string
+	Workdir string
+}
+
+// DockerPortBinding is a host address a container port is published on.
+type DockerPortBinding struct {
+	HostIP   string `json:"HostIp"`
+	HostPort string `json:"HostPort"`
+}
+
+type DockerContainerHealthLog struct {
+	Start    time.Time `json:"Start"`
+	End      time.Time `json:"End"`
+	ExitCode int       `json:"ExitCode"`
+	Output   string    `json:"Output"`
+}
+
+type DockerContainerHealth struct {
+	// Status is "starting", "healthy" or "unhealthy".
+	Status        string                      `json:"Status"`
+	FailingStreak int                         `json:"FailingStreak"`
+	Log           []*DockerContainerHealthLog `json:"Log"`
+}
+
+type DockerContainerState struct {
+	// Status is "created", "running", "paused", "restarting", "removing", "exited" or "dead".
+	Status     string    `json:"Status"`
+	Running    bool      `json:"Running"`
+	Paused     bool      `json:"Paused"`
+	Restarting bool      `json:"Restarting"`
+	OOMKilled  bool      `json:"OOMKilled"`
+	Dead       bool      `json:"Dead"`
+	Pid        int       `json:"Pid"`
+	ExitCode   int       `json:"ExitCode"`
+	Error      string    `json:"Error"`
+	StartedAt  time.Time `json:"StartedAt"`
+	FinishedAt time.Time `json:"FinishedAt"`
+	// Health is nil, unless the container has a health check.
+	Health *DockerContainerHealth `json:"Health"`
+}
+
+type DockerContainerConfig struct {
+	Hostname   string            `json:"Hostname"`
+	Image      string            `json:"Image"`
+	Env        []string          `json:"Env"`
+	Cmd        []string          `json:"Cmd"`
+	Entrypoint []string          `json:"Entrypoint"`
+	Labels     map[string]string `json:"Labels"`
+}
+
+type DockerContainerNetwork struct {
+	IPAddress string   `json:"IPAddress"`
+	Gateway   string   `json:"Gateway"`
+	Aliases   []string `json:"Aliases"`
+}
+
+type DockerContainerNetworkSettings struct {
+	// Ports are keyed by "<port>/<protocol>", e.g "22/tcp".
+	Ports    map[string][]*DockerPortBinding    `json:"Ports"`
+	Networks map[string]*DockerContainerNetwork `json:"Networks"`
+}
+
+// DockerContainer is the parsed output of `docker container inspect`.
+type DockerContainer struct {
+	ID              string                          `json:"Id"`
+	Name            string                          `json:"Name"`
+	Image           string                          `json:"Image"`
+	Created         time.Time                       `json:"Created"`
+	State           *DockerContainerState           `json:"State"`
+	Config          *DockerContainerConfig          `json:"Config"`
+	NetworkSettings *DockerContainerNetworkSettings `json:"NetworkSettings"`
+}
+
+// HostPort returns the host port `containerPort`, e.g "22/tcp", is published on,
+// or an empty string when it's not published.
+func (container *DockerContainer) HostPort(containerPort string) string {
+	if !strings.Contains(containerPort, "/") {
+		containerPort += "/tcp"
+	}
+
+	if container.NetworkSettings == nil {
+		return ""
+	}
+
+	for _, binding := range container.NetworkSettings.Ports[containerPort] {
+		if binding.HostPort != "" {
+			return binding.HostPort
+		}
+	}
+
+	return ""
+}
+
+// Run creates and starts a container.
+// It returns the container ID when detached, otherwise the container's stdout.
+func (docker *Docker) Run(options *DockerRunOptions) (string, error) {
+	if options.Image == nil {
+		return "", stacktrace.NewError("docker run option Image is required")
+	}
+
+	args := []string{"run"}
+
+	if options.Name != "" {
+		args = append(args, fmt.Sprintf("--name=%s", options.Name))
+	}
+
+	if options.Detach {
+		args = append(args, "--detach")
+	}
+
+	if options.Remove {
+		args = append(args, "--rm")
+	}
+
+	if options.Interactive {
+		args = append(args, "--interactive")
+	}
+
+	if options.TTY {
+		args = append(args, "--tty")
+	}
+
+	if options.Entrypoint != "" {
+		args = append(args, fmt.Sprintf("--entrypoint=%s", options.Entrypoint))
+	}
+
+	if options.Network != "" {
+		args = append(args, fmt.Sprintf("--network=%s", options.Network))
+	}
+
+	if options.User != "" {
+		args = append(args, fmt.Sprintf("--user=%s", options.User))
+	}
+
+	if options.Workdir != "" {
+		args = append(args, fmt.Sprintf("--workdir=%s", options.Workdir))
+	}
+
+	for _, variable := range options.Env {
+		args = append(args, fmt.Sprintf("--env=%s", variable))
+	}
+
+	for _, port := range options.Ports {
+		args = append(args, fmt.Sprintf("--publish=%s", dockerPublishArg(port)))
+	}
+
+	for _, volume := range options.Volumes {
+		volumeArg := fmt.Sprintf("%s:%s", volume.Source, volume.Target)
+		if volume.ReadOnly {
+			volumeArg += ":ro"
+		}
+
+		args = append(args, fmt.Sprintf("--volume=%s", volumeArg))
+	}
+
+	args = append(args, dockerLabelArgs(options.Labels)...)
+	args = append(args, options.Image.String())
+	args = append(args, options.Command...)
+
+	stdout, stderr, err := docker.commandExecutor.Execute(docker.binaryPath, args, docker.env(nil), "")
+	if err != nil {
+		return "", stacktrace.Propagate(err, "Stderr: %s, Stdout: %s", stderr, stdout)
+	}
+
+	if options.Detach {
+		return strings.TrimSpace(string(stdout)), nil
+	}
+
+	return string(stdout), nil
+}
+
+// Stop stops `container`, killing it after `timeout`, rounded up to whole seconds.
+// A zero `timeout` uses docker's default.
+func (docker *Docker) Stop(container string, timeout time.Duration) error {
+	args := []string{"stop"}
+	if timeout > 0 {
+		args = append(args, fmt.Sprintf("--time=%d", ceilSeconds(timeout)))
+	}
+
+	args = append(args, container)
+
+	stdout, stderr, err := docker.commandExecutor.Execute(docker.binaryPath, args, docker.env(nil), "")
+	return stacktrace.Propagate(err, "Stderr: %s, Stdout: %s", stderr, stdout)
+}
+
+// Remove removes `container`.
+// When `force` is true, a running container is killed. When `volumes` is true, its anonymous volumes are removed.
+func (docker *Docker) Remove(container string, force, volumes bool) error {
+	args := []string{"rm"}
+
+	if force {
+		args = append(args, "--force")
+	}
+
+	if volumes {
+		args = append(args, "--volumes")
+	}
+
+	args = append(args, container)
+
+	stdout, stderr, err := docker.commandExecutor.Execute(docker.binaryPath, args, docker.env(nil), "")
+	return stacktrace.Propagate(err, "Stderr: %s, Stdout: %s", stderr, stdout)
+}
+
+// Logs returns the stdout and stderr of `container`.
+func (docker *Docker) Logs(container string, options *DockerLogsOptions) (string, string, error) {
+	args := []string{"logs"}
+
+	if options != nil {
+		if options.Tail > 0 {
+			args = append(args, fmt.Sprintf("--tail=%d", options.Tail))
+		}
+
+		if options.Since != "" {
+			args = append(args, fmt.Sprintf("--since=%s", options.Since))
+		}
+
+		if options.Timestamps {
+			args = append(args, "--timestamps")
+		}
+	}
+
+	args = append(args, container)
+
+	stdout, stderr, err := docker.commandExecutor.Execute(docker.binaryPath, args, docker.env(nil), "")
+	if err != nil {
+		return "", "", stacktrace.Propagate(err, "Stderr: %s, Stdout: %s", stderr, stdout)
+	}
+
+	return string(stdout), string(stderr), nil
+}
+
+// Exec runs `command` in the running `container` and returns its stdout.
+func (docker *Docker) Exec(container string, command []string, options *DockerExecOptions) (string, error) {
+	args := []string{"exec"}
+
+	if options != nil {
+		if options.User != "" {
+			args = append(args, fmt.Sprintf("--user=%s", options.User))
+		}
+
+		if options.Workdir != "" {
+			args = append(args, fmt.Sprintf("--workdir=%s", options.Workdir))
+		}
+
+		for _, variable := range options.Env {
+			args = append(args, fmt.Sprintf("--env=%s", variable))
+		}
+	}
+
+	args = append(args, container)
+	args = append(args, command...)
+
+	stdout, stderr, err := docker.commandExecutor.Execute(docker.binaryPath, args, docker.env(nil), "")
+	if err != nil {
+		return "", stacktrace.Propagate(err, "Stderr: %s, Stdout: %s", stderr, stdout)
+	}
+
+	return string(stdout), nil
+}
+
+// Inspect returns the configuration and state of `container`.
+func (docker *Docker) Inspect(container string) (*DockerContainer, error) {
+	stdout, stderr, err := docker.commandExecutor.Execute(
+		docker.binaryPath,
+		[]string{"container", "inspect", container},
+		docker.env(nil),
+		"",
+	)
+	if err != nil {
+		return nil, stacktrace.Propagate(
+			err,
+			"executing `docker container inspect %s` failed. Stderr: %s",
+			container,
+			stderr,
+		)
+	}
+
+	var containers []*DockerContainer
+	err = json.Unmarshal(stdout, &containers)
+	if err != nil {
+		return nil, stacktrace.Propagate(
+			err,
+			"json decode command `docker container inspect %s` output failed",
+			container,
+		)
+	}
+
+	if len(containers) == 0 {
+		return nil, stacktrace.NewError("docker container %s not found", container)
+	}
+
+	return containers[0], nil
+}
+
+func dockerPublishArg(port *DockerPortMapping) string {
+	containerPort := port.ContainerPort
+	if port.Protocol != "" {
+		containerPort = fmt.Sprintf("%s/%s", containerPort, port.Protocol)
+	}
+
+	switch {
+	case port.HostIP != "":
+		return fmt.Sprintf("%s:%s:%s", port.HostIP, port.HostPort, containerPort)
+	case port.HostPort != "":
+		return fmt.Sprintf("%s:%s", port.HostPort, containerPort)
+	default:
+		return containerPort
+	}
+}
+
+// ceilSeconds rounds `duration` up to whole seconds,
+// since docker only accepts timeouts in seconds and a truncated timeout of zero kills immediately.
+func ceilSeconds(duration time.Duration) int {
+	seconds := duration / time.Second
+	if duration%time.Second > 0 {
+		seconds++
+	}
+
+	return int(seconds)
+}
+
+// dockerLabelArgs returns `--label` arguments sorted by key, so that the arguments are deterministic.
+func dockerLabelArgs(labels map[string]string) []string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	args := make([]string, 0, len(keys))
+	for _, key := range keys {
+		args = append(args, fmt.Sprintf("--label=%s=%s", key, labels[key]))
+	}
+
+	return args
+}
@@ -0,0 +1,306 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sumup-oss/go-pkgs/os/ostest"
+)
+
+func TestDocker_Run(t *testing.T) {
+	t.Run("when detached, it passes the options and returns the container id", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{
+				"run",
+				"--name=git-server",
+				"--detach",
+				"--rm",
+				"--entrypoint=/bin/sh",
+				"--network=ci",
+				"--env=GIT_USER=git",
+				"--publish=127.0.0.1:2222:22/tcp",
+				"--publish=8080:80",
+				"--publish=9090",
+				"--volume=/tmp/repos:/srv/git:ro",
+				"--volume=cache:/cache",
+				"--label=ci.job=42",
+				"--label=owner=platform",
+				"docker.io/example/git-server:v1",
+				"-c",
+				"sshd -D",
+			},
+			[]string(nil),
+			"",
+		).Return([]byte("4f1c0e2d9a7b\n"), []byte{}, nil)
+
+		dockerInstance := NewDocker(executorArg)
+		actual, actualErr := dockerInstance.Run(
+			&DockerRunOptions{
+				Image:      MustParseImageRef("example/git-server:v1"),
+				Name:       "git-server",
+				Command:    []string{"-c", "sshd -D"},
+				Entrypoint: "/bin/sh",
+				Env:        []string{"GIT_USER=git"},
+				Ports: []*DockerPortMapping{
+					{HostIP: "127.0.0.1", HostPort: "2222", ContainerPort: "22", Protocol: "tcp"},
+					{HostPort: "8080", ContainerPort: "80"},
+					{ContainerPort: "9090"},
+				},
+				Volumes: []*DockerVolumeMount{
+					{Source: "/tmp/repos", Target: "/srv/git", ReadOnly: true},
+					{Source: "cache", Target: "/cache"},
+				},
+				Network: "ci",
+				Labels:  map[string]string{"owner": "platform", "ci.job": "42"},
+				Detach:  true,
+				Remove:  true,
+			},
+		)
+		require.Nil(t, actualErr)
+		assert.Equal(t, "4f1c0e2d9a7b", actual)
+	})
+
+	t.Run("when the image is missing, it returns error without running", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		dockerInstance := NewDocker(executorArg)
+		actual, actualErr := dockerInstance.Run(&DockerRunOptions{Command: []string{"true"}})
+		require.NotNil(t, actualErr)
+		assert.Equal(t, "", actual)
+		assert.Contains(t, actualErr.Error(), "Image is required")
+		executorArg.AssertNotCalled(t, "Execute", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("when running fails, it returns error", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		fakeError := errors.New("fake error")
+		fakeStdout := []byte("fake stdout")
+		fakeStderr := []byte("fake stderr")
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"run", "docker.io/library/example:latest", "true"},
+			[]string(nil),
+			"",
+		).Return(fakeStdout, fakeStderr, fakeError)
+
+		dockerInstance := NewDocker(executorArg)
+		actual, actualErr := dockerInstance.Run(&DockerRunOptions{Image: MustParseImageRef("example"), Command: []string{"true"}})
+		require.NotNil(t, actualErr)
+		assert.Equal(t, "", actual)
+		assert.Contains(t, actualErr.Error(), fakeError.Error())
+		assert.Contains(t, actualErr.Error(), string(fakeStderr))
+	})
+}
+
+func TestDocker_Stop(t *testing.T) {
+	t.Run("it passes the timeout in seconds", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"stop", "--time=30", "git-server"},
+			[]string(nil),
+			"",
+		).Return([]byte("git-server\n"), []byte{}, nil)
+
+		dockerInstance := NewDocker(executorArg)
+		actual := dockerInstance.Stop("git-server", 30*time.Second)
+		require.Nil(t, actual)
+	})
+
+	t.Run("it rounds a sub-second timeout up to a second", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"stop", "--time=1", "git-server"},
+			[]string(nil),
+			"",
+		).Return([]byte("git-server\n"), []byte{}, nil)
+
+		dockerInstance := NewDocker(executorArg)
+		actual := dockerInstance.Stop("git-server", 500*time.Millisecond)
+		require.Nil(t, actual)
+		executorArg.AssertExpectations(t)
+	})
+}
+
+func TestDocker_Remove(t *testing.T) {
+	t.Run("it forces the removal with volumes", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"rm", "--force", "--volumes", "git-server"},
+			[]string(nil),
+			"",
+		).Return([]byte("git-server\n"), []byte{}, nil)
+
+		dockerInstance := NewDocker(executorArg)
+		actual := dockerInstance.Remove("git-server", true, true)
+		require.Nil(t, actual)
+	})
+}
+
+func TestDocker_Logs(t *testing.T) {
+	t.Run("it returns the stdout and stderr of the container", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"logs", "--tail=50", "--since=10m", "git-server"},
+			[]string(nil),
+			"",
+		).Return([]byte("Server listening on :: port 22.\n"), []byte("Invalid user admin\n"), nil)
+
+		dockerInstance := NewDocker(executorArg)
+		actualStdout, actualStderr, actualErr := dockerInstance.Logs(
+			"git-server",
+			&DockerLogsOptions{Tail: 50, Since: "10m"},
+		)
+		require.Nil(t, actualErr)
+		assert.Equal(t, "Server listening on :: port 22.\n", actualStdout)
+		assert.Equal(t, "Invalid user admin\n", actualStderr)
+	})
+}
+
+func TestDocker_Exec(t *testing.T) {
+	t.Run("it runs the command in the container and returns stdout", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"exec", "--user=git", "--env=HOME=/home/git", "git-server", "sh", "add_git_user.sh", "alice"},
+			[]string(nil),
+			"",
+		).Return([]byte("added alice\n"), []byte{}, nil)
+
+		dockerInstance := NewDocker(executorArg)
+		actual, actualErr := dockerInstance.Exec(
+			"git-server",
+			[]string{"sh", "add_git_user.sh", "alice"},
+			&DockerExecOptions{User: "git", Env: []string{"HOME=/home/git"}},
+		)
+		require.Nil(t, actualErr)
+		assert.Equal(t, "added alice\n", actual)
+	})
+}
+
+func TestDocker_Inspect(t *testing.T) {
+	t.Run("it parses the container state, config and published ports", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"container", "inspect", "git-server"},
+			[]string(nil),
+			"",
+		).Return(
+			[]byte(`[
+  {
+    "Id": "4f1c0e2d9a7b",
+    "Created": "2019-06-01T10:00:00.000000000Z",
+    "Name": "/git-server",
+    "Image": "sha256:aa11",
+    "State": {
+      "Status": "running",
+      "Running": true,
+      "Pid": 4242,
+      "ExitCode": 0,
+      "StartedAt": "2019-06-01T10:00:01.5Z",
+      "FinishedAt": "0001-01-01T00:00:00Z",
+      "Health": {
+        "Status": "healthy",
+        "FailingStreak": 0,
+        "Log": [{"Start": "2019-06-01T10:00:31Z", "End": "2019-06-01T10:00:32Z", "ExitCode": 0, "Output": "ok"}]
+      }
+    },
+    "Config": {
+      "Hostname": "4f1c0e2d9a7b",
+      "Image": "example/git-server:v1",
+      "Env": ["GIT_USER=git"],
+      "Cmd": ["sshd", "-D"],
+      "Entrypoint": null,
+      "Labels": {"owner": "platform"}
+    },
+    "NetworkSettings": {
+      "Ports": {"22/tcp": [{"HostIp": "127.0.0.1", "HostPort": "2222"}], "80/tcp": null},
+      "Networks": {"ci": {"IPAddress": "172.18.0.2", "Gateway": "172.18.0.1", "Aliases": ["git"]}}
+    }
+  }
+]`),
+			[]byte{},
+			nil,
+		)
+
+		dockerInstance := NewDocker(executorArg)
+		actual, actualErr := dockerInstance.Inspect("git-server")
+		require.Nil(t, actualErr)
+
+		assert.Equal(t, "4f1c0e2d9a7b", actual.ID)
+		assert.Equal(t, "/git-server", actual.Name)
+		assert.Equal(t, time.Date(2019, 6, 1, 10, 0, 0, 0, time.UTC), actual.Created.UTC())
+		assert.Equal(t, "running", actual.State.Status)
+		assert.True(t, actual.State.Running)
+		assert.Equal(t, 4242, actual.State.Pid)
+		require.NotNil(t, actual.State.Health)
+		assert.Equal(t, "healthy", actual.State.Health.Status)
+		require.Len(t, actual.State.Health.Log, 1)
+		assert.Equal(t, "ok", actual.State.Health.Log[0].Output)
+		assert.Equal(t, "example/git-server:v1", actual.Config.Image)
+		assert.Equal(t, []string{"sshd", "-D"}, actual.Config.Cmd)
+		assert.Equal(t, map[string]string{"owner": "platform"}, actual.Config.Labels)
+		assert.Equal(t, "172.18.0.2", actual.NetworkSettings.Networks["ci"].IPAddress)
+		assert.Equal(t, "2222", actual.HostPort("22"))
+		assert.Equal(t, "", actual.HostPort("80/tcp"))
+	})
+
+	t.Run("when the container does not exist, it returns error", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"container", "inspect", "missing"},
+			[]string(nil),
+			"",
+		).Return([]byte("[]\n"), []byte("Error: No such container: missing"), errors.New("exit status 1"))
+
+		dockerInstance := NewDocker(executorArg)
+		actual, actualErr := dockerInstance.Inspect("missing")
+		require.NotNil(t, actualErr)
+		assert.Nil(t, actual)
+		assert.Contains(t, actualErr.Error(), "No such container")
+	})
+}
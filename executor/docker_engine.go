@@ -0,0 +1,75 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sumup-oss/go-pkgs/os"
+)
+
+type DockerBackend string
+
+const (
+	// DockerBackendCLI executes the `docker` binary.
+	DockerBackendCLI DockerBackend = "cli"
+	// DockerBackendAPI speaks the Docker Engine HTTP API and requires no `docker` binary.
+	DockerBackendAPI DockerBackend = "api"
+)
+
+// DockerEngine is the set of docker operations supported by every backend.
+// Use Docker directly for operations only the CLI backend supports, e.g Build or Run.
+// Every backend returns the same results, e.g NetworkList returns inspected networks with their Containers,
+// and rejects the same arguments, e.g Push and PushWithProgress reject references without a tag.
+type DockerEngine interface {
+	Pull(image *ImageRef) error
+	Push(image *ImageRef) error
+	PushWithProgress(image *ImageRef, progress DockerProgressFunc) (string, error)
+	Tag(oldImage, newImage *ImageRef) error
+	ImageInspect(image *ImageRef) (*DockerImage, error)
+	ImageExists(image *ImageRef) (bool, error)
+	ImageRemove(images []*ImageRef, force bool) error
+	Inspect(container string) (*DockerContainer, error)
+	Stop(container string, timeout time.Duration) error
+	Remove(container string, force, volumes bool) error
+	NetworkInspect(name string) (*DockerNetwork, error)
+	NetworkGateway(name string) (string, error)
+	NetworkCreate(options *DockerNetworkCreateOptions) (string, error)
+	NetworkRemove(name string) error
+	NetworkConnect(name, container string, options *DockerNetworkConnectOptions) error
+	NetworkDisconnect(name, container string, force bool) error
+	NetworkList(filters []string) ([]*DockerNetwork, error)
+	VolumeCreate(options *DockerVolumeCreateOptions) (string, error)
+	VolumeInspect(name string) (*DockerVolume, error)
+	VolumeList(filters []string) ([]*DockerVolume, error)
+	VolumeRemove(name string, force bool) error
+}
+
+var _ DockerEngine = (*Docker)(nil)
+var _ DockerEngine = (*DockerAPI)(nil)
+
+// NewDockerEngine creates a DockerEngine using `backend`.
+// `executor` is only used by DockerBackendCLI. DockerBackendAPI connects to DOCKER_HOST.
+func NewDockerEngine(backend DockerBackend, executor os.CommandExecutor) (DockerEngine, error) {
+	switch backend {
+	case DockerBackendCLI:
+		return NewDocker(executor), nil
+	case DockerBackendAPI:
+		return NewDockerAPI(nil)
+	default:
+		return nil, fmt.Errorf("unknown docker backend %s", backend)
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/palantir/stacktrace"
+
+	"github.com/sumup-oss/go-pkgs/internal/dockerwait"
+	"github.com/sumup-oss/go-pkgs/task"
+)
+
+const (
+	DefaultWaitHealthyTimeout  = 30 * time.Second
+	DefaultWaitHealthyInterval = time.Second
+	DefaultWaitHealthyLogsTail = dockerwait.DefaultLogsTail
+)
+
+type DockerWaitHealthyOptions struct {
+	// Timeout to wait for, DefaultWaitHealthyTimeout when zero.
+	Timeout time.Duration
+	// Interval between checks, DefaultWaitHealthyInterval when zero.
+	Interval time.Duration
+	// TCPPorts are container ports, e.g "22/tcp", whose published host ports must accept connections.
+	TCPPorts []string
+	// Host the published ports are dialed on, "127.0.0.1" when empty.
+	Host string
+	// Probe is a custom check, called once the other checks pass. The container is healthy when it returns nil.
+	Probe func(container *DockerContainer) error
+	// LogsTail is the number of lines of the container's logs included in DockerWaitTimeoutError,
+	// DefaultWaitHealthyLogsTail when zero.
+	LogsTail int
+}
+
+// DockerWaitTimeoutError is returned when a container is not healthy within the timeout of WaitHealthy.
+type DockerWaitTimeoutError struct {
+	Container string
+	Timeout   time.Duration
+	// Logs are the last lines of the container's stdout and stderr.
+	Logs    string
+	lastErr error
+}
+
+// Error returns the error message.
+func (err *DockerWaitTimeoutError) Error() string {
+	return fmt.Sprintf(
+		"docker container %s not healthy after %v, last err: %v. Logs: %s",
+		err.Container,
+		err.Timeout,
+		err.lastErr,
+		err.Logs,
+	)
+}
+
+// Unwrap returns the last reason the container was not healthy.
+func (err *DockerWaitTimeoutError) Unwrap() error {
+	return err.lastErr
+}
+
+// WaitHealthy waits until `container` is running and healthy.
+// The container is healthy when its health check, if it has one, reports "healthy",
+// its `options.TCPPorts` accept connections and `options.Probe` returns nil.
+// It fails without waiting when the container exits.
+func (docker *Docker) WaitHealthy(container string, options *DockerWaitHealthyOptions) error {
+	if options == nil {
+		options = &DockerWaitHealthyOptions{}
+	}
+
+	timeout := options.Timeout
+	if timeout == 0 {
+		timeout = DefaultWaitHealthyTimeout
+	}
+
+	interval := options.Interval
+	if interval == 0 {
+		interval = DefaultWaitHealthyInterval
+	}
+
+	logs, err := dockerwait.Wait(
+		container,
+		&dockerwait.Options{Timeout: timeout, Interval: interval, LogsTail: options.LogsTail, Logs: docker.tailLogs},
+		func() error {
+			return docker.checkHealthy(container, options, interval)
+		},
+	)
+	if err == nil {
+		return nil
+	}
+
+	var deadlineErr *task.DeadlineRetryError
+	if errors.As(err, &deadlineErr) {
+		return &DockerWaitTimeoutError{
+			Container: container,
+			Timeout:   timeout,
+			Logs:      logs,
+			lastErr:   deadlineErr.Cause(),
+		}
+	}
+
+	return stacktrace.Propagate(err, "Logs: %s", logs)
+}
+
+// checkHealthy returns a retryable error while the container may still become healthy.
+func (docker *Docker) checkHealthy(container string, options *DockerWaitHealthyOptions, dialTimeout time.Duration) error {
+	inspected, err := docker.Inspect(container)
+	if err != nil {
+		return task.NewRetryableError(err)
+	}
+
+	state := inspected.State
+	if state == nil {
+		return task.NewRetryableError(fmt.Errorf("docker container %s has no state", container))
+	}
+
+	if state.Status == "exited" || state.Status == "dead" {
+		return stacktrace.NewError("docker container %s %s with exit code %d", container, state.Status, state.ExitCode)
+	}
+
+	if !state.Running {
+		return task.NewRetryableError(fmt.Errorf("docker container %s is %s", container, state.Status))
+	}
+
+	if state.Health != nil && state.Health.Status != "healthy" {
+		return task.NewRetryableError(fmt.Errorf("docker container %s health is %s", container, state.Health.Status))
+	}
+
+	host := options.Host
+	if host == "" {
+		host = "127.0.0.1"
+	}
+
+	for _, port := range options.TCPPorts {
+		hostPort := inspected.HostPort(port)
+		if hostPort == "" {
+			return task.NewRetryableError(fmt.Errorf("docker container %s port %s is not published", container, port))
+		}
+
+		err = dockerwait.Dial(net.JoinHostPort(host, hostPort), dialTimeout)
+		if err != nil {
+			return err
+		}
+	}
+
+	if options.Probe != nil {
+		err = options.Probe(inspected)
+		if err != nil {
+			return task.NewRetryableError(err)
+		}
+	}
+
+	return nil
+}
+
+func (docker *Docker) tailLogs(container string, tail int) (string, string, error) {
+	return docker.Logs(container, &DockerLogsOptions{Tail: tail})
+}
@@ -0,0 +1,196 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sumup-oss/go-pkgs/os/ostest"
+)
+
+func testContainerInspectOutput(state, ports string) []byte {
+	return []byte(
+		fmt.Sprintf(
+			`[{"Id":"4f1c0e2d9a7b","Name":"/git-server","State":%s,"NetworkSettings":{"Ports":%s}}]`,
+			state,
+			ports,
+		),
+	)
+}
+
+func TestDocker_WaitHealthy(t *testing.T) {
+	t.Run("when the health check reports healthy, it returns nil", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"container", "inspect", "git-server"},
+			[]string(nil),
+			"",
+		).Return(
+			testContainerInspectOutput(`{"Status":"running","Running":true,"Health":{"Status":"starting"}}`, `{}`),
+			[]byte{},
+			nil,
+		).Once()
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"container", "inspect", "git-server"},
+			[]string(nil),
+			"",
+		).Return(
+			testContainerInspectOutput(`{"Status":"running","Running":true,"Health":{"Status":"healthy"}}`, `{}`),
+			[]byte{},
+			nil,
+		).Once()
+
+		dockerInstance := NewDocker(executorArg)
+		err := dockerInstance.WaitHealthy(
+			"git-server",
+			&DockerWaitHealthyOptions{Timeout: time.Second, Interval: time.Millisecond},
+		)
+		require.Nil(t, err)
+		executorArg.AssertExpectations(t)
+	})
+
+	t.Run("when the published port accepts connections and the probe passes, it returns nil", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.Nil(t, err)
+		defer listener.Close()
+
+		hostPort := strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"container", "inspect", "git-server"},
+			[]string(nil),
+			"",
+		).Return(
+			testContainerInspectOutput(
+				`{"Status":"running","Running":true}`,
+				`{"22/tcp":[{"HostIp":"127.0.0.1","HostPort":"`+hostPort+`"}]}`,
+			),
+			[]byte{},
+			nil,
+		)
+
+		probeCalls := 0
+
+		dockerInstance := NewDocker(executorArg)
+		err = dockerInstance.WaitHealthy(
+			"git-server",
+			&DockerWaitHealthyOptions{
+				Timeout:  time.Second,
+				Interval: time.Millisecond,
+				TCPPorts: []string{"22/tcp"},
+				Probe: func(container *DockerContainer) error {
+					probeCalls++
+					if probeCalls < 3 {
+						return errors.New("not ready")
+					}
+
+					return nil
+				},
+			},
+		)
+		require.Nil(t, err)
+		assert.Equal(t, 3, probeCalls)
+	})
+
+	t.Run("when the container is not healthy within the timeout, it returns timeout error with its logs", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"container", "inspect", "git-server"},
+			[]string(nil),
+			"",
+		).Return(
+			testContainerInspectOutput(`{"Status":"running","Running":true,"Health":{"Status":"unhealthy"}}`, `{}`),
+			[]byte{},
+			nil,
+		)
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"logs", "--tail=5", "git-server"},
+			[]string(nil),
+			"",
+		).Return([]byte("starting sshd\n"), []byte("sshd: no hostkeys available\n"), nil)
+
+		dockerInstance := NewDocker(executorArg)
+		err := dockerInstance.WaitHealthy(
+			"git-server",
+			&DockerWaitHealthyOptions{Timeout: 20 * time.Millisecond, Interval: time.Millisecond, LogsTail: 5},
+		)
+		require.NotNil(t, err)
+
+		var timeoutErr *DockerWaitTimeoutError
+		require.True(t, errors.As(err, &timeoutErr))
+		assert.Equal(t, "git-server", timeoutErr.Container)
+		assert.Equal(t, 20*time.Millisecond, timeoutErr.Timeout)
+		assert.Equal(t, "starting sshd\nsshd: no hostkeys available\n", timeoutErr.Logs)
+		assert.Contains(t, err.Error(), "health is unhealthy")
+	})
+
+	t.Run("when the container exits, it returns error without waiting", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"container", "inspect", "git-server"},
+			[]string(nil),
+			"",
+		).Return(
+			testContainerInspectOutput(`{"Status":"exited","Running":false,"ExitCode":1}`, `{}`),
+			[]byte{},
+			nil,
+		).Once()
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"logs", "--tail=50", "git-server"},
+			[]string(nil),
+			"",
+		).Return([]byte{}, []byte("fatal: missing config\n"), nil)
+
+		dockerInstance := NewDocker(executorArg)
+		err := dockerInstance.WaitHealthy("git-server", &DockerWaitHealthyOptions{Timeout: time.Minute})
+		require.NotNil(t, err)
+
+		var timeoutErr *DockerWaitTimeoutError
+		assert.False(t, errors.As(err, &timeoutErr))
+		assert.Contains(t, err.Error(), "exited with exit code 1")
+		assert.Contains(t, err.Error(), "fatal: missing config")
+		executorArg.AssertExpectations(t)
+	})
+}
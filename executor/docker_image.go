@@ -0,0 +1,226 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/palantir/stacktrace"
+)
+
+type DockerImageConfig struct {
+	User       string   `json:"User"`
+	Env        []string `json:"Env"`
+	Entrypoint []string `json:"Entrypoint"`
+	Cmd        []string `json:"Cmd"`
+	WorkingDir string   `json:"WorkingDir"`
+	// ExposedPorts are keyed by "<port>/<protocol>", e.g "80/tcp".
+	ExposedPorts map[string]struct{} `json:"ExposedPorts"`
+	Labels       map[string]string   `json:"Labels"`
+}
+
+type DockerImageRootFS struct {
+	Type string `json:"Type"`
+	// Layers are the digests of the uncompressed layers, from the base layer up.
+	Layers []string `json:"Layers"`
+}
+
+// DockerImage is the parsed output of `docker image inspect`.
+type DockerImage struct {
+	ID       string   `json:"Id"`
+	RepoTags []string `json:"RepoTags"`
+	// RepoDigests are the image's digests in the registries it was pulled from or pushed to,
+	// e.g "example/app@sha256:...".
+	RepoDigests  []string           `json:"RepoDigests"`
+	Created      time.Time          `json:"Created"`
+	Size         int64              `json:"Size"`
+	Architecture string             `json:"Architecture"`
+	Os           string             `json:"Os"`
+	Config       *DockerImageConfig `json:"Config"`
+	RootFS       *DockerImageRootFS `json:"RootFS"`
+}
+
+// ExposedPorts returns the sorted ports exposed by the image, e.g "80/tcp".
+func (image *DockerImage) ExposedPorts() []string {
+	ports := make([]string, 0)
+	if image.Config == nil {
+		return ports
+	}
+
+	for port := range image.Config.ExposedPorts {
+		ports = append(ports, port)
+	}
+
+	sort.Strings(ports)
+
+	return ports
+}
+
+type DockerManifestPlatform struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+type DockerManifestDescriptor struct {
+	MediaType string                  `json:"mediaType"`
+	Size      int64                   `json:"size"`
+	Digest    string                  `json:"digest"`
+	Platform  *DockerManifestPlatform `json:"platform,omitempty"`
+}
+
+// DockerManifest is the parsed output of `docker manifest inspect`.
+// Multi-arch images have a manifest list with Manifests, single-arch images have Config and Layers.
+type DockerManifest struct {
+	SchemaVersion int                         `json:"schemaVersion"`
+	MediaType     string                      `json:"mediaType"`
+	Manifests     []*DockerManifestDescriptor `json:"manifests"`
+	Config        *DockerManifestDescriptor   `json:"config"`
+	Layers        []*DockerManifestDescriptor `json:"layers"`
+}
+
+// Digest returns the digest of the manifest of `platform`, e.g "linux/arm64" or "linux/arm/v7",
+// or an empty string when the manifest list has no such platform.
+func (manifest *DockerManifest) Digest(platform string) string {
+	for _, descriptor := range manifest.Manifests {
+		if descriptor.Platform == nil {
+			continue
+		}
+
+		descriptorPlatform := fmt.Sprintf("%s/%s", descriptor.Platform.OS, descriptor.Platform.Architecture)
+		if descriptor.Platform.Variant != "" {
+			descriptorPlatform = fmt.Sprintf("%s/%s", descriptorPlatform, descriptor.Platform.Variant)
+		}
+
+		if descriptorPlatform == platform {
+			return descriptor.Digest
+		}
+	}
+
+	return ""
+}
+
+// ImageInspect returns the metadata of the local `image`.
+func (docker *Docker) ImageInspect(image *ImageRef) (*DockerImage, error) {
+	stdout, stderr, err := docker.commandExecutor.Execute(
+		docker.binaryPath,
+		[]string{"image", "inspect", image.String()},
+		docker.env(nil),
+		"",
+	)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "executing `docker image inspect %s` failed. Stderr: %s", image, stderr)
+	}
+
+	var images []*DockerImage
+	err = json.Unmarshal(stdout, &images)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "json decode command `docker image inspect %s` output failed", image)
+	}
+
+	if len(images) == 0 {
+		return nil, stacktrace.NewError("docker image %s not found", image)
+	}
+
+	return images[0], nil
+}
+
+// ImageExists reports whether `image` exists locally.
+func (docker *Docker) ImageExists(image *ImageRef) (bool, error) {
+	stdout, stderr, err := docker.commandExecutor.Execute(
+		docker.binaryPath,
+		[]string{"image", "inspect", "--format={{.Id}}", image.String()},
+		docker.env(nil),
+		"",
+	)
+	if err != nil {
+		if strings.Contains(strings.ToLower(string(stderr)), "no such image") {
+			return false, nil
+		}
+
+		return false, stacktrace.Propagate(err, "Stderr: %s, Stdout: %s", stderr, stdout)
+	}
+
+	return true, nil
+}
+
+// ManifestInspect returns the manifest, or manifest list of multi-arch images, of `image` in its registry.
+func (docker *Docker) ManifestInspect(image *ImageRef) (*DockerManifest, error) {
+	stdout, stderr, err := docker.commandExecutor.Execute(
+		docker.binaryPath,
+		[]string{"manifest", "inspect", image.String()},
+		docker.env(nil),
+		"",
+	)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "executing `docker manifest inspect %s` failed. Stderr: %s", image, stderr)
+	}
+
+	var manifest DockerManifest
+	err = json.Unmarshal(stdout, &manifest)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "json decode command `docker manifest inspect %s` output failed", image)
+	}
+
+	return &manifest, nil
+}
+
+// ImageRemove removes the local `images`. When `force` is true, images used by stopped containers are removed too.
+func (docker *Docker) ImageRemove(images []*ImageRef, force bool) error {
+	args := []string{"image", "rm"}
+	if force {
+		args = append(args, "--force")
+	}
+
+	for _, image := range images {
+		args = append(args, image.String())
+	}
+
+	stdout, stderr, err := docker.commandExecutor.Execute(docker.binaryPath, args, docker.env(nil), "")
+	return stacktrace.Propagate(err, "Stderr: %s, Stdout: %s", stderr, stdout)
+}
+
+// ImagePrune removes dangling images, or all unused images when `all` is true,
+// matching `filters`, e.g "until=24h" or "label=ci=true".
+// It returns the IDs of the deleted images.
+func (docker *Docker) ImagePrune(all bool, filters []string) ([]string, error) {
+	args := []string{"image", "prune", "--force"}
+	if all {
+		args = append(args, "--all")
+	}
+
+	for _, filter := range filters {
+		args = append(args, fmt.Sprintf("--filter=%s", filter))
+	}
+
+	stdout, stderr, err := docker.commandExecutor.Execute(docker.binaryPath, args, docker.env(nil), "")
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Stderr: %s, Stdout: %s", stderr, stdout)
+	}
+
+	deleted := make([]string, 0)
+
+	for _, line := range splitLines(stdout) {
+		if strings.HasPrefix(line, "deleted: ") {
+			deleted = append(deleted, strings.TrimPrefix(line, "deleted: "))
+		}
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,230 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	DefaultImageRegistry  = "docker.io"
+	DefaultImageNamespace = "library"
+	DefaultImageTag       = "latest"
+
+	imageNameMaxLength = 255
+)
+
+var ErrInvalidImageRef = errors.New("invalid docker image reference")
+
+// NOTE: The patterns follow the grammar of github.com/docker/distribution/reference.
+var (
+	imageRegistryRegex = regexp.MustCompile(
+		`^[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?)*(?::[0-9]+)?$`,
+	)
+	imageComponentRegex = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*$`)
+	imageTagRegex       = regexp.MustCompile(`^[\w][\w.-]{0,127}$`)
+	imageDigestRegex    = regexp.MustCompile(`^[a-z0-9]+(?:[.+_-][a-z0-9]+)*:[a-fA-F0-9]{32,}$`)
+	imageIDRegex        = regexp.MustCompile(`^(?:sha256:)?([a-f0-9]{12,64})$`)
+)
+
+// ImageRefError is returned when parsing an invalid image reference.
+// It matches ErrInvalidImageRef with `errors.Is`.
+type ImageRefError struct {
+	Ref    string
+	Reason string
+}
+
+// Error returns the error message.
+func (err *ImageRefError) Error() string {
+	return fmt.Sprintf("%s %q: %s", ErrInvalidImageRef, err.Ref, err.Reason)
+}
+
+// Is reports whether `target` is ErrInvalidImageRef.
+func (err *ImageRefError) Is(target error) bool {
+	return target == ErrInvalidImageRef
+}
+
+// ImageRef is a normalized docker image reference,
+// e.g "docker.io/library/alpine:3.10" or "ghcr.io/sumup-oss/app@sha256:...".
+// An ImageRef of a local image ID only has a Digest.
+type ImageRef struct {
+	Registry string
+	// Namespace are the path components before the repository, e.g "library" or "sumup-oss/team".
+	Namespace  string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// ParseImageRef parses and normalizes `ref`.
+// The registry defaults to DefaultImageRegistry, the namespace of docker.io images to DefaultImageNamespace
+// and the tag, when there's no digest, to DefaultImageTag.
+// Image IDs, i.e 12 to 64 lowercase hex characters with an optional "sha256:" prefix, are parsed as ID references.
+// Full IDs are normalized to "sha256:<64 hex characters>", while short IDs are kept as they are.
+func ParseImageRef(ref string) (*ImageRef, error) {
+	if match := imageIDRegex.FindStringSubmatch(ref); match != nil {
+		if len(match[1]) < 64 {
+			return &ImageRef{Digest: ref}, nil
+		}
+
+		return &ImageRef{Digest: "sha256:" + match[1]}, nil
+	}
+
+	name := ref
+	imageRef := &ImageRef{}
+
+	if index := strings.Index(name, "@"); index != -1 {
+		imageRef.Digest = name[index+1:]
+		name = name[:index]
+
+		if !imageDigestRegex.MatchString(imageRef.Digest) {
+			return nil, &ImageRefError{Ref: ref, Reason: fmt.Sprintf("invalid digest %q", imageRef.Digest)}
+		}
+	}
+
+	if index := strings.LastIndex(name, ":"); index != -1 && !strings.Contains(name[index+1:], "/") {
+		imageRef.Tag = name[index+1:]
+		name = name[:index]
+
+		if !imageTagRegex.MatchString(imageRef.Tag) {
+			return nil, &ImageRefError{Ref: ref, Reason: fmt.Sprintf("invalid tag %q", imageRef.Tag)}
+		}
+	}
+
+	if name == "" {
+		return nil, &ImageRefError{Ref: ref, Reason: "empty repository"}
+	}
+
+	components := strings.Split(name, "/")
+
+	imageRef.Registry = DefaultImageRegistry
+	if len(components) > 1 && isImageRegistry(components[0]) {
+		imageRef.Registry = components[0]
+		components = components[1:]
+
+		if !imageRegistryRegex.MatchString(imageRef.Registry) {
+			return nil, &ImageRefError{Ref: ref, Reason: fmt.Sprintf("invalid registry %q", imageRef.Registry)}
+		}
+	}
+
+	if imageRef.Registry == "index.docker.io" || imageRef.Registry == "registry-1.docker.io" {
+		imageRef.Registry = DefaultImageRegistry
+	}
+
+	for _, component := range components {
+		if !imageComponentRegex.MatchString(component) {
+			return nil, &ImageRefError{Ref: ref, Reason: fmt.Sprintf("invalid repository component %q", component)}
+		}
+	}
+
+	imageRef.Repository = components[len(components)-1]
+	imageRef.Namespace = strings.Join(components[:len(components)-1], "/")
+
+	if imageRef.Registry == DefaultImageRegistry && imageRef.Namespace == "" {
+		imageRef.Namespace = DefaultImageNamespace
+	}
+
+	if imageRef.Tag == "" && imageRef.Digest == "" {
+		imageRef.Tag = DefaultImageTag
+	}
+
+	if len(imageRef.Name()) > imageNameMaxLength {
+		return nil, &ImageRefError{Ref: ref, Reason: fmt.Sprintf("name longer than %d characters", imageNameMaxLength)}
+	}
+
+	return imageRef, nil
+}
+
+// MustParseImageRef is like ParseImageRef, but panics when `ref` is invalid.
+// It's meant for references known at compile time.
+func MustParseImageRef(ref string) *ImageRef {
+	imageRef, err := ParseImageRef(ref)
+	if err != nil {
+		panic(err)
+	}
+
+	return imageRef
+}
+
+// Name returns the reference without the tag and digest, e.g "docker.io/library/alpine".
+// It's empty for ID references.
+func (ref *ImageRef) Name() string {
+	if ref.Repository == "" {
+		return ""
+	}
+
+	if ref.Namespace == "" {
+		return fmt.Sprintf("%s/%s", ref.Registry, ref.Repository)
+	}
+
+	return fmt.Sprintf("%s/%s/%s", ref.Registry, ref.Namespace, ref.Repository)
+}
+
+// String returns the normalized reference, e.g "docker.io/library/alpine:3.10".
+func (ref *ImageRef) String() string {
+	reference := ref.Name()
+
+	if ref.Tag != "" {
+		reference = fmt.Sprintf("%s:%s", reference, ref.Tag)
+	}
+
+	if ref.Digest != "" {
+		if reference == "" {
+			return ref.Digest
+		}
+
+		reference = fmt.Sprintf("%s@%s", reference, ref.Digest)
+	}
+
+	return reference
+}
+
+// WithTag returns a copy of the reference with `tag` and without a digest.
+func (ref *ImageRef) WithTag(tag string) (*ImageRef, error) {
+	if ref.Repository == "" {
+		return nil, &ImageRefError{Ref: ref.String(), Reason: "image ID references can't be tagged"}
+	}
+
+	if !imageTagRegex.MatchString(tag) {
+		return nil, &ImageRefError{Ref: ref.String(), Reason: fmt.Sprintf("invalid tag %q", tag)}
+	}
+
+	imageRef := *ref
+	imageRef.Tag = tag
+	imageRef.Digest = ""
+
+	return &imageRef, nil
+}
+
+// WithDigest returns a copy of the reference pinned to `digest`, keeping the tag.
+func (ref *ImageRef) WithDigest(digest string) (*ImageRef, error) {
+	if !imageDigestRegex.MatchString(digest) {
+		return nil, &ImageRefError{Ref: ref.String(), Reason: fmt.Sprintf("invalid digest %q", digest)}
+	}
+
+	imageRef := *ref
+	imageRef.Digest = digest
+
+	return &imageRef, nil
+}
+
+// isImageRegistry reports whether the first path `component` of a reference is a registry host,
+// which, like in docker, is when it contains a "." or ":" or is "localhost".
+func isImageRegistry(component string) bool {
+	return strings.ContainsAny(component, ".:") || component == "localhost"
+}
@@ -0,0 +1,175 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testImageDigest = "sha256:3fc9b689459d738f8c88a3a48aa9e33542016b7a4052e001aaa536fca74813cb"
+
+func TestParseImageRef(t *testing.T) {
+	testCases := []struct {
+		ref      string
+		expected *ImageRef
+		string   string
+	}{
+		{
+			ref:      "alpine",
+			expected: &ImageRef{Registry: "docker.io", Namespace: "library", Repository: "alpine", Tag: "latest"},
+			string:   "docker.io/library/alpine:latest",
+		},
+		{
+			ref:      "sumup/app:v1.2.3",
+			expected: &ImageRef{Registry: "docker.io", Namespace: "sumup", Repository: "app", Tag: "v1.2.3"},
+			string:   "docker.io/sumup/app:v1.2.3",
+		},
+		{
+			ref:      "index.docker.io/library/alpine:3.10",
+			expected: &ImageRef{Registry: "docker.io", Namespace: "library", Repository: "alpine", Tag: "3.10"},
+			string:   "docker.io/library/alpine:3.10",
+		},
+		{
+			ref:      "localhost:5000/app",
+			expected: &ImageRef{Registry: "localhost:5000", Repository: "app", Tag: "latest"},
+			string:   "localhost:5000/app:latest",
+		},
+		{
+			ref:      "ghcr.io/sumup-oss/team/app_server@" + testImageDigest,
+			expected: &ImageRef{Registry: "ghcr.io", Namespace: "sumup-oss/team", Repository: "app_server", Digest: testImageDigest},
+			string:   "ghcr.io/sumup-oss/team/app_server@" + testImageDigest,
+		},
+		{
+			ref: "registry.example.com:443/app:v1@" + testImageDigest,
+			expected: &ImageRef{
+				Registry:   "registry.example.com:443",
+				Repository: "app",
+				Tag:        "v1",
+				Digest:     testImageDigest,
+			},
+			string: "registry.example.com:443/app:v1@" + testImageDigest,
+		},
+		{
+			ref:      testImageDigest,
+			expected: &ImageRef{Digest: testImageDigest},
+			string:   testImageDigest,
+		},
+		{
+			ref:      strings.TrimPrefix(testImageDigest, "sha256:"),
+			expected: &ImageRef{Digest: testImageDigest},
+			string:   testImageDigest,
+		},
+		{
+			ref:      "3fc9b689459d",
+			expected: &ImageRef{Digest: "3fc9b689459d"},
+			string:   "3fc9b689459d",
+		},
+		{
+			ref:      "sha256:3fc9b689459d738f",
+			expected: &ImageRef{Digest: "sha256:3fc9b689459d738f"},
+			string:   "sha256:3fc9b689459d738f",
+		},
+		{
+			ref:      "3fc9b689459",
+			expected: &ImageRef{Registry: "docker.io", Namespace: "library", Repository: "3fc9b689459", Tag: "latest"},
+			string:   "docker.io/library/3fc9b689459:latest",
+		},
+		{
+			ref:      "3fc9b689459d:v1",
+			expected: &ImageRef{Registry: "docker.io", Namespace: "library", Repository: "3fc9b689459d", Tag: "v1"},
+			string:   "docker.io/library/3fc9b689459d:v1",
+		},
+		{
+			ref:      "sumup/3fc9b689459d",
+			expected: &ImageRef{Registry: "docker.io", Namespace: "sumup", Repository: "3fc9b689459d", Tag: "latest"},
+			string:   "docker.io/sumup/3fc9b689459d:latest",
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		t.Run(testCase.ref, func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := ParseImageRef(testCase.ref)
+			require.Nil(t, err)
+			assert.Equal(t, testCase.expected, actual)
+			assert.Equal(t, testCase.string, actual.String())
+		})
+	}
+
+	for _, ref := range []string{
+		"",
+		":v1",
+		"Alpine",
+		"alpine:v1/2",
+		"alpine:-v1",
+		"app@sha256:short",
+		"example.com/",
+		"exa_mple.com/app",
+		"example.com/app--server/",
+		strings.Repeat("a", 256),
+	} {
+		ref := ref
+
+		t.Run("when "+ref+" is invalid, it returns error", func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := ParseImageRef(ref)
+			require.NotNil(t, err)
+			assert.Nil(t, actual)
+			assert.True(t, errors.Is(err, ErrInvalidImageRef))
+		})
+	}
+}
+
+func TestImageRef_WithTag(t *testing.T) {
+	t.Run("it replaces the tag and drops the digest", func(t *testing.T) {
+		t.Parallel()
+
+		ref := MustParseImageRef("sumup/app:v1@" + testImageDigest)
+
+		actual, err := ref.WithTag("v2")
+		require.Nil(t, err)
+		assert.Equal(t, "docker.io/sumup/app:v2", actual.String())
+		assert.Equal(t, "docker.io/sumup/app:v1@"+testImageDigest, ref.String())
+	})
+
+	t.Run("when the tag is invalid, it returns error", func(t *testing.T) {
+		t.Parallel()
+
+		actual, err := MustParseImageRef("sumup/app").WithTag("v1/2")
+		require.NotNil(t, err)
+		assert.Nil(t, actual)
+		assert.True(t, errors.Is(err, ErrInvalidImageRef))
+	})
+}
+
+func TestImageRef_WithDigest(t *testing.T) {
+	t.Run("it pins the reference to the digest", func(t *testing.T) {
+		t.Parallel()
+
+		actual, err := MustParseImageRef("sumup/app:v1").WithDigest(testImageDigest)
+		require.Nil(t, err)
+		assert.Equal(t, "docker.io/sumup/app:v1@"+testImageDigest, actual.String())
+		assert.Equal(t, "docker.io/sumup/app", actual.Name())
+	})
+}
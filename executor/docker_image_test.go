@@ -0,0 +1,260 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sumup-oss/go-pkgs/os/ostest"
+)
+
+func TestDocker_ImageInspect(t *testing.T) {
+	t.Run("it parses the image metadata", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"image", "inspect", "docker.io/example/app:v1"},
+			[]string(nil),
+			"",
+		).Return(
+			[]byte(`[
+  {
+    "Id": "sha256:aa11",
+    "RepoTags": ["example/app:v1"],
+    "RepoDigests": ["example/app@sha256:bb22"],
+    "Created": "2019-06-01T10:00:00.000000000Z",
+    "Size": 7340032,
+    "Architecture": "amd64",
+    "Os": "linux",
+    "Config": {
+      "User": "app",
+      "Env": ["PATH=/usr/local/bin:/usr/bin", "APP_ENV=production"],
+      "Cmd": ["/app"],
+      "Entrypoint": null,
+      "WorkingDir": "/srv",
+      "ExposedPorts": {"8080/tcp": {}, "443/tcp": {}},
+      "Labels": {"org.opencontainers.image.revision": "f00d"}
+    },
+    "RootFS": {"Type": "layers", "Layers": ["sha256:cc33", "sha256:dd44"]}
+  }
+]`),
+			[]byte{},
+			nil,
+		)
+
+		dockerInstance := NewDocker(executorArg)
+		actual, actualErr := dockerInstance.ImageInspect(MustParseImageRef("example/app:v1"))
+		require.Nil(t, actualErr)
+
+		assert.Equal(t, "sha256:aa11", actual.ID)
+		assert.Equal(t, []string{"example/app:v1"}, actual.RepoTags)
+		assert.Equal(t, []string{"example/app@sha256:bb22"}, actual.RepoDigests)
+		assert.Equal(t, time.Date(2019, 6, 1, 10, 0, 0, 0, time.UTC), actual.Created.UTC())
+		assert.Equal(t, int64(7340032), actual.Size)
+		assert.Equal(t, []string{"PATH=/usr/local/bin:/usr/bin", "APP_ENV=production"}, actual.Config.Env)
+		assert.Equal(t, map[string]string{"org.opencontainers.image.revision": "f00d"}, actual.Config.Labels)
+		assert.Equal(t, []string{"443/tcp", "8080/tcp"}, actual.ExposedPorts())
+		assert.Equal(t, []string{"sha256:cc33", "sha256:dd44"}, actual.RootFS.Layers)
+	})
+
+	t.Run("when the image does not exist, it returns error", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"image", "inspect", "docker.io/library/missing:latest"},
+			[]string(nil),
+			"",
+		).Return([]byte("[]\n"), []byte("Error: No such image: missing"), errors.New("exit status 1"))
+
+		dockerInstance := NewDocker(executorArg)
+		actual, actualErr := dockerInstance.ImageInspect(MustParseImageRef("missing"))
+		require.NotNil(t, actualErr)
+		assert.Nil(t, actual)
+		assert.Contains(t, actualErr.Error(), "No such image")
+	})
+}
+
+func TestDocker_ImageExists(t *testing.T) {
+	t.Run("when the image exists, it returns true", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"image", "inspect", "--format={{.Id}}", "docker.io/example/app:v1"},
+			[]string(nil),
+			"",
+		).Return([]byte("sha256:aa11\n"), []byte{}, nil)
+
+		dockerInstance := NewDocker(executorArg)
+		actual, actualErr := dockerInstance.ImageExists(MustParseImageRef("example/app:v1"))
+		require.Nil(t, actualErr)
+		assert.True(t, actual)
+	})
+
+	t.Run("when the image does not exist, it returns false", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"image", "inspect", "--format={{.Id}}", "docker.io/library/missing:latest"},
+			[]string(nil),
+			"",
+		).Return([]byte("\n"), []byte("Error: No such image: missing"), errors.New("exit status 1"))
+
+		dockerInstance := NewDocker(executorArg)
+		actual, actualErr := dockerInstance.ImageExists(MustParseImageRef("missing"))
+		require.Nil(t, actualErr)
+		assert.False(t, actual)
+	})
+
+	t.Run("when the daemon is unreachable, it returns error", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		fakeStderr := []byte("Cannot connect to the Docker daemon at unix:///var/run/docker.sock.")
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"image", "inspect", "--format={{.Id}}", "docker.io/example/app:v1"},
+			[]string(nil),
+			"",
+		).Return([]byte{}, fakeStderr, errors.New("exit status 1"))
+
+		dockerInstance := NewDocker(executorArg)
+		actual, actualErr := dockerInstance.ImageExists(MustParseImageRef("example/app:v1"))
+		require.NotNil(t, actualErr)
+		assert.False(t, actual)
+		assert.Contains(t, actualErr.Error(), string(fakeStderr))
+	})
+}
+
+func TestDocker_ManifestInspect(t *testing.T) {
+	t.Run("it parses the manifest list of a multi-arch image", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"manifest", "inspect", "docker.io/example/app:v1"},
+			[]string(nil),
+			"",
+		).Return(
+			[]byte(`{
+  "schemaVersion": 2,
+  "mediaType": "application/vnd.docker.distribution.manifest.list.v2+json",
+  "manifests": [
+    {
+      "mediaType": "application/vnd.docker.distribution.manifest.v2+json",
+      "size": 1570,
+      "digest": "sha256:aa11",
+      "platform": {"architecture": "amd64", "os": "linux"}
+    },
+    {
+      "mediaType": "application/vnd.docker.distribution.manifest.v2+json",
+      "size": 1570,
+      "digest": "sha256:bb22",
+      "platform": {"architecture": "arm", "os": "linux", "variant": "v7"}
+    }
+  ]
+}`),
+			[]byte{},
+			nil,
+		)
+
+		dockerInstance := NewDocker(executorArg)
+		actual, actualErr := dockerInstance.ManifestInspect(MustParseImageRef("example/app:v1"))
+		require.Nil(t, actualErr)
+
+		assert.Equal(t, "application/vnd.docker.distribution.manifest.list.v2+json", actual.MediaType)
+		require.Len(t, actual.Manifests, 2)
+		assert.Equal(t, "sha256:aa11", actual.Digest("linux/amd64"))
+		assert.Equal(t, "sha256:bb22", actual.Digest("linux/arm/v7"))
+		assert.Equal(t, "", actual.Digest("windows/amd64"))
+	})
+
+	t.Run("when the manifest is unknown, it returns error", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		fakeStderr := []byte("no such manifest: docker.io/example/app:missing")
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"manifest", "inspect", "docker.io/example/app:missing"},
+			[]string(nil),
+			"",
+		).Return([]byte{}, fakeStderr, errors.New("exit status 1"))
+
+		dockerInstance := NewDocker(executorArg)
+		actual, actualErr := dockerInstance.ManifestInspect(MustParseImageRef("example/app:missing"))
+		require.NotNil(t, actualErr)
+		assert.Nil(t, actual)
+		assert.Contains(t, actualErr.Error(), string(fakeStderr))
+	})
+}
+
+func TestDocker_ImageRemove(t *testing.T) {
+	t.Run("it forces the removal of the images", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"image", "rm", "--force", "docker.io/example/app:v1", "docker.io/example/app:v2"},
+			[]string(nil),
+			"",
+		).Return([]byte("Untagged: example/app:v1\nUntagged: example/app:v2\n"), []byte{}, nil)
+
+		dockerInstance := NewDocker(executorArg)
+		actual := dockerInstance.ImageRemove(
+			[]*ImageRef{MustParseImageRef("example/app:v1"), MustParseImageRef("example/app:v2")},
+			true,
+		)
+		require.Nil(t, actual)
+	})
+}
+
+func TestDocker_ImagePrune(t *testing.T) {
+	t.Run("it passes the filters and returns the deleted image ids", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"image", "prune", "--force", "--all", "--filter=until=24h", "--filter=label=ci=true"},
+			[]string(nil),
+			"",
+		).Return(
+			[]byte("Deleted Images:\nuntagged: example/app:v1\ndeleted: sha256:aa11\ndeleted: sha256:bb22\n\n"+
+				"Total reclaimed space: 7.34MB\n"),
+			[]byte{},
+			nil,
+		)
+
+		dockerInstance := NewDocker(executorArg)
+		actual, actualErr := dockerInstance.ImagePrune(true, []string{"until=24h", "label=ci=true"})
+		require.Nil(t, actualErr)
+		assert.Equal(t, []string{"sha256:aa11", "sha256:bb22"}, actual)
+	})
+}
@@ -0,0 +1,173 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/palantir/stacktrace"
+)
+
+type DockerNetworkContainer struct {
+	Name        string `json:"Name"`
+	IPv4Address string `json:"IPv4Address"`
+	MacAddress  string `json:"MacAddress"`
+}
+
+type DockerNetworkCreateOptions struct {
+	Name string
+	// Driver defaults to "bridge".
+	Driver string
+	// Subnet is in CIDR format, e.g "172.28.0.0/16".
+	Subnet  string
+	Gateway string
+	Labels  map[string]string
+	// Internal restricts external access to the network.
+	Internal bool
+	// Attachable allows standalone containers to connect to swarm networks.
+	Attachable bool
+}
+
+type DockerNetworkConnectOptions struct {
+	Aliases []string
+	// IP is the IPv4 address of the container in the network.
+	IP string
+}
+
+// NetworkCreate creates a network and returns its ID. `options.Name` is required.
+func (docker *Docker) NetworkCreate(options *DockerNetworkCreateOptions) (string, error) {
+	if options == nil || options.Name == "" {
+		return "", stacktrace.NewError("network name is required")
+	}
+
+	args := []string{"network", "create"}
+
+	if options.Driver != "" {
+		args = append(args, fmt.Sprintf("--driver=%s", options.Driver))
+	}
+
+	if options.Subnet != "" {
+		args = append(args, fmt.Sprintf("--subnet=%s", options.Subnet))
+	}
+
+	if options.Gateway != "" {
+		args = append(args, fmt.Sprintf("--gateway=%s", options.Gateway))
+	}
+
+	if options.Internal {
+		args = append(args, "--internal")
+	}
+
+	if options.Attachable {
+		args = append(args, "--attachable")
+	}
+
+	args = append(args, dockerLabelArgs(options.Labels)...)
+	args = append(args, options.Name)
+
+	stdout, stderr, err := docker.commandExecutor.Execute(docker.binaryPath, args, docker.env(nil), "")
+	if err != nil {
+		return "", stacktrace.Propagate(err, "Stderr: %s, Stdout: %s", stderr, stdout)
+	}
+
+	return strings.TrimSpace(string(stdout)), nil
+}
+
+// NetworkRemove removes the network `name`.
+func (docker *Docker) NetworkRemove(name string) error {
+	args := []string{"network", "rm", name}
+	stdout, stderr, err := docker.commandExecutor.Execute(docker.binaryPath, args, docker.env(nil), "")
+	return stacktrace.Propagate(err, "Stderr: %s, Stdout: %s", stderr, stdout)
+}
+
+// NetworkConnect connects `container` to the network `name`.
+func (docker *Docker) NetworkConnect(name, container string, options *DockerNetworkConnectOptions) error {
+	args := []string{"network", "connect"}
+
+	if options != nil {
+		for _, alias := range options.Aliases {
+			args = append(args, fmt.Sprintf("--alias=%s", alias))
+		}
+
+		if options.IP != "" {
+			args = append(args, fmt.Sprintf("--ip=%s", options.IP))
+		}
+	}
+
+	args = append(args, name, container)
+
+	stdout, stderr, err := docker.commandExecutor.Execute(docker.binaryPath, args, docker.env(nil), "")
+	return stacktrace.Propagate(err, "Stderr: %s, Stdout: %s", stderr, stdout)
+}
+
+// NetworkDisconnect disconnects `container` from the network `name`.
+// When `force` is true, the container is disconnected even when it's not running.
+func (docker *Docker) NetworkDisconnect(name, container string, force bool) error {
+	args := []string{"network", "disconnect"}
+	if force {
+		args = append(args, "--force")
+	}
+
+	args = append(args, name, container)
+
+	stdout, stderr, err := docker.commandExecutor.Execute(docker.binaryPath, args, docker.env(nil), "")
+	return stacktrace.Propagate(err, "Stderr: %s, Stdout: %s", stderr, stdout)
+}
+
+// NetworkList returns the networks matching `filters`, e.g "label=ci.job=42" or "driver=bridge".
+func (docker *Docker) NetworkList(filters []string) ([]*DockerNetwork, error) {
+	ids, err := docker.listIDs([]string{"network", "ls", "--quiet", "--no-trunc"}, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	networks := make([]*DockerNetwork, 0)
+	if len(ids) == 0 {
+		return networks, nil
+	}
+
+	stdout, stderr, err := docker.commandExecutor.Execute(
+		docker.binaryPath,
+		append([]string{"network", "inspect"}, ids...),
+		docker.env(nil),
+		"",
+	)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "executing `docker network inspect` failed. Stderr: %s", stderr)
+	}
+
+	err = json.Unmarshal(stdout, &networks)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "json decode command `docker network inspect` output failed")
+	}
+
+	return networks, nil
+}
+
+// listIDs runs the `ls` command of `args` with `filters` and returns the listed IDs, one per line.
+func (docker *Docker) listIDs(args, filters []string) ([]string, error) {
+	for _, filter := range filters {
+		args = append(args, fmt.Sprintf("--filter=%s", filter))
+	}
+
+	stdout, stderr, err := docker.commandExecutor.Execute(docker.binaryPath, args, docker.env(nil), "")
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "Stderr: %s, Stdout: %s", stderr, stdout)
+	}
+
+	return splitLines(stdout), nil
+}
@@ -0,0 +1,223 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sumup-oss/go-pkgs/os/ostest"
+)
+
+func TestDocker_NetworkCreate(t *testing.T) {
+	t.Run("it passes the options and returns the network id", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{
+				"network",
+				"create",
+				"--driver=bridge",
+				"--subnet=172.28.0.0/16",
+				"--gateway=172.28.0.1",
+				"--internal",
+				"--label=ci.job=42",
+				"ci-42",
+			},
+			[]string(nil),
+			"",
+		).Return([]byte("9c1e5a0d3f2b\n"), []byte{}, nil)
+
+		dockerInstance := NewDocker(executorArg)
+		actual, actualErr := dockerInstance.NetworkCreate(
+			&DockerNetworkCreateOptions{
+				Name:     "ci-42",
+				Driver:   "bridge",
+				Subnet:   "172.28.0.0/16",
+				Gateway:  "172.28.0.1",
+				Labels:   map[string]string{"ci.job": "42"},
+				Internal: true,
+			},
+		)
+		require.Nil(t, actualErr)
+		assert.Equal(t, "9c1e5a0d3f2b", actual)
+	})
+
+	t.Run("when creating fails, it returns error", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		fakeStderr := []byte("Error response from daemon: network with name ci-42 already exists")
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"network", "create", "ci-42"},
+			[]string(nil),
+			"",
+		).Return([]byte{}, fakeStderr, errors.New("exit status 1"))
+
+		dockerInstance := NewDocker(executorArg)
+		actual, actualErr := dockerInstance.NetworkCreate(&DockerNetworkCreateOptions{Name: "ci-42"})
+		require.NotNil(t, actualErr)
+		assert.Equal(t, "", actual)
+		assert.Contains(t, actualErr.Error(), string(fakeStderr))
+	})
+
+	t.Run("when the name is missing, it returns error without creating", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		dockerInstance := NewDocker(executorArg)
+
+		for _, options := range []*DockerNetworkCreateOptions{nil, {Driver: "bridge"}} {
+			actual, actualErr := dockerInstance.NetworkCreate(options)
+			require.NotNil(t, actualErr)
+			assert.Equal(t, "", actual)
+			assert.Contains(t, actualErr.Error(), "network name is required")
+		}
+
+		executorArg.AssertNotCalled(t, "Execute", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestDocker_NetworkRemove(t *testing.T) {
+	t.Run("it removes the network", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"network", "rm", "ci-42"},
+			[]string(nil),
+			"",
+		).Return([]byte("ci-42\n"), []byte{}, nil)
+
+		dockerInstance := NewDocker(executorArg)
+		actual := dockerInstance.NetworkRemove("ci-42")
+		require.Nil(t, actual)
+	})
+}
+
+func TestDocker_NetworkConnect(t *testing.T) {
+	t.Run("it connects the container with aliases and ip", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"network", "connect", "--alias=git", "--ip=172.28.0.10", "ci-42", "git-server"},
+			[]string(nil),
+			"",
+		).Return([]byte{}, []byte{}, nil)
+
+		dockerInstance := NewDocker(executorArg)
+		actual := dockerInstance.NetworkConnect(
+			"ci-42",
+			"git-server",
+			&DockerNetworkConnectOptions{Aliases: []string{"git"}, IP: "172.28.0.10"},
+		)
+		require.Nil(t, actual)
+	})
+}
+
+func TestDocker_NetworkDisconnect(t *testing.T) {
+	t.Run("it forces the disconnect of the container", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"network", "disconnect", "--force", "ci-42", "git-server"},
+			[]string(nil),
+			"",
+		).Return([]byte{}, []byte{}, nil)
+
+		dockerInstance := NewDocker(executorArg)
+		actual := dockerInstance.NetworkDisconnect("ci-42", "git-server", true)
+		require.Nil(t, actual)
+	})
+}
+
+func TestDocker_NetworkList(t *testing.T) {
+	t.Run("it inspects the networks matching the filters", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"network", "ls", "--quiet", "--no-trunc", "--filter=label=ci.job=42"},
+			[]string(nil),
+			"",
+		).Return([]byte("9c1e5a0d3f2b\n"), []byte{}, nil)
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"network", "inspect", "9c1e5a0d3f2b"},
+			[]string(nil),
+			"",
+		).Return(
+			[]byte(`[
+  {
+    "Name": "ci-42",
+    "Id": "9c1e5a0d3f2b",
+    "Scope": "local",
+    "Driver": "bridge",
+    "Internal": true,
+    "IPAM": {"Driver": "default", "Config": [{"Subnet": "172.28.0.0/16", "Gateway": "172.28.0.1"}]},
+    "Containers": {
+      "4f1c0e2d9a7b": {"Name": "git-server", "MacAddress": "02:42:ac:1c:00:0a", "IPv4Address": "172.28.0.10/16"}
+    },
+    "Labels": {"ci.job": "42"}
+  }
+]`),
+			[]byte{},
+			nil,
+		)
+
+		dockerInstance := NewDocker(executorArg)
+		actual, actualErr := dockerInstance.NetworkList([]string{"label=ci.job=42"})
+		require.Nil(t, actualErr)
+		require.Len(t, actual, 1)
+
+		assert.Equal(t, "ci-42", actual[0].Name)
+		assert.Equal(t, "bridge", actual[0].Driver)
+		assert.True(t, actual[0].Internal)
+		assert.Equal(t, map[string]string{"ci.job": "42"}, actual[0].Labels)
+		assert.Equal(t, "git-server", actual[0].Containers["4f1c0e2d9a7b"].Name)
+		assert.Equal(t, "172.28.0.1", actual[0].IPAМ.Config[0].Gateway)
+	})
+
+	t.Run("when no network matches, it returns no networks", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"network", "ls", "--quiet", "--no-trunc", "--filter=label=ci.job=43"},
+			[]string(nil),
+			"",
+		).Return([]byte{}, []byte{}, nil)
+
+		dockerInstance := NewDocker(executorArg)
+		actual, actualErr := dockerInstance.NetworkList([]string{"label=ci.job=43"})
+		require.Nil(t, actualErr)
+		assert.Empty(t, actual)
+	})
+}
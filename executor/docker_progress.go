@@ -0,0 +1,237 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/palantir/stacktrace"
+
+	"github.com/sumup-oss/go-pkgs/os"
+)
+
+type DockerProgressEventType string
+
+const (
+	// DockerProgressBuildStep is emitted when a build step starts.
+	DockerProgressBuildStep DockerProgressEventType = "build-step"
+	// DockerProgressLayer is emitted when the status or progress of a pushed or pulled layer changes.
+	DockerProgressLayer DockerProgressEventType = "layer"
+	// DockerProgressDigest is emitted with the ID of a built image, or the digest of a pushed image.
+	DockerProgressDigest DockerProgressEventType = "digest"
+	// DockerProgressLog is emitted with any other output line.
+	DockerProgressLog DockerProgressEventType = "log"
+)
+
+type DockerProgressEvent struct {
+	Type DockerProgressEventType
+	// Step and TotalSteps of DockerProgressBuildStep events, e.g 2 and 5 for "Step 2/5".
+	Step       int
+	TotalSteps int
+	// Instruction of DockerProgressBuildStep events, e.g "RUN apk add git".
+	Instruction string
+	// LayerID of DockerProgressLayer events.
+	LayerID string
+	// Status of DockerProgressLayer events, e.g "Preparing", "Pushing", "Pushed" or "Layer already exists".
+	Status string
+	// Current and Total bytes of DockerProgressLayer events. They're zero when unknown,
+	// which is always the case with the CLI backend, since docker only reports them to terminals.
+	Current int64
+	Total   int64
+	// Digest of DockerProgressDigest events, e.g "sha256:...".
+	Digest string
+	// Message is the output line the event was parsed from.
+	Message string
+}
+
+// DockerProgressFunc receives progress events. It's never called concurrently.
+type DockerProgressFunc func(event *DockerProgressEvent)
+
+var (
+	dockerBuildStepRegex       = regexp.MustCompile(`^Step (\d+)/(\d+) : (.*)$`)
+	dockerBuildKitStepRegex    = regexp.MustCompile(`^#\d+ \[(?:[\w.-]+ )?(\d+)/(\d+)\] (.*)$`)
+	dockerBuildImageIDRegex    = regexp.MustCompile(`^Successfully built ([0-9a-f]+)$`)
+	dockerBuildKitImageIDRegex = regexp.MustCompile(`^#\d+ writing image (sha256:[0-9a-f]+)`)
+	dockerPushLayerRegex       = regexp.MustCompile(`^([0-9a-f]{12}): (Preparing|Waiting|Pushing|Pushed|Layer already exists|Mounted from .+)$`)
+	dockerPushDigestRegex      = regexp.MustCompile(`^\S+: digest: (sha256:[0-9a-f]{64}) size: \d+$`)
+)
+
+// BuildWithProgress builds like Build, calling `progress` with the events parsed from the output as it's written.
+// BuildKit builds use plain progress output, so that their steps can be parsed.
+func (docker *Docker) BuildWithProgress(options *DockerBuildOptions, progress DockerProgressFunc) error {
+	args, env, err := dockerBuildArgs(options)
+	if err != nil {
+		return err
+	}
+
+	// NOTE: Set through the environment, instead of `--progress`, which the legacy builder does not support.
+	env = extendEnv(env, []string{"BUILDKIT_PROGRESS=plain"})
+
+	_, err = docker.executeWithProgress(args, docker.env(env), progress)
+	return err
+}
+
+// PushWithProgress pushes like Push, calling `progress` with the events parsed from the output as it's written.
+// It returns the digest of the pushed image.
+func (docker *Docker) PushWithProgress(image *ImageRef, progress DockerProgressFunc) (string, error) {
+	err := validatePushImageRef(image)
+	if err != nil {
+		return "", err
+	}
+
+	return docker.executeWithProgress([]string{"push", image.String()}, docker.env(nil), progress)
+}
+
+// executeWithProgress returns the digest of the last DockerProgressDigest event.
+func (docker *Docker) executeWithProgress(args, env []string, progress DockerProgressFunc) (string, error) {
+	streamsExecutor, ok := docker.commandExecutor.(os.StreamsExecutor)
+	if !ok {
+		return "", stacktrace.NewError("command executor does not support streaming output")
+	}
+
+	parser := &dockerProgressParser{progress: progress}
+	stdout := &dockerProgressWriter{parser: parser}
+	stderr := &dockerProgressWriter{parser: parser}
+
+	err := streamsExecutor.ExecuteWithStreams(docker.binaryPath, args, env, "", stdout, stderr)
+
+	stdout.flush()
+	stderr.flush()
+
+	if err != nil {
+		return "", stacktrace.Propagate(err, "Stderr: %s, Stdout: %s", stderr.output.Bytes(), stdout.output.Bytes())
+	}
+
+	return parser.digest, nil
+}
+
+// dockerProgressParser parses output lines into events, serializing the calls to `progress`.
+type dockerProgressParser struct {
+	mutex    sync.Mutex
+	progress DockerProgressFunc
+	digest   string
+}
+
+func (parser *dockerProgressParser) parseLine(line string) {
+	line = strings.TrimRight(line, "\r")
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+
+	event := parseDockerProgressLine(line)
+
+	parser.mutex.Lock()
+	defer parser.mutex.Unlock()
+
+	if event.Type == DockerProgressDigest {
+		parser.digest = event.Digest
+	}
+
+	if parser.progress != nil {
+		parser.progress(event)
+	}
+}
+
+func parseDockerProgressLine(line string) *DockerProgressEvent {
+	event := &DockerProgressEvent{Type: DockerProgressLog, Message: line}
+
+	if match := dockerBuildStepRegex.FindStringSubmatch(line); match != nil {
+		return newDockerBuildStepEvent(line, match)
+	}
+
+	if match := dockerBuildKitStepRegex.FindStringSubmatch(line); match != nil {
+		return newDockerBuildStepEvent(line, match)
+	}
+
+	if match := dockerBuildImageIDRegex.FindStringSubmatch(line); match != nil {
+		event.Type = DockerProgressDigest
+		event.Digest = match[1]
+
+		return event
+	}
+
+	if match := dockerBuildKitImageIDRegex.FindStringSubmatch(line); match != nil {
+		event.Type = DockerProgressDigest
+		event.Digest = match[1]
+
+		return event
+	}
+
+	if match := dockerPushLayerRegex.FindStringSubmatch(line); match != nil {
+		event.Type = DockerProgressLayer
+		event.LayerID = match[1]
+		event.Status = match[2]
+
+		return event
+	}
+
+	if match := dockerPushDigestRegex.FindStringSubmatch(line); match != nil {
+		event.Type = DockerProgressDigest
+		event.Digest = match[1]
+
+		return event
+	}
+
+	return event
+}
+
+func newDockerBuildStepEvent(line string, match []string) *DockerProgressEvent {
+	step, _ := strconv.Atoi(match[1])
+	totalSteps, _ := strconv.Atoi(match[2])
+
+	return &DockerProgressEvent{
+		Type:        DockerProgressBuildStep,
+		Step:        step,
+		TotalSteps:  totalSteps,
+		Instruction: match[3],
+		Message:     line,
+	}
+}
+
+// dockerProgressWriter passes complete lines to the parser and keeps a copy of the output for errors.
+type dockerProgressWriter struct {
+	parser  *dockerProgressParser
+	output  bytes.Buffer
+	partial []byte
+}
+
+func (writer *dockerProgressWriter) Write(data []byte) (int, error) {
+	writer.output.Write(data)
+	writer.partial = append(writer.partial, data...)
+
+	for {
+		index := bytes.IndexByte(writer.partial, '\n')
+		if index == -1 {
+			break
+		}
+
+		writer.parser.parseLine(string(writer.partial[:index]))
+		writer.partial = writer.partial[index+1:]
+	}
+
+	return len(data), nil
+}
+
+// flush parses the last line, when the output doesn't end with a newline.
+func (writer *dockerProgressWriter) flush() {
+	if len(writer.partial) > 0 {
+		writer.parser.parseLine(string(writer.partial))
+		writer.partial = nil
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sumup-oss/go-pkgs/os/ostest"
+)
+
+const testPushDigest = "sha256:3fc9b689459d738f8c88a3a48aa9e33542016b7a4052e001aaa536fca74813cb"
+
+func TestDocker_BuildWithProgress(t *testing.T) {
+	t.Run("it emits the steps and image id of the legacy builder", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"ExecuteWithStreams",
+			"docker",
+			[]string{"build", "-f", "Dockerfile", "--tag", "docker.io/example/app:v1", "."},
+			mock.MatchedBy(func(env []string) bool {
+				return len(env) > 0 && env[len(env)-1] == "BUILDKIT_PROGRESS=plain"
+			}),
+			"",
+			mock.Anything,
+			mock.Anything,
+		).Run(func(args mock.Arguments) {
+			stdout := args.Get(4).(io.Writer)
+			// NOTE: Written in chunks not aligned with lines, like a pipe would.
+			_, _ = stdout.Write([]byte("Sending build context to Docker daemon  3.072kB\nStep 1/2 : FROM al"))
+			_, _ = stdout.Write([]byte("pine:3.10\n ---> 961769676411\nStep 2/2 : RUN apk add git\n"))
+			_, _ = stdout.Write([]byte("Successfully built 4f1c0e2d9a7b\nSuccessfully tagged example/app:v1"))
+		}).Return(nil)
+
+		var actualEvents []*DockerProgressEvent
+
+		dockerInstance := NewDocker(executorArg)
+		err := dockerInstance.BuildWithProgress(
+			&DockerBuildOptions{File: "Dockerfile", Tag: MustParseImageRef("example/app:v1"), ContextDir: "."},
+			func(event *DockerProgressEvent) { actualEvents = append(actualEvents, event) },
+		)
+		require.Nil(t, err)
+		require.Len(t, actualEvents, 6)
+
+		assert.Equal(t, DockerProgressLog, actualEvents[0].Type)
+		assert.Equal(
+			t,
+			&DockerProgressEvent{
+				Type:        DockerProgressBuildStep,
+				Step:        1,
+				TotalSteps:  2,
+				Instruction: "FROM alpine:3.10",
+				Message:     "Step 1/2 : FROM alpine:3.10",
+			},
+			actualEvents[1],
+		)
+		assert.Equal(t, 2, actualEvents[3].Step)
+		assert.Equal(t, "RUN apk add git", actualEvents[3].Instruction)
+		assert.Equal(t, DockerProgressDigest, actualEvents[4].Type)
+		assert.Equal(t, "4f1c0e2d9a7b", actualEvents[4].Digest)
+		assert.Equal(t, "Successfully tagged example/app:v1", actualEvents[5].Message)
+	})
+
+	t.Run("it emits the steps and image id of BuildKit", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"ExecuteWithStreams",
+			"docker",
+			[]string{"build", "-f", "Dockerfile", "."},
+			mock.Anything,
+			"",
+			mock.Anything,
+			mock.Anything,
+		).Run(func(args mock.Arguments) {
+			stderr := args.Get(5).(io.Writer)
+			_, _ = stderr.Write(
+				[]byte(
+					"#1 [internal] load build definition from Dockerfile\n" +
+						"#5 [builder 2/4] RUN go build ./...\n" +
+						"#5 DONE 12.3s\n" +
+						"#9 writing image sha256:4f1c0e2d9a7b done\n",
+				),
+			)
+		}).Return(nil)
+
+		var actualEvents []*DockerProgressEvent
+
+		dockerInstance := NewDocker(executorArg)
+		err := dockerInstance.BuildWithProgress(
+			&DockerBuildOptions{File: "Dockerfile", ContextDir: "."},
+			func(event *DockerProgressEvent) { actualEvents = append(actualEvents, event) },
+		)
+		require.Nil(t, err)
+		require.Len(t, actualEvents, 4)
+
+		assert.Equal(t, DockerProgressLog, actualEvents[0].Type)
+		assert.Equal(t, DockerProgressBuildStep, actualEvents[1].Type)
+		assert.Equal(t, 2, actualEvents[1].Step)
+		assert.Equal(t, 4, actualEvents[1].TotalSteps)
+		assert.Equal(t, "RUN go build ./...", actualEvents[1].Instruction)
+		assert.Equal(t, DockerProgressDigest, actualEvents[3].Type)
+		assert.Equal(t, "sha256:4f1c0e2d9a7b", actualEvents[3].Digest)
+	})
+}
+
+func TestDocker_PushWithProgress(t *testing.T) {
+	t.Run("it emits the layer statuses and returns the pushed digest", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"ExecuteWithStreams",
+			"docker",
+			[]string{"push", "docker.io/example/app:v1"},
+			[]string(nil),
+			"",
+			mock.Anything,
+			mock.Anything,
+		).Run(func(args mock.Arguments) {
+			stdout := args.Get(4).(io.Writer)
+			_, _ = stdout.Write(
+				[]byte(
+					"The push refers to repository [docker.io/example/app]\n" +
+						"5f70bf18a086: Preparing\n" +
+						"5f70bf18a086: Layer already exists\n" +
+						"v1: digest: " + testPushDigest + " size: 1570\n",
+				),
+			)
+		}).Return(nil)
+
+		var actualEvents []*DockerProgressEvent
+
+		dockerInstance := NewDocker(executorArg)
+		actual, err := dockerInstance.PushWithProgress(
+			MustParseImageRef("example/app:v1"),
+			func(event *DockerProgressEvent) { actualEvents = append(actualEvents, event) },
+		)
+		require.Nil(t, err)
+		assert.Equal(t, testPushDigest, actual)
+		require.Len(t, actualEvents, 4)
+
+		assert.Equal(t, DockerProgressLayer, actualEvents[1].Type)
+		assert.Equal(t, "5f70bf18a086", actualEvents[1].LayerID)
+		assert.Equal(t, "Preparing", actualEvents[1].Status)
+		assert.Equal(t, "Layer already exists", actualEvents[2].Status)
+		assert.Equal(t, DockerProgressDigest, actualEvents[3].Type)
+	})
+
+	t.Run("when pushing fails, it returns error with the output", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"ExecuteWithStreams",
+			"docker",
+			[]string{"push", "docker.io/example/app:v1"},
+			[]string(nil),
+			"",
+			mock.Anything,
+			mock.Anything,
+		).Run(func(args mock.Arguments) {
+			stderr := args.Get(5).(io.Writer)
+			_, _ = stderr.Write([]byte("denied: requested access to the resource is denied\n"))
+		}).Return(errors.New("exit status 1"))
+
+		dockerInstance := NewDocker(executorArg)
+		actual, err := dockerInstance.PushWithProgress(MustParseImageRef("example/app:v1"), nil)
+		require.NotNil(t, err)
+		assert.Equal(t, "", actual)
+		assert.Contains(t, err.Error(), "requested access to the resource is denied")
+	})
+}
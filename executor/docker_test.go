@@ -17,8 +17,11 @@ package executor
 import (
 	"errors"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"github.com/sumup-oss/go-pkgs/os/ostest"
+	"io/ioutil"
+	"path/filepath"
 	"testing"
 )
 
@@ -33,12 +36,12 @@ func TestNewDocker(t *testing.T) {
 func TestDocker_Push(t *testing.T) {
 	t.Run("when pushing does not fail, it returns nil", func(t *testing.T) {
 		executorArg := &ostest.FakeOsExecutor{}
-		imageArg := "example"
+		imageArg := MustParseImageRef("example")
 
 		executorArg.On(
 			"Execute",
 			"docker",
-			[]string{"push", imageArg},
+			[]string{"push", "docker.io/library/example:latest"},
 			[]string(nil),
 			"",
 		).Return([]byte{}, []byte{}, nil)
@@ -50,7 +53,7 @@ func TestDocker_Push(t *testing.T) {
 
 	t.Run("when pushing fails, it returns error", func(t *testing.T) {
 		executorArg := &ostest.FakeOsExecutor{}
-		imageArg := "example"
+		imageArg := MustParseImageRef("example")
 
 		fakeError := errors.New("fake error")
 		fakeStdout := []byte("fake stdout")
@@ -58,7 +61,7 @@ func TestDocker_Push(t *testing.T) {
 		executorArg.On(
 			"Execute",
 			"docker",
-			[]string{"push", imageArg},
+			[]string{"push", "docker.io/library/example:latest"},
 			[]string(nil),
 			"",
 		).Return(fakeStdout, fakeStderr, fakeError)
@@ -70,17 +73,28 @@ func TestDocker_Push(t *testing.T) {
 		assert.Contains(t, actual.Error(), string(fakeStdout))
 		assert.Contains(t, actual.Error(), string(fakeStderr))
 	})
+
+	t.Run("when the image has a digest without a tag, it returns error without pushing", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+		imageArg := MustParseImageRef("example@" + testPushDigest)
+
+		dockerInstance := NewDocker(executorArg)
+		actual := dockerInstance.Push(imageArg)
+		require.NotNil(t, actual)
+		assert.Contains(t, actual.Error(), "without a tag")
+		executorArg.AssertNotCalled(t, "Execute", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
 }
 
 func TestDocker_Pull(t *testing.T) {
 	t.Run("when pulling does not fail, it returns nil", func(t *testing.T) {
 		executorArg := &ostest.FakeOsExecutor{}
-		imageArg := "example"
+		imageArg := MustParseImageRef("example")
 
 		executorArg.On(
 			"Execute",
 			"docker",
-			[]string{"pull", imageArg},
+			[]string{"pull", "docker.io/library/example:latest"},
 			[]string(nil),
 			"",
 		).Return([]byte{}, []byte{}, nil)
@@ -92,7 +106,7 @@ func TestDocker_Pull(t *testing.T) {
 
 	t.Run("when pulling fails, it returns error", func(t *testing.T) {
 		executorArg := &ostest.FakeOsExecutor{}
-		imageArg := "example"
+		imageArg := MustParseImageRef("example")
 
 		fakeError := errors.New("fake error")
 		fakeStdout := []byte("fake stdout")
@@ -100,7 +114,7 @@ func TestDocker_Pull(t *testing.T) {
 		executorArg.On(
 			"Execute",
 			"docker",
-			[]string{"pull", imageArg},
+			[]string{"pull", "docker.io/library/example:latest"},
 			[]string(nil),
 			"",
 		).Return(fakeStdout, fakeStderr, fakeError)
@@ -117,13 +131,13 @@ func TestDocker_Pull(t *testing.T) {
 func TestDocker_Tag(t *testing.T) {
 	t.Run("when tagging does not fail, it returns nil", func(t *testing.T) {
 		executorArg := &ostest.FakeOsExecutor{}
-		oldImageArg := "example"
-		newImageArg := "newexample"
+		oldImageArg := MustParseImageRef("example")
+		newImageArg := MustParseImageRef("registry.example.com:5000/team/newexample:v1")
 
 		executorArg.On(
 			"Execute",
 			"docker",
-			[]string{"tag", oldImageArg, newImageArg},
+			[]string{"tag", "docker.io/library/example:latest", "registry.example.com:5000/team/newexample:v1"},
 			[]string(nil),
 			"",
 		).Return([]byte{}, []byte{}, nil)
@@ -135,8 +149,8 @@ func TestDocker_Tag(t *testing.T) {
 
 	t.Run("when tagging fails, it returns error", func(t *testing.T) {
 		executorArg := &ostest.FakeOsExecutor{}
-		oldImageArg := "example"
-		newImageArg := "newexample"
+		oldImageArg := MustParseImageRef("example")
+		newImageArg := MustParseImageRef("registry.example.com:5000/team/newexample:v1")
 
 		fakeError := errors.New("fake error")
 		fakeStdout := []byte("fake stdout")
@@ -144,7 +158,7 @@ func TestDocker_Tag(t *testing.T) {
 		executorArg.On(
 			"Execute",
 			"docker",
-			[]string{"tag", oldImageArg, newImageArg},
+			[]string{"tag", "docker.io/library/example:latest", "registry.example.com:5000/team/newexample:v1"},
 			[]string(nil),
 			"",
 		).Return(fakeStdout, fakeStderr, fakeError)
@@ -169,7 +183,7 @@ func TestDocker_Build(t *testing.T) {
 			optionsArg := &DockerBuildOptions{
 				File:       "./examplefile",
 				ContextDir: ".",
-				Tag:        "mytag",
+				Tag:        MustParseImageRef("mytag"),
 			}
 
 			fakeError := errors.New("fake error")
@@ -183,7 +197,7 @@ func TestDocker_Build(t *testing.T) {
 					"-f",
 					optionsArg.File,
 					"--tag",
-					optionsArg.Tag,
+					optionsArg.Tag.String(),
 					optionsArg.ContextDir,
 				},
 				[]string(nil),
@@ -209,7 +223,7 @@ func TestDocker_Build(t *testing.T) {
 				File:       "./examplefile",
 				Target:     "",
 				ContextDir: ".",
-				Tag:        "mytag",
+				Tag:        MustParseImageRef("mytag"),
 			}
 
 			executorArg.On(
@@ -220,7 +234,7 @@ func TestDocker_Build(t *testing.T) {
 					"-f",
 					optionsArg.File,
 					"--tag",
-					optionsArg.Tag,
+					optionsArg.Tag.String(),
 					optionsArg.ContextDir,
 				},
 				[]string(nil),
@@ -243,7 +257,7 @@ func TestDocker_Build(t *testing.T) {
 				File:       "./examplefile",
 				Target:     "mytarget",
 				ContextDir: ".",
-				Tag:        "mytag",
+				Tag:        MustParseImageRef("mytag"),
 			}
 
 			executorArg.On(
@@ -254,7 +268,7 @@ func TestDocker_Build(t *testing.T) {
 					"-f",
 					optionsArg.File,
 					"--tag",
-					optionsArg.Tag,
+					optionsArg.Tag.String(),
 					"--target",
 					optionsArg.Target,
 					optionsArg.ContextDir,
@@ -279,7 +293,7 @@ func TestDocker_Build(t *testing.T) {
 				File:       "./examplefile",
 				Target:     "mytarget",
 				ContextDir: ".",
-				Tag:        "mytag",
+				Tag:        MustParseImageRef("mytag"),
 			}
 
 			executorArg.On(
@@ -290,7 +304,7 @@ func TestDocker_Build(t *testing.T) {
 					"-f",
 					optionsArg.File,
 					"--tag",
-					optionsArg.Tag,
+					optionsArg.Tag.String(),
 					"--target",
 					optionsArg.Target,
 					optionsArg.ContextDir,
@@ -315,7 +329,7 @@ func TestDocker_Build(t *testing.T) {
 				File:       "./examplefile",
 				Hosts:      map[string]string{"examplehost": "exampleaddress"},
 				ContextDir: ".",
-				Tag:        "mytag",
+				Tag:        MustParseImageRef("mytag"),
 			}
 
 			executorArg.On(
@@ -326,7 +340,7 @@ func TestDocker_Build(t *testing.T) {
 					"-f",
 					optionsArg.File,
 					"--tag",
-					optionsArg.Tag,
+					optionsArg.Tag.String(),
 					"--add-host=examplehost:exampleaddress",
 					optionsArg.ContextDir,
 				},
@@ -350,7 +364,7 @@ func TestDocker_Build(t *testing.T) {
 				File:       "./examplefile",
 				BuildArgs:  []string{"EXAMPLE=VALUE"},
 				ContextDir: ".",
-				Tag:        "mytag",
+				Tag:        MustParseImageRef("mytag"),
 			}
 
 			executorArg.On(
@@ -361,7 +375,7 @@ func TestDocker_Build(t *testing.T) {
 					"-f",
 					optionsArg.File,
 					"--tag",
-					optionsArg.Tag,
+					optionsArg.Tag.String(),
 					"--build-arg=EXAMPLE=VALUE",
 					optionsArg.ContextDir,
 				},
@@ -375,50 +389,145 @@ func TestDocker_Build(t *testing.T) {
 		},
 	)
 
-}
+	t.Run(
+		"when platforms are present, it builds with buildx and passes the BuildKit options",
+		func(t *testing.T) {
+			executorArg := &ostest.FakeOsExecutor{}
 
-func TestDocker_Login(t *testing.T) {
-	t.Run("when log-in does not fail, it returns nil", func(t *testing.T) {
+			optionsArg := &DockerBuildOptions{
+				File:       "./examplefile",
+				ContextDir: ".",
+				Tag:        MustParseImageRef("example/app:v1"),
+				Tags:       []*ImageRef{MustParseImageRef("example/app:latest")},
+				Labels:     map[string]string{"org.opencontainers.image.revision": "abc123"},
+				CacheFrom:  []*ImageRef{MustParseImageRef("example/app:cache")},
+				CacheTo:    []string{"type=inline"},
+				Platforms:  []string{"linux/amd64", "linux/arm64"},
+				NoCache:    true,
+				Pull:       true,
+				Network:    "host",
+				IIDFile:    "/tmp/iid",
+				Push:       true,
+			}
+
+			executorArg.On(
+				"Execute",
+				"docker",
+				[]string{
+					"buildx",
+					"build",
+					"-f",
+					optionsArg.File,
+					"--tag",
+					"docker.io/example/app:v1",
+					"--tag",
+					"docker.io/example/app:latest",
+					"--label=org.opencontainers.image.revision=abc123",
+					"--cache-from=docker.io/example/app:cache",
+					"--cache-to=type=inline",
+					"--platform=linux/amd64,linux/arm64",
+					"--no-cache",
+					"--pull",
+					"--network=host",
+					"--iidfile=/tmp/iid",
+					"--push",
+					optionsArg.ContextDir,
+				},
+				[]string(nil),
+				"",
+			).Return([]byte{}, []byte{}, nil)
+
+			dockerInstance := NewDocker(executorArg)
+			actual := dockerInstance.Build(optionsArg)
+			require.Nil(t, actual)
+		},
+	)
+
+	t.Run(
+		"when secrets are present without buildx, it enables BuildKit",
+		func(t *testing.T) {
+			executorArg := &ostest.FakeOsExecutor{}
+
+			optionsArg := &DockerBuildOptions{
+				File:       "./examplefile",
+				ContextDir: ".",
+				Tag:        MustParseImageRef("mytag"),
+				Secrets:    []string{"id=npmrc,src=/home/ci/.npmrc"},
+				SSH:        []string{"default"},
+			}
+
+			executorArg.On(
+				"Execute",
+				"docker",
+				[]string{
+					"build",
+					"-f",
+					optionsArg.File,
+					"--tag",
+					optionsArg.Tag.String(),
+					"--secret=id=npmrc,src=/home/ci/.npmrc",
+					"--ssh=default",
+					optionsArg.ContextDir,
+				},
+				extendEnv(nil, []string{"DOCKER_BUILDKIT=1"}),
+				"",
+			).Return([]byte{}, []byte{}, nil)
+
+			dockerInstance := NewDocker(executorArg)
+			actual := dockerInstance.Build(optionsArg)
+			require.Nil(t, actual)
+		},
+	)
+
+	t.Run("when push or load are present without buildx, it returns error without building", func(t *testing.T) {
 		executorArg := &ostest.FakeOsExecutor{}
-		usernameArg := "example"
-		passwordArg := "examplePass"
-		registryUrlArg := "exampleRegistry"
 
-		executorArg.On(
-			"Execute",
-			"docker",
-			[]string{"login", "-u", usernameArg, "-p", passwordArg, registryUrlArg},
-			[]string(nil),
-			"",
-		).Return([]byte{}, []byte{}, nil)
+		dockerInstance := NewDocker(executorArg)
+		actual := dockerInstance.Build(&DockerBuildOptions{File: "./examplefile", ContextDir: ".", Push: true})
+		require.NotNil(t, actual)
+		assert.Contains(t, actual.Error(), "require buildx")
+		executorArg.AssertNotCalled(t, "Execute", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("when platforms are present without push or load, it returns error without building", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
 
 		dockerInstance := NewDocker(executorArg)
-		actual := dockerInstance.Login(usernameArg, passwordArg, registryUrlArg)
-		require.Nil(t, actual)
+		actual := dockerInstance.Build(
+			&DockerBuildOptions{File: "./examplefile", ContextDir: ".", Platforms: []string{"linux/arm64"}},
+		)
+		require.NotNil(t, actual)
+		assert.Contains(t, actual.Error(), "requires Push or Load")
+		executorArg.AssertNotCalled(t, "Execute", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
 	})
+}
 
-	t.Run("when log-in fails, it returns error", func(t *testing.T) {
+func TestDocker_BuildImage(t *testing.T) {
+	t.Run("it returns the image ID written to the iidfile", func(t *testing.T) {
 		executorArg := &ostest.FakeOsExecutor{}
-		usernameArg := "example"
-		passwordArg := "examplePass"
-		registryUrlArg := "exampleRegistry"
 
-		fakeError := errors.New("fake error")
-		fakeStdout := []byte("fake stdout")
-		fakeStderr := []byte("fake stderr")
+		iidFile := filepath.Join(t.TempDir(), "iid")
+
+		optionsArg := &DockerBuildOptions{
+			File:       "./examplefile",
+			ContextDir: ".",
+			Tag:        MustParseImageRef("mytag"),
+			IIDFile:    iidFile,
+		}
+
 		executorArg.On(
 			"Execute",
 			"docker",
-			[]string{"login", "-u", usernameArg, "-p", passwordArg, registryUrlArg},
+			[]string{"build", "-f", optionsArg.File, "--tag", optionsArg.Tag.String(), "--iidfile=" + iidFile, optionsArg.ContextDir},
 			[]string(nil),
 			"",
-		).Return(fakeStdout, fakeStderr, fakeError)
+		).Run(func(args mock.Arguments) {
+			require.Nil(t, ioutil.WriteFile(iidFile, []byte("sha256:4f1c0e2d9a7b\n"), 0644))
+		}).Return([]byte{}, []byte{}, nil)
 
 		dockerInstance := NewDocker(executorArg)
-		actual := dockerInstance.Login(usernameArg, passwordArg, registryUrlArg)
-		require.NotNil(t, actual)
-		assert.Contains(t, actual.Error(), fakeError.Error())
-		assert.Contains(t, actual.Error(), string(fakeStdout))
-		assert.Contains(t, actual.Error(), string(fakeStderr))
+		actual, actualErr := dockerInstance.BuildImage(optionsArg)
+		require.Nil(t, actualErr)
+		assert.Equal(t, "sha256:4f1c0e2d9a7b", actual)
 	})
 }
@@ -0,0 +1,145 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/palantir/stacktrace"
+)
+
+type DockerVolumeCreateOptions struct {
+	// Name is generated by docker when empty.
+	Name string
+	// Driver defaults to "local".
+	Driver string
+	// DriverOpts are options of the driver, e.g "type=tmpfs".
+	DriverOpts map[string]string
+	Labels     map[string]string
+}
+
+// DockerVolume is the parsed output of `docker volume inspect`.
+type DockerVolume struct {
+	Name       string            `json:"Name"`
+	Driver     string            `json:"Driver"`
+	Mountpoint string            `json:"Mountpoint"`
+	Scope      string            `json:"Scope"`
+	CreatedAt  time.Time         `json:"CreatedAt"`
+	Labels     map[string]string `json:"Labels"`
+	Options    map[string]string `json:"Options"`
+}
+
+// VolumeCreate creates a volume and returns its name.
+func (docker *Docker) VolumeCreate(options *DockerVolumeCreateOptions) (string, error) {
+	if options == nil {
+		options = &DockerVolumeCreateOptions{}
+	}
+
+	args := []string{"volume", "create"}
+
+	if options.Driver != "" {
+		args = append(args, fmt.Sprintf("--driver=%s", options.Driver))
+	}
+
+	driverOptKeys := make([]string, 0, len(options.DriverOpts))
+	for key := range options.DriverOpts {
+		driverOptKeys = append(driverOptKeys, key)
+	}
+
+	sort.Strings(driverOptKeys)
+
+	for _, key := range driverOptKeys {
+		args = append(args, fmt.Sprintf("--opt=%s=%s", key, options.DriverOpts[key]))
+	}
+
+	args = append(args, dockerLabelArgs(options.Labels)...)
+
+	if options.Name != "" {
+		args = append(args, options.Name)
+	}
+
+	stdout, stderr, err := docker.commandExecutor.Execute(docker.binaryPath, args, docker.env(nil), "")
+	if err != nil {
+		return "", stacktrace.Propagate(err, "Stderr: %s, Stdout: %s", stderr, stdout)
+	}
+
+	return strings.TrimSpace(string(stdout)), nil
+}
+
+// VolumeInspect returns the volume `name`.
+func (docker *Docker) VolumeInspect(name string) (*DockerVolume, error) {
+	volumes, err := docker.volumeInspect([]string{name})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(volumes) == 0 {
+		return nil, stacktrace.NewError("docker volume %s not found", name)
+	}
+
+	return volumes[0], nil
+}
+
+// VolumeList returns the volumes matching `filters`, e.g "label=ci.job=42" or "dangling=true".
+func (docker *Docker) VolumeList(filters []string) ([]*DockerVolume, error) {
+	names, err := docker.listIDs([]string{"volume", "ls", "--quiet"}, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(names) == 0 {
+		return make([]*DockerVolume, 0), nil
+	}
+
+	return docker.volumeInspect(names)
+}
+
+// VolumeRemove removes the volume `name`.
+// When `force` is true, no error is returned when the volume does not exist.
+func (docker *Docker) VolumeRemove(name string, force bool) error {
+	args := []string{"volume", "rm"}
+	if force {
+		args = append(args, "--force")
+	}
+
+	args = append(args, name)
+
+	stdout, stderr, err := docker.commandExecutor.Execute(docker.binaryPath, args, docker.env(nil), "")
+	return stacktrace.Propagate(err, "Stderr: %s, Stdout: %s", stderr, stdout)
+}
+
+func (docker *Docker) volumeInspect(names []string) ([]*DockerVolume, error) {
+	stdout, stderr, err := docker.commandExecutor.Execute(
+		docker.binaryPath,
+		append([]string{"volume", "inspect"}, names...),
+		docker.env(nil),
+		"",
+	)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "executing `docker volume inspect` failed. Stderr: %s", stderr)
+	}
+
+	var volumes []*DockerVolume
+	err = json.Unmarshal(stdout, &volumes)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "json decode command `docker volume inspect` output failed")
+	}
+
+	return volumes, nil
+}
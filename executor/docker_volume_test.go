@@ -0,0 +1,176 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sumup-oss/go-pkgs/os/ostest"
+)
+
+const testVolumeInspectOutput = `[
+  {
+    "CreatedAt": "2019-06-01T10:00:00Z",
+    "Driver": "local",
+    "Labels": {"ci.job": "42"},
+    "Mountpoint": "/var/lib/docker/volumes/ci-42-cache/_data",
+    "Name": "ci-42-cache",
+    "Options": {"type": "tmpfs"},
+    "Scope": "local"
+  }
+]`
+
+func TestDocker_VolumeCreate(t *testing.T) {
+	t.Run("it passes the options and returns the volume name", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{
+				"volume",
+				"create",
+				"--driver=local",
+				"--opt=device=tmpfs",
+				"--opt=type=tmpfs",
+				"--label=ci.job=42",
+				"ci-42-cache",
+			},
+			[]string(nil),
+			"",
+		).Return([]byte("ci-42-cache\n"), []byte{}, nil)
+
+		dockerInstance := NewDocker(executorArg)
+		actual, actualErr := dockerInstance.VolumeCreate(
+			&DockerVolumeCreateOptions{
+				Name:       "ci-42-cache",
+				Driver:     "local",
+				DriverOpts: map[string]string{"type": "tmpfs", "device": "tmpfs"},
+				Labels:     map[string]string{"ci.job": "42"},
+			},
+		)
+		require.Nil(t, actualErr)
+		assert.Equal(t, "ci-42-cache", actual)
+	})
+
+	t.Run("when options are nil, it creates an anonymous volume", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"volume", "create"},
+			[]string(nil),
+			"",
+		).Return([]byte("4f1c0e2d9a7b\n"), []byte{}, nil)
+
+		dockerInstance := NewDocker(executorArg)
+		actual, actualErr := dockerInstance.VolumeCreate(nil)
+		require.Nil(t, actualErr)
+		assert.Equal(t, "4f1c0e2d9a7b", actual)
+	})
+}
+
+func TestDocker_VolumeInspect(t *testing.T) {
+	t.Run("it parses the volume", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"volume", "inspect", "ci-42-cache"},
+			[]string(nil),
+			"",
+		).Return([]byte(testVolumeInspectOutput), []byte{}, nil)
+
+		dockerInstance := NewDocker(executorArg)
+		actual, actualErr := dockerInstance.VolumeInspect("ci-42-cache")
+		require.Nil(t, actualErr)
+
+		assert.Equal(t, "ci-42-cache", actual.Name)
+		assert.Equal(t, "local", actual.Driver)
+		assert.Equal(t, "/var/lib/docker/volumes/ci-42-cache/_data", actual.Mountpoint)
+		assert.Equal(t, map[string]string{"ci.job": "42"}, actual.Labels)
+		assert.Equal(t, map[string]string{"type": "tmpfs"}, actual.Options)
+	})
+
+	t.Run("when the volume does not exist, it returns error", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"volume", "inspect", "missing"},
+			[]string(nil),
+			"",
+		).Return([]byte("[]\n"), []byte("Error: No such volume: missing"), errors.New("exit status 1"))
+
+		dockerInstance := NewDocker(executorArg)
+		actual, actualErr := dockerInstance.VolumeInspect("missing")
+		require.NotNil(t, actualErr)
+		assert.Nil(t, actual)
+		assert.Contains(t, actualErr.Error(), "No such volume")
+	})
+}
+
+func TestDocker_VolumeList(t *testing.T) {
+	t.Run("it inspects the volumes matching the filters", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"volume", "ls", "--quiet", "--filter=label=ci.job=42"},
+			[]string(nil),
+			"",
+		).Return([]byte("ci-42-cache\n"), []byte{}, nil)
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"volume", "inspect", "ci-42-cache"},
+			[]string(nil),
+			"",
+		).Return([]byte(testVolumeInspectOutput), []byte{}, nil)
+
+		dockerInstance := NewDocker(executorArg)
+		actual, actualErr := dockerInstance.VolumeList([]string{"label=ci.job=42"})
+		require.Nil(t, actualErr)
+		require.Len(t, actual, 1)
+		assert.Equal(t, "ci-42-cache", actual[0].Name)
+	})
+}
+
+func TestDocker_VolumeRemove(t *testing.T) {
+	t.Run("it forces the removal of the volume", func(t *testing.T) {
+		executorArg := &ostest.FakeOsExecutor{}
+
+		executorArg.On(
+			"Execute",
+			"docker",
+			[]string{"volume", "rm", "--force", "ci-42-cache"},
+			[]string(nil),
+			"",
+		).Return([]byte("ci-42-cache\n"), []byte{}, nil)
+
+		dockerInstance := NewDocker(executorArg)
+		actual := dockerInstance.VolumeRemove("ci-42-cache", true)
+		require.Nil(t, actual)
+	})
+}
@@ -15,13 +15,17 @@
 package executor
 
 import (
+	"io"
 	"strings"
 
+	"github.com/palantir/stacktrace"
+
 	"github.com/sumup-oss/go-pkgs/logger"
 	"github.com/sumup-oss/go-pkgs/os"
 )
 
 var _ os.OsExecutor = (*ExecuteLogger)(nil)
+var _ os.StdinExecutor = (*ExecuteLogger)(nil)
 
 // ExecuteLogger is os.OsExecutor decorator, that decorates the Execute method for real time debug logging.
 type ExecuteLogger struct {
@@ -53,3 +57,29 @@ func (c *ExecuteLogger) Execute(cmd string, arg []string, env []string, dir stri
 
 	return []byte(stdout.GetOutput()), []byte(stderr.GetOutput()), err
 }
+
+// ExecuteWithStdin logs the command like Execute, but not `stdin`.
+// The output is logged once the command exits.
+func (c *ExecuteLogger) ExecuteWithStdin(
+	cmd string,
+	arg []string,
+	env []string,
+	dir string,
+	stdin io.Reader,
+) ([]byte, []byte, error) {
+	stdinExecutor, ok := c.OsExecutor.(os.StdinExecutor)
+	if !ok {
+		return nil, nil, stacktrace.NewError("os executor does not support stdin")
+	}
+
+	c.log.Debugf("command# %s %s", cmd, strings.Join(arg, " "))
+
+	stdout, stderr, err := stdinExecutor.ExecuteWithStdin(cmd, arg, env, dir, stdin)
+
+	stdoutWriter := NewRealtimeWriter(c.log, c.logLevel)
+	_, _ = stdoutWriter.Write(stdout)
+	stderrWriter := NewRealtimeWriter(c.log, c.logLevel)
+	_, _ = stderrWriter.Write(stderr)
+
+	return []byte(stdoutWriter.GetOutput()), []byte(stderrWriter.GetOutput()), err
+}
@@ -33,20 +33,34 @@ type Git struct {
 }
 
 func NewGit(executor os.CommandExecutor, url, dir string, env []string) *Git {
-	var gitEnv []string
-
-	if len(env) < 1 {
-		gitEnv = make([]string, 0)
-	} else {
-		gitEnv = env
-	}
-
 	return &Git{
 		binPath:         "git",
 		dir:             dir,
 		url:             url,
 		commandExecutor: executor,
-		env:             gitEnv,
+		env:             gitLocaleEnv(env),
+	}
+}
+
+// gitLocaleEnv returns `env`, or the environment of the process when empty, with the C locale,
+// so that newGitError can classify the untranslated messages of git.
+func gitLocaleEnv(env []string) []string {
+	return extendEnv(env, []string{"LC_ALL=C"})
+}
+
+// withDir returns a Git instance bound to `dir`, sharing the executor, url and env.
+// A relative `dir` is relative to the repository directory.
+func (git *Git) withDir(dir string) *Git {
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(git.dir, dir)
+	}
+
+	return &Git{
+		binPath:         git.binPath,
+		dir:             dir,
+		url:             git.url,
+		env:             git.env,
+		commandExecutor: git.commandExecutor,
 	}
 }
 
@@ -63,7 +77,7 @@ func (git *Git) Clone() error {
 	)
 
 	if err != nil {
-		return fmt.Errorf("%s. Stderr: %s", err, stderr)
+		return newGitError(err, nil, stderr)
 	}
 
 	return nil
@@ -78,7 +92,7 @@ func (git *Git) CloneWithoutCheckout() error {
 	)
 
 	if err != nil {
-		return fmt.Errorf("%s. Stderr: %s", err, stderr)
+		return newGitError(err, nil, stderr)
 	}
 
 	return nil
@@ -92,7 +106,7 @@ func (git *Git) HasDiff() (bool, error) {
 		"",
 	)
 	if err != nil {
-		return false, fmt.Errorf("%s. Stderr: %s", err, stderr)
+		return false, newGitError(err, nil, stderr)
 	}
 
 	return len(output) > 0, nil
@@ -107,14 +121,14 @@ func (git *Git) Add(file string) error {
 	)
 
 	if err != nil {
-		return fmt.Errorf("%s. Stderr: %s", err, stderr)
+		return newGitError(err, nil, stderr)
 	}
 
 	return nil
 }
 
 func (git *Git) Commit(message string) error {
-	_, stderr, err := git.commandExecutor.Execute(
+	stdout, stderr, err := git.commandExecutor.Execute(
 		git.binPath,
 		[]string{"-C", git.dir, "commit", "-m", message},
 		git.env,
@@ -122,37 +136,14 @@ func (git *Git) Commit(message string) error {
 	)
 
 	if err != nil {
-		return fmt.Errorf("%s. Stderr: %s", err, stderr)
-	}
-
-	return nil
-}
-
-func (git *Git) Push(destination string, force bool) error {
-	forceArg := ""
-
-	if force {
-		forceArg = "--force"
-	}
-
-	args := []string{"-C", git.dir, "push", forceArg}
-	args = append(args, strings.Split(destination, " ")...)
-
-	_, stderr, err := git.commandExecutor.Execute(
-		git.binPath,
-		args,
-		git.env,
-		"",
-	)
-	if err != nil {
-		return fmt.Errorf("failed to execute git command. Err: %s. Stderr: %s", err, stderr)
+		return newGitError(err, stdout, stderr)
 	}
 
 	return nil
 }
 
 func (git *Git) Pull() error {
-	_, stderr, err := git.commandExecutor.Execute(
+	stdout, stderr, err := git.commandExecutor.Execute(
 		git.binPath,
 		[]string{"-C", git.dir, "pull"},
 		git.env,
@@ -160,7 +151,7 @@ func (git *Git) Pull() error {
 	)
 
 	if err != nil {
-		return fmt.Errorf("%s. Stderr: %s", err, stderr)
+		return newGitError(err, stdout, stderr)
 	}
 
 	return nil
@@ -176,7 +167,7 @@ func (git *Git) Fetch() error {
 	)
 
 	if err != nil {
-		return fmt.Errorf("%s. Stderr: %s", err, stderr)
+		return newGitError(err, nil, stderr)
 	}
 
 	return nil
@@ -203,7 +194,7 @@ func (git *Git) SwitchAndReset(branch string) error {
 			"",
 		)
 		if err != nil {
-			return fmt.Errorf("%s. Stderr: %s", err, stderr)
+			return newGitError(err, nil, stderr)
 		}
 	}
 
@@ -219,7 +210,7 @@ func (git *Git) ListBranches() ([]string, error) {
 	)
 
 	if err != nil {
-		return nil, fmt.Errorf("%s. Stderr: %s", err, stderr)
+		return nil, newGitError(err, nil, stderr)
 	}
 
 	branchesOutput := strings.Split(string(stdout), "\n")
@@ -253,7 +244,7 @@ func (git *Git) ListTags() ([]string, error) {
 	)
 
 	if err != nil {
-		return nil, fmt.Errorf("%s. Stderr: %s", err, stderr)
+		return nil, newGitError(err, nil, stderr)
 	}
 
 	tagsOutput := strings.Split(string(stdout), "\n")
@@ -280,7 +271,7 @@ func (git *Git) CheckoutClean() error {
 	)
 
 	if err != nil {
-		return fmt.Errorf("%s. Stderr: %s", err, stderr)
+		return newGitError(err, nil, stderr)
 	}
 
 	return nil
@@ -295,7 +286,7 @@ func (git *Git) EnableSparseCheckout() error {
 	)
 
 	if err != nil {
-		return fmt.Errorf("%s. Stderr: %s", err, stderr)
+		return newGitError(err, nil, stderr)
 	}
 
 	return nil
@@ -309,12 +300,15 @@ func (git *Git) DisableSparseCheckout() error {
 		"",
 	)
 	if err != nil {
-		return fmt.Errorf("%s. Stderr: %s", err, stderr)
+		return newGitError(err, nil, stderr)
 	}
 
 	return nil
 }
 
+// SetSparseCheckoutPaths writes the raw sparse checkout patterns, without updating the working directory.
+//
+// Deprecated: Use SparseCheckoutSet, which also updates the working directory and supports cone mode.
 func (git *Git) SetSparseCheckoutPaths(paths []string) error {
 	sparsePaths := strings.Join(paths, "\n")
 	sparseConfigPath := filepath.Join(git.dir, ".git", "info", "sparse-checkout")
@@ -336,7 +330,7 @@ func (git *Git) GetCurrentHash() (string, error) {
 	)
 
 	if err != nil {
-		return "", fmt.Errorf("%s. Stderr: %s", err, stderr)
+		return "", newGitError(err, nil, stderr)
 	}
 
 	return strings.Trim(string(stdout), "\n\r "), nil
@@ -365,7 +359,7 @@ func (git *Git) GetCurrentHashForPath(path string) (string, error) {
 
 	stdout, stderr, err := git.commandExecutor.Execute(git.binPath, args, git.env, "")
 	if err != nil {
-		return "", fmt.Errorf("failed to execute git command. Err: %s. Stderr: %s", err, stderr)
+		return "", newGitError(err, nil, stderr)
 	}
 
 	stdoutParts := strings.Split(string(stdout), " ")
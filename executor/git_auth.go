@@ -0,0 +1,245 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"fmt"
+	stdOs "os"
+	"strconv"
+	"strings"
+
+	"github.com/sumup-oss/go-pkgs/os"
+)
+
+const (
+	gitAuthUsernameEnv = "GIT_AUTH_USERNAME"
+	gitAuthPasswordEnv = "GIT_AUTH_PASSWORD"
+
+	defaultGitTokenUsername = "x-access-token"
+
+	gitConfigCountEnv       = "GIT_CONFIG_COUNT"
+	gitConfigKeyEnvPrefix   = "GIT_CONFIG_KEY_"
+	gitConfigValueEnvPrefix = "GIT_CONFIG_VALUE_"
+)
+
+// NOTE: The credential helper only references environment variables,
+// so that the secrets are never part of a command line visible in `ps` or in the `ExecuteLogger` output.
+var gitEnvCredentialHelper = fmt.Sprintf(
+	`!f() { test "$1" = get && echo "username=${%s}" && echo "password=${%s}"; }; f`,
+	gitAuthUsernameEnv,
+	gitAuthPasswordEnv,
+)
+
+// GitAuth provides the environment variables that configure git to authenticate against remotes.
+type GitAuth interface {
+	Env() []string
+}
+
+var _ GitAuth = (*GitSSHAuth)(nil)
+var _ GitAuth = (*GitTokenAuth)(nil)
+var _ GitAuth = (*GitBasicAuth)(nil)
+
+// GitSSHAuth authenticates with a private key over SSH.
+// SSH is always run in batch mode, so it fails instead of prompting for passphrases or unknown host keys.
+type GitSSHAuth struct {
+	PrivateKeyPath string
+	// KnownHostsPath is the known_hosts file the remote host key is verified against.
+	// When empty, the user's known_hosts file is used.
+	KnownHostsPath string
+	// AcceptNewHostKeys trusts and records host keys of hosts that are not in known_hosts yet,
+	// but still rejects changed host keys.
+	AcceptNewHostKeys bool
+	// InsecureIgnoreHostKey disables host key verification completely.
+	// Only meant for tests against throwaway servers.
+	InsecureIgnoreHostKey bool
+}
+
+// Env returns `GIT_SSH_COMMAND` configured with the private key and host key verification.
+func (auth *GitSSHAuth) Env() []string {
+	sshArgs := []string{"ssh", "-o", "BatchMode=yes"}
+
+	if auth.PrivateKeyPath != "" {
+		sshArgs = append(sshArgs, "-i", shellQuote(auth.PrivateKeyPath), "-o", "IdentitiesOnly=yes")
+	}
+
+	switch {
+	case auth.InsecureIgnoreHostKey:
+		sshArgs = append(sshArgs, "-o", "StrictHostKeyChecking=no", "-o", "UserKnownHostsFile=/dev/null")
+	case auth.AcceptNewHostKeys:
+		sshArgs = append(sshArgs, "-o", "StrictHostKeyChecking=accept-new")
+	default:
+		sshArgs = append(sshArgs, "-o", "StrictHostKeyChecking=yes")
+	}
+
+	if auth.KnownHostsPath != "" && !auth.InsecureIgnoreHostKey {
+		sshArgs = append(sshArgs, "-o", fmt.Sprintf("UserKnownHostsFile=%s", shellQuote(auth.KnownHostsPath)))
+	}
+
+	return []string{
+		fmt.Sprintf("GIT_SSH_COMMAND=%s", strings.Join(sshArgs, " ")),
+		"GIT_SSH_VARIANT=ssh",
+	}
+}
+
+// GitTokenAuth authenticates over HTTPS with an access token.
+type GitTokenAuth struct {
+	// Username defaults to "x-access-token", which is accepted by GitHub.
+	// Other providers expect specific usernames, e.g "oauth2" for GitLab.
+	Username string
+	Token    string
+}
+
+// Env returns an ephemeral credential helper that answers with the token.
+func (auth *GitTokenAuth) Env() []string {
+	username := auth.Username
+	if username == "" {
+		username = defaultGitTokenUsername
+	}
+
+	return gitCredentialHelperEnv(username, auth.Token)
+}
+
+// GitBasicAuth authenticates over HTTPS with username and password.
+type GitBasicAuth struct {
+	Username string
+	Password string
+}
+
+// Env returns an ephemeral credential helper that answers with the username and password.
+func (auth *GitBasicAuth) Env() []string {
+	return gitCredentialHelperEnv(auth.Username, auth.Password)
+}
+
+// NewGitWithAuth creates a Git instance that authenticates against remotes using `auth`.
+func NewGitWithAuth(executor os.CommandExecutor, url, dir string, env []string, auth GitAuth) *Git {
+	return NewGit(executor, url, dir, extendEnv(env, auth.Env()))
+}
+
+// gitCredentialHelperEnv configures the credential helper through `GIT_CONFIG_*` environment variables,
+// which requires git 2.31 or newer.
+// The first, empty `credential.helper` resets any helpers from the user's or system's git config.
+// Entries of `GIT_CONFIG_*` variables the environment already has are kept by extendEnv.
+func gitCredentialHelperEnv(username, password string) []string {
+	return []string{
+		"GIT_TERMINAL_PROMPT=0",
+		fmt.Sprintf("%s=2", gitConfigCountEnv),
+		fmt.Sprintf("%s0=credential.helper", gitConfigKeyEnvPrefix),
+		fmt.Sprintf("%s0=", gitConfigValueEnvPrefix),
+		fmt.Sprintf("%s1=credential.helper", gitConfigKeyEnvPrefix),
+		fmt.Sprintf("%s1=%s", gitConfigValueEnvPrefix, gitEnvCredentialHelper),
+		fmt.Sprintf("%s=%s", gitAuthUsernameEnv, username),
+		fmt.Sprintf("%s=%s", gitAuthPasswordEnv, password),
+	}
+}
+
+// extendEnv returns `env` with the variables of `overrides` replacing the ones with the same name.
+// When `env` is empty, `overrides` are added to the environment of the current process instead,
+// since a non-empty environment replaces the inherited one.
+func extendEnv(env, overrides []string) []string {
+	if len(env) < 1 {
+		env = stdOs.Environ()
+	}
+
+	return mergeEnv(env, overrides)
+}
+
+// mergeEnv returns `env` with the variables of `overrides` replacing the ones with the same name.
+// The git config entries of both are kept, see offsetGitConfigEnv.
+func mergeEnv(env, overrides []string) []string {
+	overrides = offsetGitConfigEnv(env, overrides)
+
+	overriddenKeys := make(map[string]bool)
+	for _, variable := range overrides {
+		overriddenKeys[envKey(variable)] = true
+	}
+
+	merged := make([]string, 0, len(env)+len(overrides))
+	for _, variable := range env {
+		if overriddenKeys[envKey(variable)] {
+			continue
+		}
+
+		merged = append(merged, variable)
+	}
+
+	return append(merged, overrides...)
+}
+
+// offsetGitConfigEnv numbers the `GIT_CONFIG_KEY_<n>` and `GIT_CONFIG_VALUE_<n>` variables of `overrides`
+// after the ones of `env` and adds up their `GIT_CONFIG_COUNT`, so that the entries of `env` are neither
+// replaced nor left out of the count.
+func offsetGitConfigEnv(env, overrides []string) []string {
+	envCount := gitConfigCount(env)
+	overridesCount := gitConfigCount(overrides)
+	if envCount == 0 || overridesCount == 0 {
+		return overrides
+	}
+
+	offset := make([]string, 0, len(overrides))
+	for _, variable := range overrides {
+		key := envKey(variable)
+		value := strings.TrimPrefix(variable, key+"=")
+
+		switch {
+		case key == gitConfigCountEnv:
+			variable = fmt.Sprintf("%s=%d", gitConfigCountEnv, envCount+overridesCount)
+		case strings.HasPrefix(key, gitConfigKeyEnvPrefix):
+			variable = offsetGitConfigVariable(gitConfigKeyEnvPrefix, key, value, envCount)
+		case strings.HasPrefix(key, gitConfigValueEnvPrefix):
+			variable = offsetGitConfigVariable(gitConfigValueEnvPrefix, key, value, envCount)
+		}
+
+		offset = append(offset, variable)
+	}
+
+	return offset
+}
+
+func offsetGitConfigVariable(prefix, key, value string, offset int) string {
+	index, err := strconv.Atoi(strings.TrimPrefix(key, prefix))
+	if err != nil {
+		return fmt.Sprintf("%s=%s", key, value)
+	}
+
+	return fmt.Sprintf("%s%d=%s", prefix, index+offset, value)
+}
+
+// gitConfigCount returns the `GIT_CONFIG_COUNT` of `env`, or 0 when it's not set or invalid.
+func gitConfigCount(env []string) int {
+	count := 0
+
+	for _, variable := range env {
+		if envKey(variable) != gitConfigCountEnv {
+			continue
+		}
+
+		parsed, err := strconv.Atoi(strings.TrimPrefix(variable, gitConfigCountEnv+"="))
+		if err != nil || parsed < 0 {
+			return 0
+		}
+
+		count = parsed
+	}
+
+	return count
+}
+
+func envKey(variable string) string {
+	return strings.SplitN(variable, "=", 2)[0]
+}
+
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
@@ -0,0 +1,127 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sumup-oss/go-pkgs/os/ostest"
+)
+
+func TestGitSSHAuth_Env(t *testing.T) {
+	t.Run("with known hosts path, it verifies host keys strictly against it", func(t *testing.T) {
+		t.Parallel()
+
+		auth := &GitSSHAuth{
+			PrivateKeyPath: "/home/user/.ssh/id rsa",
+			KnownHostsPath: "/tmp/known_hosts",
+		}
+
+		expected := []string{
+			"GIT_SSH_COMMAND=ssh -o BatchMode=yes -i '/home/user/.ssh/id rsa' -o IdentitiesOnly=yes " +
+				"-o StrictHostKeyChecking=yes -o UserKnownHostsFile='/tmp/known_hosts'",
+			"GIT_SSH_VARIANT=ssh",
+		}
+		assert.Equal(t, expected, auth.Env())
+	})
+
+	t.Run("with insecure ignore host key, it ignores the known hosts path", func(t *testing.T) {
+		t.Parallel()
+
+		auth := &GitSSHAuth{
+			PrivateKeyPath:        "/tmp/id_rsa",
+			KnownHostsPath:        "/tmp/known_hosts",
+			InsecureIgnoreHostKey: true,
+		}
+
+		expected := []string{
+			"GIT_SSH_COMMAND=ssh -o BatchMode=yes -i '/tmp/id_rsa' -o IdentitiesOnly=yes " +
+				"-o StrictHostKeyChecking=no -o UserKnownHostsFile=/dev/null",
+			"GIT_SSH_VARIANT=ssh",
+		}
+		assert.Equal(t, expected, auth.Env())
+	})
+}
+
+func TestGitTokenAuth_Env(t *testing.T) {
+	t.Run("it passes the token only as environment variable to the credential helper", func(t *testing.T) {
+		t.Parallel()
+
+		auth := &GitTokenAuth{Token: "s3cr3t"}
+		actual := auth.Env()
+
+		assert.Contains(t, actual, "GIT_AUTH_USERNAME=x-access-token")
+		assert.Contains(t, actual, "GIT_AUTH_PASSWORD=s3cr3t")
+		assert.Contains(t, actual, "GIT_TERMINAL_PROMPT=0")
+
+		for _, variable := range actual {
+			if strings.HasPrefix(variable, "GIT_AUTH_PASSWORD=") {
+				continue
+			}
+
+			assert.NotContains(t, variable, "s3cr3t")
+		}
+	})
+}
+
+func TestNewGitWithAuth(t *testing.T) {
+	t.Run("it replaces variables of the provided env with the auth ones", func(t *testing.T) {
+		t.Parallel()
+
+		osExecutor := ostest.NewFakeOsExecutor(t)
+		envArg := []string{"HOME=/home/user", "GIT_SSH_COMMAND=ssh"}
+		auth := &GitBasicAuth{Username: "user", Password: "pass"}
+
+		actual := NewGitWithAuth(osExecutor, "https://example.com/repo.git", "/tmp/repo", envArg, auth)
+
+		assert.Equal(t, "https://example.com/repo.git", actual.GetURL())
+		assert.Contains(t, actual.env, "HOME=/home/user")
+		assert.Contains(t, actual.env, "GIT_SSH_COMMAND=ssh")
+		assert.Contains(t, actual.env, "GIT_AUTH_USERNAME=user")
+		assert.Contains(t, actual.env, "GIT_AUTH_PASSWORD=pass")
+
+		sshAuth := &GitSSHAuth{PrivateKeyPath: "/tmp/id_rsa"}
+		actual = NewGitWithAuth(osExecutor, "", "/tmp/repo", envArg, sshAuth)
+
+		assert.NotContains(t, actual.env, "GIT_SSH_COMMAND=ssh")
+		assert.Equal(t, append(append([]string{"HOME=/home/user"}, sshAuth.Env()...), "LC_ALL=C"), actual.env)
+	})
+	t.Run("it numbers the credential helper config after the git config of the provided env", func(t *testing.T) {
+		t.Parallel()
+
+		osExecutor := ostest.NewFakeOsExecutor(t)
+		envArg := []string{
+			"HOME=/home/user",
+			"GIT_CONFIG_COUNT=1",
+			"GIT_CONFIG_KEY_0=http.sslVerify",
+			"GIT_CONFIG_VALUE_0=false",
+		}
+		auth := &GitTokenAuth{Token: "token"}
+
+		actual := NewGitWithAuth(osExecutor, "", "/tmp/repo", envArg, auth)
+
+		assert.Contains(t, actual.env, "GIT_CONFIG_KEY_0=http.sslVerify")
+		assert.Contains(t, actual.env, "GIT_CONFIG_VALUE_0=false")
+		assert.Contains(t, actual.env, "GIT_CONFIG_COUNT=3")
+		assert.Contains(t, actual.env, "GIT_CONFIG_KEY_1=credential.helper")
+		assert.Contains(t, actual.env, "GIT_CONFIG_VALUE_1=")
+		assert.Contains(t, actual.env, "GIT_CONFIG_KEY_2=credential.helper")
+		assert.Contains(t, actual.env, "GIT_CONFIG_VALUE_2="+gitEnvCredentialHelper)
+		assert.NotContains(t, actual.env, "GIT_CONFIG_COUNT=1")
+	})
+}
@@ -0,0 +1,230 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GitTreeEntry is a file, symlink or submodule at a revision, as listed by `git ls-tree`.
+type GitTreeEntry struct {
+	Mode string
+	// Type is "blob", or "commit" for submodules.
+	Type string
+	Hash string
+	Path string
+}
+
+type GitBlameCommit struct {
+	Hash           string
+	AuthorName     string
+	AuthorEmail    string
+	AuthorTime     time.Time
+	CommitterName  string
+	CommitterEmail string
+	CommitterTime  time.Time
+	Summary        string
+	// Filename is the path of the file in this commit, which differs from the blamed path after renames.
+	Filename string
+}
+
+type GitBlameLine struct {
+	// LineNumber is the 1-based line number in the blamed revision.
+	LineNumber int
+	// OriginalLineNumber is the 1-based line number in Commit.
+	OriginalLineNumber int
+	Content            string
+	Commit             *GitBlameCommit
+}
+
+// Show returns the content of `path` at revision `rev`.
+// `path` is relative to the repository root.
+func (git *Git) Show(rev, path string) ([]byte, error) {
+	if rev == "" {
+		rev = "HEAD"
+	}
+
+	stdout, stderr, err := git.commandExecutor.Execute(
+		git.binPath,
+		[]string{"-C", git.dir, "show", fmt.Sprintf("%s:%s", rev, path)},
+		git.env,
+		"",
+	)
+	if err != nil {
+		return nil, newGitError(err, nil, stderr)
+	}
+
+	return stdout, nil
+}
+
+// ListFiles returns the files at revision `rev`, recursively, limited to `pathspec` when not empty.
+func (git *Git) ListFiles(rev string, pathspec []string) ([]*GitTreeEntry, error) {
+	if rev == "" {
+		rev = "HEAD"
+	}
+
+	args := []string{"-C", git.dir, "ls-tree", "-r", "-z", rev}
+	if len(pathspec) > 0 {
+		args = append(args, "--")
+		args = append(args, pathspec...)
+	}
+
+	stdout, stderr, err := git.commandExecutor.Execute(git.binPath, args, git.env, "")
+	if err != nil {
+		return nil, newGitError(err, nil, stderr)
+	}
+
+	entries := make([]*GitTreeEntry, 0)
+
+	for _, record := range strings.Split(string(stdout), "\x00") {
+		// NOTE: Every record is "<mode> SP <type> SP <hash> TAB <path>".
+		parts := strings.SplitN(record, "\t", 2)
+		if len(parts) < 2 {
+			continue
+		}
+
+		fields := strings.Fields(parts[0])
+		if len(fields) < 3 {
+			continue
+		}
+
+		entries = append(entries, &GitTreeEntry{
+			Mode: fields[0],
+			Type: fields[1],
+			Hash: fields[2],
+			Path: parts[1],
+		})
+	}
+
+	return entries, nil
+}
+
+// Blame returns the commit that last changed every line of `path` at revision `rev`.
+func (git *Git) Blame(path, rev string) ([]*GitBlameLine, error) {
+	args := []string{"-C", git.dir, "blame", "--porcelain"}
+	if rev != "" {
+		args = append(args, rev)
+	}
+
+	args = append(args, "--", path)
+
+	stdout, stderr, err := git.commandExecutor.Execute(git.binPath, args, git.env, "")
+	if err != nil {
+		return nil, newGitError(err, nil, stderr)
+	}
+
+	return parseGitBlamePorcelain(stdout)
+}
+
+// parseGitBlamePorcelain parses `git blame --porcelain` output.
+// Every line starts with a "<hash> <original line> <final line> [<lines in group>]" header,
+// followed by the commit's metadata the first time the commit appears,
+// and the line content prefixed with a TAB.
+func parseGitBlamePorcelain(output []byte) ([]*GitBlameLine, error) {
+	lines := make([]*GitBlameLine, 0)
+	commits := make(map[string]*GitBlameCommit)
+
+	var current *GitBlameLine
+
+	for _, line := range strings.Split(string(output), "\n") {
+		if strings.HasPrefix(line, "\t") {
+			if current == nil {
+				return nil, fmt.Errorf("failed to parse git blame output, content without header: %s", line)
+			}
+
+			current.Content = strings.TrimPrefix(line, "\t")
+			lines = append(lines, current)
+			current = nil
+
+			continue
+		}
+
+		if line == "" {
+			continue
+		}
+
+		if current == nil {
+			fields := strings.Fields(line)
+			if len(fields) < 3 {
+				return nil, fmt.Errorf("failed to parse git blame output header: %s", line)
+			}
+
+			originalLineNumber, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse git blame output header: %s", line)
+			}
+
+			lineNumber, err := strconv.Atoi(fields[2])
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse git blame output header: %s", line)
+			}
+
+			commit, ok := commits[fields[0]]
+			if !ok {
+				commit = &GitBlameCommit{Hash: fields[0]}
+				commits[fields[0]] = commit
+			}
+
+			current = &GitBlameLine{
+				LineNumber:         lineNumber,
+				OriginalLineNumber: originalLineNumber,
+				Commit:             commit,
+			}
+
+			continue
+		}
+
+		parts := strings.SplitN(line, " ", 2)
+		value := ""
+		if len(parts) > 1 {
+			value = parts[1]
+		}
+
+		commit := current.Commit
+
+		switch parts[0] {
+		case "author":
+			commit.AuthorName = value
+		case "author-mail":
+			commit.AuthorEmail = strings.Trim(value, "<>")
+		case "author-time":
+			commit.AuthorTime = parseGitUnixTime(value)
+		case "committer":
+			commit.CommitterName = value
+		case "committer-mail":
+			commit.CommitterEmail = strings.Trim(value, "<>")
+		case "committer-time":
+			commit.CommitterTime = parseGitUnixTime(value)
+		case "summary":
+			commit.Summary = value
+		case "filename":
+			commit.Filename = value
+		}
+	}
+
+	return lines, nil
+}
+
+func parseGitUnixTime(value string) time.Time {
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return time.Unix(seconds, 0).UTC()
+}
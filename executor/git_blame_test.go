@@ -0,0 +1,172 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sumup-oss/go-pkgs/os/ostest"
+)
+
+func TestGit_Show(t *testing.T) {
+	t.Run("it returns the file content at the revision", func(t *testing.T) {
+		t.Parallel()
+
+		osExecutor := ostest.NewFakeOsExecutor(t)
+		gitInstance := NewGit(osExecutor, "", "/tmp/repo", nil)
+
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{"-C", "/tmp/repo", "show", "v1.0.0:charts/api/values.yaml"},
+			cLocaleEnvArg,
+			"",
+		).Return([]byte("replicas: 2\n"), []byte{}, nil)
+
+		actual, actualErr := gitInstance.Show("v1.0.0", "charts/api/values.yaml")
+		require.Nil(t, actualErr)
+		assert.Equal(t, []byte("replicas: 2\n"), actual)
+	})
+
+	t.Run("when the path does not exist at the revision, it returns error", func(t *testing.T) {
+		t.Parallel()
+
+		osExecutor := ostest.NewFakeOsExecutor(t)
+		gitInstance := NewGit(osExecutor, "", "/tmp/repo", nil)
+
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{"-C", "/tmp/repo", "show", "HEAD:missing.yaml"},
+			cLocaleEnvArg,
+			"",
+		).Return(
+			[]byte{},
+			[]byte("fatal: path 'missing.yaml' does not exist in 'HEAD'"),
+			errors.New("exit status 128"),
+		)
+
+		actual, actualErr := gitInstance.Show("", "missing.yaml")
+		require.NotNil(t, actualErr)
+		assert.Nil(t, actual)
+		assert.Contains(t, actualErr.Error(), "does not exist")
+	})
+}
+
+func TestGit_ListFiles(t *testing.T) {
+	t.Run("it parses the ls-tree entries", func(t *testing.T) {
+		t.Parallel()
+
+		osExecutor := ostest.NewFakeOsExecutor(t)
+		gitInstance := NewGit(osExecutor, "", "/tmp/repo", nil)
+
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{"-C", "/tmp/repo", "ls-tree", "-r", "-z", "main", "--", "charts"},
+			cLocaleEnvArg,
+			"",
+		).Return(
+			[]byte(
+				"100644 blob aaa111\tcharts/api/values.yaml\x00"+
+					"160000 commit bbb222\tcharts/shared\x00",
+			),
+			[]byte{},
+			nil,
+		)
+
+		actual, actualErr := gitInstance.ListFiles("main", []string{"charts"})
+		require.Nil(t, actualErr)
+
+		expected := []*GitTreeEntry{
+			{Mode: "100644", Type: "blob", Hash: "aaa111", Path: "charts/api/values.yaml"},
+			{Mode: "160000", Type: "commit", Hash: "bbb222", Path: "charts/shared"},
+		}
+		assert.Equal(t, expected, actual)
+	})
+}
+
+func TestGit_Blame(t *testing.T) {
+	t.Run("it parses the porcelain output, sharing commits between lines", func(t *testing.T) {
+		t.Parallel()
+
+		osExecutor := ostest.NewFakeOsExecutor(t)
+		gitInstance := NewGit(osExecutor, "", "/tmp/repo", nil)
+
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{"-C", "/tmp/repo", "blame", "--porcelain", "main", "--", "values.yaml"},
+			cLocaleEnvArg,
+			"",
+		).Return(
+			[]byte(
+				"aaa111 1 1 1\n"+
+					"author Jane\n"+
+					"author-mail <jane@example.com>\n"+
+					"author-time 1556704800\n"+
+					"author-tz +0000\n"+
+					"committer Jane\n"+
+					"committer-mail <jane@example.com>\n"+
+					"committer-time 1556704800\n"+
+					"committer-tz +0000\n"+
+					"summary Add values\n"+
+					"filename values.yml\n"+
+					"\treplicas: 1\n"+
+					"bbb222 2 2 2\n"+
+					"author John\n"+
+					"author-mail <john@example.com>\n"+
+					"author-time 1556791200\n"+
+					"author-tz +0000\n"+
+					"committer John\n"+
+					"committer-mail <john@example.com>\n"+
+					"committer-time 1556791200\n"+
+					"committer-tz +0000\n"+
+					"summary Add image\n"+
+					"previous aaa111 values.yml\n"+
+					"filename values.yaml\n"+
+					"\timage: api\n"+
+					"bbb222 3 3\n"+
+					"\t\n",
+			),
+			[]byte{},
+			nil,
+		)
+
+		actual, actualErr := gitInstance.Blame("values.yaml", "main")
+		require.Nil(t, actualErr)
+		require.Len(t, actual, 3)
+
+		assert.Equal(t, 1, actual[0].LineNumber)
+		assert.Equal(t, "replicas: 1", actual[0].Content)
+		assert.Equal(t, "aaa111", actual[0].Commit.Hash)
+		assert.Equal(t, "Jane", actual[0].Commit.AuthorName)
+		assert.Equal(t, "jane@example.com", actual[0].Commit.AuthorEmail)
+		assert.Equal(t, time.Date(2019, 5, 1, 10, 0, 0, 0, time.UTC), actual[0].Commit.AuthorTime)
+		assert.Equal(t, "values.yml", actual[0].Commit.Filename)
+
+		assert.Equal(t, "image: api", actual[1].Content)
+		assert.Equal(t, "Add image", actual[1].Commit.Summary)
+
+		assert.Equal(t, 3, actual[2].LineNumber)
+		assert.Equal(t, "", actual[2].Content)
+		assert.True(t, actual[1].Commit == actual[2].Commit)
+	})
+}
@@ -0,0 +1,243 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// GitComponent is a part of a mono-repository that is built independently, e.g a service.
+type GitComponent struct {
+	Name string
+	// Paths are relative to the repository root and support `path.Match` patterns,
+	// as well as `**` matching any number of directories.
+	// A path matches a file when it matches the file itself or any of its parent directories,
+	// e.g "services/api" matches "services/api/main.go".
+	Paths []string
+	// DependsOn are names of components, that when changed, also affect this component.
+	DependsOn []string
+}
+
+// GitChangedFile is a file changed between two refs.
+// For renames and copies OldPath is the source path, otherwise it's empty.
+type GitChangedFile struct {
+	Status  string
+	Path    string
+	OldPath string
+}
+
+// GitChangedComponent is a component affected by changes between two refs.
+type GitChangedComponent struct {
+	Name string
+	// Files are the changed files matching the component's paths.
+	Files []*GitChangedFile
+	// ChangedDependencies are the names of the changed components this component depends on,
+	// directly or transitively.
+	ChangedDependencies []string
+}
+
+// ChangedFiles returns the files changed on `head` since it diverged from `base`, detecting renames.
+func (git *Git) ChangedFiles(base, head string) ([]*GitChangedFile, error) {
+	stdout, stderr, err := git.commandExecutor.Execute(
+		git.binPath,
+		[]string{
+			"-C", git.dir,
+			"diff",
+			"--name-status",
+			"--find-renames",
+			"-z",
+			fmt.Sprintf("%s...%s", base, head),
+		},
+		git.env,
+		"",
+	)
+	if err != nil {
+		return nil, newGitError(err, nil, stderr)
+	}
+
+	return parseGitNameStatus(stdout), nil
+}
+
+// ChangedComponents returns the components affected by changes on `head` since it diverged from `base`,
+// in the order of `components`.
+// A component is affected when any of its paths matches a changed file,
+// or when any component it depends on, directly or transitively, is affected.
+// Renamed files affect the components of both the old and the new path.
+func (git *Git) ChangedComponents(
+	base string,
+	head string,
+	components []*GitComponent,
+) ([]*GitChangedComponent, error) {
+	changedFiles, err := git.ChangedFiles(base, head)
+	if err != nil {
+		return nil, err
+	}
+
+	return detectChangedComponents(changedFiles, components)
+}
+
+func detectChangedComponents(
+	changedFiles []*GitChangedFile,
+	components []*GitComponent,
+) ([]*GitChangedComponent, error) {
+	componentsByName := make(map[string]*GitComponent)
+	for _, component := range components {
+		componentsByName[component.Name] = component
+	}
+
+	for _, component := range components {
+		for _, dependency := range component.DependsOn {
+			if _, ok := componentsByName[dependency]; !ok {
+				return nil, fmt.Errorf("component %s depends on unknown component %s", component.Name, dependency)
+			}
+		}
+	}
+
+	filesByComponent := make(map[string][]*GitChangedFile)
+	for _, component := range components {
+		for _, changedFile := range changedFiles {
+			if componentMatchesFile(component, changedFile) {
+				filesByComponent[component.Name] = append(filesByComponent[component.Name], changedFile)
+			}
+		}
+	}
+
+	changedComponents := make([]*GitChangedComponent, 0)
+
+	for _, component := range components {
+		changedDependencies := make([]string, 0)
+		visited := map[string]bool{component.Name: true}
+		pending := append([]string{}, component.DependsOn...)
+
+		for len(pending) > 0 {
+			dependency := pending[0]
+			pending = pending[1:]
+
+			if visited[dependency] {
+				continue
+			}
+			visited[dependency] = true
+
+			if len(filesByComponent[dependency]) > 0 {
+				changedDependencies = append(changedDependencies, dependency)
+			}
+
+			pending = append(pending, componentsByName[dependency].DependsOn...)
+		}
+
+		files := filesByComponent[component.Name]
+		if len(files) == 0 && len(changedDependencies) == 0 {
+			continue
+		}
+
+		if files == nil {
+			files = make([]*GitChangedFile, 0)
+		}
+
+		changedComponents = append(changedComponents, &GitChangedComponent{
+			Name:                component.Name,
+			Files:               files,
+			ChangedDependencies: changedDependencies,
+		})
+	}
+
+	return changedComponents, nil
+}
+
+func componentMatchesFile(component *GitComponent, changedFile *GitChangedFile) bool {
+	for _, pattern := range component.Paths {
+		if matchesPathOrParent(pattern, changedFile.Path) {
+			return true
+		}
+
+		if changedFile.OldPath != "" && matchesPathOrParent(pattern, changedFile.OldPath) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchesPathOrParent(pattern, filePath string) bool {
+	patternParts := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathParts := strings.Split(filePath, "/")
+
+	for i := len(pathParts); i > 0; i-- {
+		if matchPathParts(patternParts, pathParts[:i]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchPathParts(patternParts, pathParts []string) bool {
+	if len(patternParts) == 0 {
+		return len(pathParts) == 0
+	}
+
+	if patternParts[0] == "**" {
+		for i := 0; i <= len(pathParts); i++ {
+			if matchPathParts(patternParts[1:], pathParts[i:]) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if len(pathParts) == 0 {
+		return false
+	}
+
+	matched, err := path.Match(patternParts[0], pathParts[0])
+	if err != nil || !matched {
+		return false
+	}
+
+	return matchPathParts(patternParts[1:], pathParts[1:])
+}
+
+// parseGitNameStatus parses `git diff --name-status -z` output,
+// where renames and copies are followed by both the old and the new path.
+func parseGitNameStatus(output []byte) []*GitChangedFile {
+	changedFiles := make([]*GitChangedFile, 0)
+	fields := strings.Split(strings.TrimRight(string(output), "\x00"), "\x00")
+
+	for i := 0; i+1 < len(fields); {
+		status := fields[i]
+
+		if (strings.HasPrefix(status, "R") || strings.HasPrefix(status, "C")) && i+2 < len(fields) {
+			changedFiles = append(changedFiles, &GitChangedFile{
+				Status:  status[:1],
+				OldPath: fields[i+1],
+				Path:    fields[i+2],
+			})
+			i += 3
+
+			continue
+		}
+
+		changedFiles = append(changedFiles, &GitChangedFile{
+			Status: status,
+			Path:   fields[i+1],
+		})
+		i += 2
+	}
+
+	return changedFiles
+}
@@ -0,0 +1,132 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sumup-oss/go-pkgs/os/ostest"
+)
+
+func TestGit_ChangedComponents(t *testing.T) {
+	components := []*GitComponent{
+		{Name: "proto", Paths: []string{"**/*.proto"}},
+		{Name: "lib", Paths: []string{"libs/common"}},
+		{Name: "api", Paths: []string{"services/api"}, DependsOn: []string{"lib", "proto"}},
+		{Name: "web", Paths: []string{"services/web", "charts/web/*.yaml"}},
+		{Name: "worker", Paths: []string{"services/worker"}, DependsOn: []string{"api"}},
+	}
+
+	t.Run(
+		"it returns components matching changed files, including renames and transitive dependencies",
+		func(t *testing.T) {
+			t.Parallel()
+
+			osExecutor := ostest.NewFakeOsExecutor(t)
+			gitInstance := NewGit(osExecutor, "", "/tmp/repo", nil)
+
+			osExecutor.On(
+				"Execute",
+				"git",
+				[]string{"-C", "/tmp/repo", "diff", "--name-status", "--find-renames", "-z", "origin/main...HEAD"},
+				cLocaleEnvArg,
+				"",
+			).Return(
+				[]byte(
+					"M\x00libs/common/log.go\x00"+
+						"R097\x00services/web/old.go\x00services/shared/new.go\x00"+
+						"A\x00charts/web/values.yaml\x00",
+				),
+				[]byte{},
+				nil,
+			)
+
+			actual, actualErr := gitInstance.ChangedComponents("origin/main", "HEAD", components)
+			require.Nil(t, actualErr)
+
+			expected := []*GitChangedComponent{
+				{
+					Name: "lib",
+					Files: []*GitChangedFile{
+						{Status: "M", Path: "libs/common/log.go"},
+					},
+					ChangedDependencies: []string{},
+				},
+				{
+					Name:                "api",
+					Files:               []*GitChangedFile{},
+					ChangedDependencies: []string{"lib"},
+				},
+				{
+					Name: "web",
+					Files: []*GitChangedFile{
+						{Status: "R", OldPath: "services/web/old.go", Path: "services/shared/new.go"},
+						{Status: "A", Path: "charts/web/values.yaml"},
+					},
+					ChangedDependencies: []string{},
+				},
+				{
+					Name:                "worker",
+					Files:               []*GitChangedFile{},
+					ChangedDependencies: []string{"lib"},
+				},
+			}
+			assert.Equal(t, expected, actual)
+		},
+	)
+
+	t.Run("when a component depends on an unknown component, it returns error", func(t *testing.T) {
+		t.Parallel()
+
+		actual, actualErr := detectChangedComponents(
+			[]*GitChangedFile{},
+			[]*GitComponent{{Name: "api", DependsOn: []string{"missing"}}},
+		)
+		require.NotNil(t, actualErr)
+		assert.Nil(t, actual)
+		assert.Contains(t, actualErr.Error(), "unknown component missing")
+	})
+}
+
+func TestMatchesPathOrParent(t *testing.T) {
+	testCases := []struct {
+		pattern  string
+		path     string
+		expected bool
+	}{
+		{pattern: "services/api", path: "services/api/main.go", expected: true},
+		{pattern: "services/api/", path: "services/api/cmd/main.go", expected: true},
+		{pattern: "services/api", path: "services/api-gateway/main.go", expected: false},
+		{pattern: "services/*", path: "services/web/main.go", expected: true},
+		{pattern: "**/*.proto", path: "proto/v1/user.proto", expected: true},
+		{pattern: "**/*.proto", path: "user.proto", expected: true},
+		{pattern: "charts/**/values.yaml", path: "charts/web/values.yaml", expected: true},
+		{pattern: "charts/**/values.yaml", path: "charts/web/Chart.yaml", expected: false},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(
+			t,
+			testCase.expected,
+			matchesPathOrParent(testCase.pattern, testCase.path),
+			"pattern %s, path %s",
+			testCase.pattern,
+			testCase.path,
+		)
+	}
+}
@@ -0,0 +1,274 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"strings"
+)
+
+var gitCleanOutputPrefixes = []string{"Would remove ", "Removing "}
+
+type GitCleanOptions struct {
+	// DryRun only reports what would be removed.
+	DryRun bool
+	// Directories also removes untracked directories.
+	Directories bool
+	// Ignored also removes files ignored by .gitignore.
+	Ignored bool
+	// OnlyIgnored removes only files ignored by .gitignore.
+	OnlyIgnored bool
+	// NestedRepositories also removes untracked directories that are git repositories.
+	NestedRepositories bool
+	// Paths limits the cleaning to the given paths.
+	Paths []string
+}
+
+type GitStashEntry struct {
+	// Ref is the stash reference, e.g "stash@{0}".
+	Ref     string
+	Hash    string
+	Message string
+}
+
+// GitPristineReport lists what ResetToPristine discarded.
+type GitPristineReport struct {
+	// ResetFiles are the tracked files whose changes were discarded.
+	ResetFiles []string
+	// RemovedFiles are the untracked and ignored files and directories that were removed.
+	// Directories end with "/".
+	RemovedFiles []string
+}
+
+// Clean removes untracked files and returns the removed paths,
+// or with DryRun, the paths that would be removed.
+// Directories end with "/".
+func (git *Git) Clean(options *GitCleanOptions) ([]string, error) {
+	if options == nil {
+		options = &GitCleanOptions{}
+	}
+
+	args := []string{"-C", git.dir, "clean"}
+
+	if options.DryRun {
+		args = append(args, "--dry-run")
+	} else {
+		args = append(args, "--force")
+	}
+
+	if options.NestedRepositories {
+		args = append(args, "--force")
+	}
+
+	if options.Directories {
+		args = append(args, "-d")
+	}
+
+	if options.Ignored {
+		args = append(args, "-x")
+	}
+
+	if options.OnlyIgnored {
+		args = append(args, "-X")
+	}
+
+	if len(options.Paths) > 0 {
+		args = append(args, "--")
+		args = append(args, options.Paths...)
+	}
+
+	stdout, stderr, err := git.commandExecutor.Execute(git.binPath, args, git.env, "")
+	if err != nil {
+		return nil, newGitError(err, nil, stderr)
+	}
+
+	paths := make([]string, 0)
+
+	for _, line := range splitLines(stdout) {
+		for _, prefix := range gitCleanOutputPrefixes {
+			if strings.HasPrefix(line, prefix) {
+				paths = append(paths, strings.TrimPrefix(line, prefix))
+				break
+			}
+		}
+	}
+
+	return paths, nil
+}
+
+// Stash saves the local changes and reverts the working directory to HEAD.
+// It returns false when there were no local changes to save.
+func (git *Git) Stash(message string, includeUntracked bool) (bool, error) {
+	args := []string{"-C", git.dir, "stash", "push"}
+
+	if includeUntracked {
+		args = append(args, "--include-untracked")
+	}
+
+	if message != "" {
+		args = append(args, "-m", message)
+	}
+
+	// NOTE: `git stash push` succeeds without creating a stash, when there is nothing to stash,
+	// so the latest stash is compared instead of relying on the output.
+	before, err := git.latestStashHash()
+	if err != nil {
+		return false, err
+	}
+
+	stdout, stderr, err := git.commandExecutor.Execute(git.binPath, args, git.env, "")
+	if err != nil {
+		return false, newGitError(err, stdout, stderr)
+	}
+
+	after, err := git.latestStashHash()
+	if err != nil {
+		return false, err
+	}
+
+	return after != before, nil
+}
+
+// latestStashHash returns the hash of the latest stash, or empty string when there are no stashes.
+func (git *Git) latestStashHash() (string, error) {
+	stdout, stderr, err := git.commandExecutor.Execute(
+		git.binPath,
+		[]string{"-C", git.dir, "stash", "list", "--max-count=1", "--format=%H"},
+		git.env,
+		"",
+	)
+	if err != nil {
+		return "", newGitError(err, nil, stderr)
+	}
+
+	return strings.TrimSpace(string(stdout)), nil
+}
+
+// StashPop applies the latest stash and removes it.
+// When applying fails because of conflicts, the stash is kept.
+func (git *Git) StashPop() error {
+	stdout, stderr, err := git.commandExecutor.Execute(
+		git.binPath,
+		[]string{"-C", git.dir, "stash", "pop"},
+		git.env,
+		"",
+	)
+	if err != nil {
+		return newGitError(err, stdout, stderr)
+	}
+
+	return nil
+}
+
+// StashList returns the stashes, latest first.
+func (git *Git) StashList() ([]*GitStashEntry, error) {
+	stdout, stderr, err := git.commandExecutor.Execute(
+		git.binPath,
+		[]string{"-C", git.dir, "stash", "list", "--format=%gd%x00%H%x00%gs"},
+		git.env,
+		"",
+	)
+	if err != nil {
+		return nil, newGitError(err, nil, stderr)
+	}
+
+	entries := make([]*GitStashEntry, 0)
+
+	for _, line := range splitLines(stdout) {
+		parts := strings.SplitN(line, "\x00", 3)
+		if len(parts) < 3 {
+			continue
+		}
+
+		entries = append(entries, &GitStashEntry{
+			Ref:     parts[0],
+			Hash:    parts[1],
+			Message: parts[2],
+		})
+	}
+
+	return entries, nil
+}
+
+// ResetToPristine discards all local changes, untracked and ignored files,
+// leaving the working directory identical to `ref`, or HEAD when `ref` is empty.
+// Unlike CheckoutClean, untracked files are removed too.
+//
+// NOTE: Like `git reset --hard`, a `ref` other than HEAD also moves the current branch to `ref`.
+// The report only lists the local changes, not the differences between HEAD and `ref`.
+func (git *Git) ResetToPristine(ref string) (*GitPristineReport, error) {
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	stdout, stderr, err := git.commandExecutor.Execute(
+		git.binPath,
+		[]string{"-C", git.dir, "status", "--porcelain", "-z", "--untracked-files=no"},
+		git.env,
+		"",
+	)
+	if err != nil {
+		return nil, newGitError(err, nil, stderr)
+	}
+
+	resetFiles := parseGitStatusPaths(stdout)
+
+	_, stderr, err = git.commandExecutor.Execute(
+		git.binPath,
+		[]string{"-C", git.dir, "reset", "--hard", ref},
+		git.env,
+		"",
+	)
+	if err != nil {
+		return nil, newGitError(err, nil, stderr)
+	}
+
+	removedFiles, err := git.Clean(&GitCleanOptions{
+		Directories:        true,
+		Ignored:            true,
+		NestedRepositories: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &GitPristineReport{
+		ResetFiles:   resetFiles,
+		RemovedFiles: removedFiles,
+	}, nil
+}
+
+// parseGitStatusPaths returns the paths of `git status --porcelain -z` output.
+// Both the new and the original path of renamed and copied files are returned.
+func parseGitStatusPaths(output []byte) []string {
+	paths := make([]string, 0)
+	entries := strings.Split(string(output), "\x00")
+
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		// NOTE: Entries are "XY <path>", where X and Y are the index and working tree statuses.
+		if len(entry) < 4 {
+			continue
+		}
+
+		paths = append(paths, entry[3:])
+
+		if (entry[0] == 'R' || entry[0] == 'C') && i+1 < len(entries) {
+			i++
+			paths = append(paths, entries[i])
+		}
+	}
+
+	return paths
+}
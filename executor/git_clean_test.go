@@ -0,0 +1,196 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sumup-oss/go-pkgs/os/ostest"
+)
+
+func TestGit_Clean(t *testing.T) {
+	t.Run("with dry run, it returns the paths that would be removed", func(t *testing.T) {
+		t.Parallel()
+
+		osExecutor := ostest.NewFakeOsExecutor(t)
+		gitInstance := NewGit(osExecutor, "", "/tmp/repo", nil)
+
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{"-C", "/tmp/repo", "clean", "--dry-run", "-d", "-x", "--", "build"},
+			cLocaleEnvArg,
+			"",
+		).Return([]byte("Would remove build/out/\nWould remove build/app.bin\n"), []byte{}, nil)
+
+		actual, actualErr := gitInstance.Clean(&GitCleanOptions{
+			DryRun:      true,
+			Directories: true,
+			Ignored:     true,
+			Paths:       []string{"build"},
+		})
+		require.Nil(t, actualErr)
+		assert.Equal(t, []string{"build/out/", "build/app.bin"}, actual)
+	})
+
+	t.Run("with nil options, it removes only untracked files", func(t *testing.T) {
+		t.Parallel()
+
+		osExecutor := ostest.NewFakeOsExecutor(t)
+		gitInstance := NewGit(osExecutor, "", "/tmp/repo", nil)
+
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{"-C", "/tmp/repo", "clean", "--force"},
+			cLocaleEnvArg,
+			"",
+		).Return([]byte("Removing notes.txt\n"), []byte{}, nil)
+
+		actual, actualErr := gitInstance.Clean(nil)
+		require.Nil(t, actualErr)
+		assert.Equal(t, []string{"notes.txt"}, actual)
+	})
+}
+
+func TestGit_Stash(t *testing.T) {
+	t.Run("when there are no local changes, it returns false", func(t *testing.T) {
+		t.Parallel()
+
+		osExecutor := ostest.NewFakeOsExecutor(t)
+		gitInstance := NewGit(osExecutor, "", "/tmp/repo", nil)
+
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{"-C", "/tmp/repo", "stash", "list", "--max-count=1", "--format=%H"},
+			cLocaleEnvArg,
+			"",
+		).Return([]byte("aaa111\n"), []byte{}, nil).Twice()
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{"-C", "/tmp/repo", "stash", "push", "--include-untracked", "-m", "wip"},
+			cLocaleEnvArg,
+			"",
+		).Return([]byte("No local changes to save\n"), []byte{}, nil)
+
+		actual, actualErr := gitInstance.Stash("wip", true)
+		require.Nil(t, actualErr)
+		assert.False(t, actual)
+	})
+
+	t.Run("when a stash is created, it returns true", func(t *testing.T) {
+		t.Parallel()
+
+		osExecutor := ostest.NewFakeOsExecutor(t)
+		gitInstance := NewGit(osExecutor, "", "/tmp/repo", nil)
+
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{"-C", "/tmp/repo", "stash", "list", "--max-count=1", "--format=%H"},
+			cLocaleEnvArg,
+			"",
+		).Return([]byte{}, []byte{}, nil).Once()
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{"-C", "/tmp/repo", "stash", "push"},
+			cLocaleEnvArg,
+			"",
+		).Return([]byte("Saved working directory and index state WIP on main: abc123 Add\n"), []byte{}, nil)
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{"-C", "/tmp/repo", "stash", "list", "--max-count=1", "--format=%H"},
+			cLocaleEnvArg,
+			"",
+		).Return([]byte("aaa111\n"), []byte{}, nil).Once()
+
+		actual, actualErr := gitInstance.Stash("", false)
+		require.Nil(t, actualErr)
+		assert.True(t, actual)
+	})
+}
+
+func TestGit_StashList(t *testing.T) {
+	t.Run("it parses the stash entries", func(t *testing.T) {
+		t.Parallel()
+
+		osExecutor := ostest.NewFakeOsExecutor(t)
+		gitInstance := NewGit(osExecutor, "", "/tmp/repo", nil)
+
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{"-C", "/tmp/repo", "stash", "list", "--format=%gd%x00%H%x00%gs"},
+			cLocaleEnvArg,
+			"",
+		).Return(
+			[]byte("stash@{0}\x00aaa111\x00On main: wip\nstash@{1}\x00bbb222\x00WIP on main: abc123 Add\n"),
+			[]byte{},
+			nil,
+		)
+
+		actual, actualErr := gitInstance.StashList()
+		require.Nil(t, actualErr)
+
+		expected := []*GitStashEntry{
+			{Ref: "stash@{0}", Hash: "aaa111", Message: "On main: wip"},
+			{Ref: "stash@{1}", Hash: "bbb222", Message: "WIP on main: abc123 Add"},
+		}
+		assert.Equal(t, expected, actual)
+	})
+}
+
+func TestGit_ResetToPristine(t *testing.T) {
+	t.Run("it resets to the ref, removes untracked files and reports what was discarded", func(t *testing.T) {
+		t.Parallel()
+
+		osExecutor := ostest.NewFakeOsExecutor(t)
+		gitInstance := NewGit(osExecutor, "", "/tmp/repo", nil)
+
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{"-C", "/tmp/repo", "status", "--porcelain", "-z", "--untracked-files=no"},
+			cLocaleEnvArg,
+			"",
+		).Return([]byte(" M values.yaml\x00R  chart/new.yaml\x00chart/old.yaml\x00"), []byte{}, nil)
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{"-C", "/tmp/repo", "reset", "--hard", "origin/main"},
+			cLocaleEnvArg,
+			"",
+		).Return([]byte{}, []byte{}, nil)
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{"-C", "/tmp/repo", "clean", "--force", "--force", "-d", "-x"},
+			cLocaleEnvArg,
+			"",
+		).Return([]byte("Removing build/\nRemoving notes.txt\n"), []byte{}, nil)
+
+		actual, actualErr := gitInstance.ResetToPristine("origin/main")
+		require.Nil(t, actualErr)
+		assert.Equal(t, []string{"values.yaml", "chart/new.yaml", "chart/old.yaml"}, actual.ResetFiles)
+		assert.Equal(t, []string{"build/", "notes.txt"}, actual.RemovedFiles)
+	})
+}
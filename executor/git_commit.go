@@ -0,0 +1,150 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+type GitSigningFormat string
+
+const (
+	GitSigningFormatOpenPGP GitSigningFormat = "openpgp"
+	GitSigningFormatSSH     GitSigningFormat = "ssh"
+	GitSigningFormatX509    GitSigningFormat = "x509"
+)
+
+// GitTrailer is a "Key: Value" line at the end of the commit message, e.g "Signed-off-by: Name <email>".
+type GitTrailer struct {
+	Key   string
+	Value string
+}
+
+type GitCommitOptions struct {
+	// Message can be empty only when amending, in which case the amended commit's message is kept.
+	Message        string
+	AuthorName     string
+	AuthorEmail    string
+	CommitterName  string
+	CommitterEmail string
+	// Date is used as both author and committer date, when not zero.
+	Date       time.Time
+	Amend      bool
+	AllowEmpty bool
+	// Sign signs the commit with the configured, or the specified SigningKey.
+	Sign bool
+	// SigningKey is a GPG key ID, or for the SSH format, a path to a public key or a literal public key.
+	SigningKey    string
+	SigningFormat GitSigningFormat
+	Trailers      []*GitTrailer
+}
+
+// CommitWithOptions records the staged changes and returns the hash of the created commit.
+// The identity and date are passed as `GIT_AUTHOR_*` and `GIT_COMMITTER_*` environment variables,
+// overriding the ones from the environment and git config.
+// When amending, the author is only replaced when AuthorName, AuthorEmail or Date is set,
+// otherwise the amended commit's author is kept.
+func (git *Git) CommitWithOptions(options *GitCommitOptions) (string, error) {
+	if options == nil {
+		options = &GitCommitOptions{}
+	}
+
+	if options.Message == "" && !options.Amend {
+		return "", errors.New("git commit message is required, unless amending")
+	}
+
+	args := []string{"-C", git.dir}
+
+	if options.SigningFormat != "" {
+		args = append(args, "-c", fmt.Sprintf("gpg.format=%s", options.SigningFormat))
+	}
+
+	if options.SigningKey != "" {
+		args = append(args, "-c", fmt.Sprintf("user.signingkey=%s", options.SigningKey))
+	}
+
+	args = append(args, "commit")
+
+	if options.Message != "" {
+		args = append(args, "-m", options.Message)
+	} else if options.Amend {
+		args = append(args, "--no-edit")
+	}
+
+	if options.Amend {
+		args = append(args, "--amend")
+
+		// NOTE: `--amend` keeps the original author, ignoring `GIT_AUTHOR_*`.
+		if options.AuthorName != "" || options.AuthorEmail != "" || !options.Date.IsZero() {
+			args = append(args, "--reset-author")
+		}
+	}
+
+	if options.AllowEmpty {
+		args = append(args, "--allow-empty")
+	}
+
+	if options.Sign {
+		args = append(args, "--gpg-sign")
+	}
+
+	for _, trailer := range options.Trailers {
+		args = append(args, "--trailer", fmt.Sprintf("%s: %s", trailer.Key, trailer.Value))
+	}
+
+	env := git.env
+	identityEnv := gitCommitIdentityEnv(options)
+	if len(identityEnv) > 0 {
+		env = extendEnv(git.env, identityEnv)
+	}
+
+	stdout, stderr, err := git.commandExecutor.Execute(git.binPath, args, env, "")
+	if err != nil {
+		return "", newGitError(err, stdout, stderr)
+	}
+
+	return git.GetCurrentHash()
+}
+
+func gitCommitIdentityEnv(options *GitCommitOptions) []string {
+	env := make([]string, 0)
+
+	identity := []struct {
+		key   string
+		value string
+	}{
+		{key: "GIT_AUTHOR_NAME", value: options.AuthorName},
+		{key: "GIT_AUTHOR_EMAIL", value: options.AuthorEmail},
+		{key: "GIT_COMMITTER_NAME", value: options.CommitterName},
+		{key: "GIT_COMMITTER_EMAIL", value: options.CommitterEmail},
+	}
+
+	for _, variable := range identity {
+		if variable.value == "" {
+			continue
+		}
+
+		env = append(env, fmt.Sprintf("%s=%s", variable.key, variable.value))
+	}
+
+	if !options.Date.IsZero() {
+		date := options.Date.Format(time.RFC3339)
+		env = append(env, fmt.Sprintf("GIT_AUTHOR_DATE=%s", date), fmt.Sprintf("GIT_COMMITTER_DATE=%s", date))
+	}
+
+	return env
+}
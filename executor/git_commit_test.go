@@ -0,0 +1,147 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sumup-oss/go-pkgs/os/ostest"
+)
+
+func TestGit_CommitWithOptions(t *testing.T) {
+	t.Run("it commits with identity, signing and trailers and returns the commit hash", func(t *testing.T) {
+		t.Parallel()
+
+		osExecutor := ostest.NewFakeOsExecutor(t)
+		envArg := []string{"HOME=/home/user"}
+		gitInstance := NewGit(osExecutor, "", "/tmp/repo", envArg)
+
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{
+				"-C", "/tmp/repo",
+				"-c", "gpg.format=ssh",
+				"-c", "user.signingkey=/home/user/.ssh/id_ed25519.pub",
+				"commit",
+				"-m", "Update chart",
+				"--allow-empty",
+				"--gpg-sign",
+				"--trailer", "Signed-off-by: Bot <bot@example.com>",
+			},
+			[]string{
+				"HOME=/home/user",
+				"LC_ALL=C",
+				"GIT_AUTHOR_NAME=Bot",
+				"GIT_AUTHOR_EMAIL=bot@example.com",
+				"GIT_COMMITTER_NAME=Bot",
+				"GIT_COMMITTER_EMAIL=bot@example.com",
+				"GIT_AUTHOR_DATE=2019-05-01T10:00:00Z",
+				"GIT_COMMITTER_DATE=2019-05-01T10:00:00Z",
+			},
+			"",
+		).Return([]byte{}, []byte{}, nil)
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{"-C", "/tmp/repo", "rev-parse", "HEAD"},
+			[]string{"HOME=/home/user", "LC_ALL=C"},
+			"",
+		).Return([]byte("abc123\n"), []byte{}, nil)
+
+		actual, actualErr := gitInstance.CommitWithOptions(&GitCommitOptions{
+			Message:        "Update chart",
+			AuthorName:     "Bot",
+			AuthorEmail:    "bot@example.com",
+			CommitterName:  "Bot",
+			CommitterEmail: "bot@example.com",
+			Date:           time.Date(2019, 5, 1, 10, 0, 0, 0, time.UTC),
+			AllowEmpty:     true,
+			Sign:           true,
+			SigningKey:     "/home/user/.ssh/id_ed25519.pub",
+			SigningFormat:  GitSigningFormatSSH,
+			Trailers: []*GitTrailer{
+				{Key: "Signed-off-by", Value: "Bot <bot@example.com>"},
+			},
+		})
+		require.Nil(t, actualErr)
+		assert.Equal(t, "abc123", actual)
+	})
+
+	t.Run("when amending without message, it keeps the amended commit's message", func(t *testing.T) {
+		t.Parallel()
+
+		osExecutor := ostest.NewFakeOsExecutor(t)
+		gitInstance := NewGit(osExecutor, "", "/tmp/repo", nil)
+
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{"-C", "/tmp/repo", "commit", "--no-edit", "--amend"},
+			cLocaleEnvArg,
+			"",
+		).Return([]byte{}, []byte("fatal: You have nothing to amend."), errors.New("exit status 128"))
+
+		actual, actualErr := gitInstance.CommitWithOptions(&GitCommitOptions{Amend: true})
+		require.NotNil(t, actualErr)
+		assert.Equal(t, "", actual)
+		assert.Contains(t, actualErr.Error(), "nothing to amend")
+	})
+	t.Run("when amending with an author, it resets the amended commit's author", func(t *testing.T) {
+		t.Parallel()
+
+		osExecutor := ostest.NewFakeOsExecutor(t)
+		gitInstance := NewGit(osExecutor, "", "/tmp/repo", []string{"HOME=/home/user"})
+
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{"-C", "/tmp/repo", "commit", "--no-edit", "--amend", "--reset-author"},
+			[]string{"HOME=/home/user", "LC_ALL=C", "GIT_AUTHOR_NAME=CI", "GIT_AUTHOR_EMAIL=ci@example.com"},
+			"",
+		).Return([]byte{}, []byte{}, nil)
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{"-C", "/tmp/repo", "rev-parse", "HEAD"},
+			[]string{"HOME=/home/user", "LC_ALL=C"},
+			"",
+		).Return([]byte("4f1c0e2d9a7b\n"), []byte{}, nil)
+
+		actual, actualErr := gitInstance.CommitWithOptions(
+			&GitCommitOptions{Amend: true, AuthorName: "CI", AuthorEmail: "ci@example.com"},
+		)
+		require.Nil(t, actualErr)
+		assert.Equal(t, "4f1c0e2d9a7b", actual)
+	})
+
+	t.Run("when the message is empty without amending, it returns error without committing", func(t *testing.T) {
+		t.Parallel()
+
+		osExecutor := ostest.NewFakeOsExecutor(t)
+		gitInstance := NewGit(osExecutor, "", "/tmp/repo", nil)
+
+		actual, actualErr := gitInstance.CommitWithOptions(nil)
+		require.NotNil(t, actualErr)
+		assert.Equal(t, "", actual)
+		assert.Contains(t, actualErr.Error(), "message is required")
+		osExecutor.AssertNotCalled(t, "Execute")
+	})
+}
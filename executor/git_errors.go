@@ -0,0 +1,218 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/palantir/stacktrace"
+
+	"github.com/sumup-oss/go-pkgs/task"
+)
+
+// Failure kinds of GitError, to be checked with `errors.Is`.
+var (
+	ErrAuthFailed        = errors.New("git authentication failed")
+	ErrRemoteNotFound    = errors.New("git remote repository not found")
+	ErrRemoteUnreachable = errors.New("git remote unreachable")
+	ErrNonFastForward    = errors.New("git update is not a fast-forward")
+	ErrMergeConflict     = errors.New("git merge conflict")
+	ErrNothingToCommit   = errors.New("git nothing to commit")
+	ErrRefNotFound       = errors.New("git ref not found")
+	ErrLockHeld          = errors.New("git lock held by another process")
+)
+
+var _ task.RetryableError = (*GitError)(nil)
+
+// NOTE: The patterns are matched in order, case-insensitively, against stderr and stdout.
+// More specific failures come first, e.g authentication failures also report
+// that the remote repository could not be read.
+// When `exitCodes` is set and git's exit code is known, it must be one of them,
+// e.g git dies with 128 on fatal errors, but exits with 1 when there is nothing to commit.
+var gitErrorPatterns = []struct {
+	kind      error
+	exitCodes []int
+	patterns  []string
+}{
+	{
+		kind:      ErrLockHeld,
+		exitCodes: []int{128},
+		patterns: []string{
+			// NOTE: Only "File exists" means another process holds the lock,
+			// e.g "Unable to create '...lock': Permission denied" is permanent.
+			".lock': file exists",
+			"another git process seems to be running",
+		},
+	},
+	{
+		kind: ErrAuthFailed,
+		patterns: []string{
+			"authentication failed",
+			"permission denied (publickey",
+			"could not read username",
+			"could not read password",
+			"terminal prompts disabled",
+			"http basic: access denied",
+			"the requested url returned error: 401",
+			"the requested url returned error: 403",
+		},
+	},
+	{
+		kind: ErrRemoteNotFound,
+		patterns: []string{
+			"repository not found",
+			"does not appear to be a git repository",
+			"the requested url returned error: 404",
+			"no such remote",
+		},
+	},
+	{
+		kind: ErrRemoteUnreachable,
+		patterns: []string{
+			"could not resolve host",
+			"connection timed out",
+			"operation timed out",
+			"connection refused",
+			"connection reset",
+			"failed to connect",
+			"the remote end hung up unexpectedly",
+			"early eof",
+		},
+	},
+	{
+		kind: ErrNonFastForward,
+		patterns: []string{
+			"(non-fast-forward)",
+			"(fetch first)",
+			"(stale info)",
+			"updates were rejected",
+			"not possible to fast-forward",
+		},
+	},
+	{
+		kind: ErrMergeConflict,
+		patterns: []string{
+			"conflict (",
+			"automatic merge failed",
+			"could not apply",
+			"you need to resolve your current index first",
+			"unmerged files",
+		},
+	},
+	{
+		kind:      ErrNothingToCommit,
+		exitCodes: []int{1},
+		patterns: []string{
+			"nothing to commit",
+			"nothing added to commit",
+			"no changes added to commit",
+		},
+	},
+	{
+		kind: ErrRefNotFound,
+		patterns: []string{
+			"unknown revision",
+			"bad revision",
+			"needed a single revision",
+			"not a valid object name",
+			"invalid reference",
+			"couldn't find remote ref",
+			"did not match any file(s) known to git",
+		},
+	},
+}
+
+// GitError is returned by Git methods when executing git fails.
+// Use `errors.Is` with the Err* variables to check the kind of failure.
+type GitError struct {
+	// Kind is one of the Err* variables, or nil when the failure was not recognized.
+	Kind error
+	// ExitCode is the exit code of git, or -1 when git did not exit normally or was not executed.
+	ExitCode int
+	Stderr   string
+	err      error
+}
+
+func newGitError(err error, stdout, stderr []byte) error {
+	exitCode := -1
+	if exitErr, ok := stacktrace.RootCause(err).(*exec.ExitError); ok {
+		exitCode = exitErr.ExitCode()
+	}
+
+	return &GitError{
+		Kind:     gitErrorKind(string(stderr)+"\n"+string(stdout), exitCode),
+		ExitCode: exitCode,
+		Stderr:   string(stderr),
+		err:      err,
+	}
+}
+
+// gitErrorKind returns the kind of the first of gitErrorPatterns found in `output`, or nil.
+// An `exitCode` of -1 means unknown.
+func gitErrorKind(output string, exitCode int) error {
+	output = strings.ToLower(output)
+
+	for _, errorPattern := range gitErrorPatterns {
+		if exitCode != -1 && len(errorPattern.exitCodes) > 0 && !containsInt(errorPattern.exitCodes, exitCode) {
+			continue
+		}
+
+		for _, pattern := range errorPattern.patterns {
+			if strings.Contains(output, pattern) {
+				return errorPattern.kind
+			}
+		}
+	}
+
+	return nil
+}
+
+func containsInt(values []int, value int) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Error returns the error message.
+func (err *GitError) Error() string {
+	if err.Stderr == "" {
+		return err.err.Error()
+	}
+
+	return fmt.Sprintf("%s. Stderr: %s", err.err, err.Stderr)
+}
+
+// Unwrap returns the error of executing git.
+func (err *GitError) Unwrap() error {
+	return err.err
+}
+
+// Is reports whether `target` is the kind of the failure.
+func (err *GitError) Is(target error) bool {
+	return err.Kind != nil && err.Kind == target
+}
+
+// IsRetryable reports whether the failure is transient,
+// i.e a lock held by another git process or a network failure.
+func (err *GitError) IsRetryable() bool {
+	return err.Kind == ErrLockHeld || err.Kind == ErrRemoteUnreachable
+}
@@ -0,0 +1,158 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"errors"
+	"os/exec"
+	"testing"
+
+	"github.com/palantir/stacktrace"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sumup-oss/go-pkgs/os/ostest"
+	"github.com/sumup-oss/go-pkgs/task"
+)
+
+// cLocaleEnvArg matches the env of Git, which ends with the C locale.
+var cLocaleEnvArg = mock.MatchedBy(func(env []string) bool {
+	return len(env) > 0 && env[len(env)-1] == "LC_ALL=C"
+})
+
+func TestNewGitError(t *testing.T) {
+	testCases := []struct {
+		stdout    string
+		stderr    string
+		expected  error
+		retryable bool
+	}{
+		{
+			stderr:   "git@github.com: Permission denied (publickey).\nfatal: Could not read from remote repository.",
+			expected: ErrAuthFailed,
+		},
+		{
+			stderr:   "remote: Repository not found.\nfatal: repository 'https://example.com/x.git/' not found",
+			expected: ErrRemoteNotFound,
+		},
+		{
+			stderr:    "fatal: unable to access 'https://example.com/x.git/': Could not resolve host: example.com",
+			expected:  ErrRemoteUnreachable,
+			retryable: true,
+		},
+		{
+			stderr:   " ! [rejected]        main -> main (fetch first)\nerror: failed to push some refs",
+			expected: ErrNonFastForward,
+		},
+		{
+			stdout:   "CONFLICT (content): Merge conflict in values.yaml\nAutomatic merge failed",
+			expected: ErrMergeConflict,
+		},
+		{
+			stdout:   "On branch main\nnothing to commit, working tree clean",
+			expected: ErrNothingToCommit,
+		},
+		{
+			stderr:   "fatal: ambiguous argument 'v9': unknown revision or path not in the working tree.",
+			expected: ErrRefNotFound,
+		},
+		{
+			stderr:    "fatal: Unable to create '/tmp/repo/.git/index.lock': File exists.",
+			expected:  ErrLockHeld,
+			retryable: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		actual := newGitError(errors.New("exit status 1"), []byte(testCase.stdout), []byte(testCase.stderr))
+
+		assert.True(t, errors.Is(actual, testCase.expected), "expected %s, got %v", testCase.expected, actual)
+		assert.Equal(t, testCase.retryable, task.IsRetryableError(actual), "%s", testCase.expected)
+	}
+
+	t.Run("when the failure is not recognized, it has no kind and is not retryable", func(t *testing.T) {
+		t.Parallel()
+
+		actual := newGitError(errors.New("exit status 1"), nil, []byte("fatal: something else"))
+
+		var gitErr *GitError
+		require.True(t, errors.As(actual, &gitErr))
+		assert.Nil(t, gitErr.Kind)
+		assert.False(t, errors.Is(actual, ErrAuthFailed))
+		assert.False(t, task.IsRetryableError(actual))
+		assert.Equal(t, "exit status 1. Stderr: fatal: something else", actual.Error())
+	})
+
+	t.Run("when the lock cannot be created for other reasons, it is not retryable", func(t *testing.T) {
+		t.Parallel()
+
+		actual := newGitError(
+			errors.New("exit status 128"),
+			nil,
+			[]byte("fatal: Unable to create '/tmp/repo/.git/index.lock': Permission denied"),
+		)
+
+		assert.False(t, errors.Is(actual, ErrLockHeld))
+		assert.False(t, task.IsRetryableError(actual))
+	})
+
+	t.Run("when the exit code does not match the kind, it has no kind", func(t *testing.T) {
+		t.Parallel()
+
+		err := exec.Command("sh", "-c", "exit 128").Run()
+		require.NotNil(t, err)
+
+		actual := newGitError(err, []byte("nothing to commit, working tree clean"), []byte("fatal: bad config line 1"))
+
+		var gitErr *GitError
+		require.True(t, errors.As(actual, &gitErr))
+		assert.Nil(t, gitErr.Kind)
+	})
+
+	t.Run("it exposes the exit code of git through the executor's error", func(t *testing.T) {
+		t.Parallel()
+
+		err := exec.Command("sh", "-c", "exit 128").Run()
+		require.NotNil(t, err)
+
+		actual := newGitError(stacktrace.Propagate(err, "executing command failed"), nil, nil)
+
+		var gitErr *GitError
+		require.True(t, errors.As(actual, &gitErr))
+		assert.Equal(t, 128, gitErr.ExitCode)
+	})
+}
+
+func TestGit_Commit_NothingToCommit(t *testing.T) {
+	t.Run("when there is nothing to commit, it returns ErrNothingToCommit", func(t *testing.T) {
+		t.Parallel()
+
+		osExecutor := ostest.NewFakeOsExecutor(t)
+		gitInstance := NewGit(osExecutor, "", "/tmp/repo", nil)
+
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{"-C", "/tmp/repo", "commit", "-m", "Update"},
+			cLocaleEnvArg,
+			"",
+		).Return([]byte("nothing to commit, working tree clean\n"), []byte{}, errors.New("exit status 1"))
+
+		actualErr := gitInstance.Commit("Update")
+		require.NotNil(t, actualErr)
+		assert.True(t, errors.Is(actualErr, ErrNothingToCommit))
+	})
+}
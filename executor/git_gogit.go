@@ -0,0 +1,845 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	gogithttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gogitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const (
+	defaultGoGitRemote   = "origin"
+	goGitAnonymousRemote = "anonymous"
+)
+
+// GoGit implements GitRepository with the pure-Go go-git library,
+// so that it works on images without the `git` binary.
+// Pushing to and fetching from local paths still executes `git-upload-pack` and `git-receive-pack`.
+type GoGit struct {
+	url  string
+	dir  string
+	env  []string
+	auth GitAuth
+}
+
+// NewGoGit creates a GoGit instance for the repository in `dir`, cloned from `url`.
+// Only the `GIT_AUTHOR_*` and `GIT_COMMITTER_*` variables of `env` are used, for commits.
+// `auth` is optional.
+func NewGoGit(url, dir string, env []string, auth GitAuth) *GoGit {
+	return &GoGit{
+		url:  url,
+		dir:  dir,
+		env:  env,
+		auth: auth,
+	}
+}
+
+func (goGit *GoGit) GetURL() string {
+	return goGit.url
+}
+
+func (goGit *GoGit) Clone() error {
+	authMethod, err := goGit.authMethod(goGit.url)
+	if err != nil {
+		return err
+	}
+
+	_, err = gogit.PlainClone(
+		goGit.dir,
+		false,
+		&gogit.CloneOptions{
+			URL:  goGit.url,
+			Auth: authMethod,
+		},
+	)
+	if err != nil {
+		return newGoGitError(err)
+	}
+
+	return nil
+}
+
+// Fetch fetches from `origin` and prunes the remote-tracking refs that no longer exist on the remote.
+func (goGit *GoGit) Fetch() error {
+	repository, err := goGit.open()
+	if err != nil {
+		return err
+	}
+
+	remote, err := repository.Remote(defaultGoGitRemote)
+	if err != nil {
+		return newGoGitError(err)
+	}
+
+	authMethod, err := goGit.authMethod(remote.Config().URLs[0])
+	if err != nil {
+		return err
+	}
+
+	err = repository.Fetch(
+		&gogit.FetchOptions{
+			RemoteName: defaultGoGitRemote,
+			Auth:       authMethod,
+			Prune:      true,
+		},
+	)
+	if err != nil && err != gogit.NoErrAlreadyUpToDate {
+		return newGoGitError(err)
+	}
+
+	return nil
+}
+
+func (goGit *GoGit) HasDiff() (bool, error) {
+	worktree, err := goGit.worktree()
+	if err != nil {
+		return false, err
+	}
+
+	status, err := worktree.Status()
+	if err != nil {
+		return false, newGoGitError(err)
+	}
+
+	return !status.IsClean(), nil
+}
+
+// Add stages `file`, which can also be a directory. A relative `file` is relative to the repository directory.
+func (goGit *GoGit) Add(file string) error {
+	worktree, err := goGit.worktree()
+	if err != nil {
+		return err
+	}
+
+	relativePath, err := goGit.relativePath(file)
+	if err != nil {
+		return err
+	}
+
+	_, err = worktree.Add(relativePath)
+	if err != nil {
+		return newGoGitError(err)
+	}
+
+	return nil
+}
+
+// Commit commits the staged changes.
+// The author and committer are read from the `GIT_AUTHOR_*` and `GIT_COMMITTER_*` variables of the env,
+// falling back to `user.name` and `user.email` of the git config.
+func (goGit *GoGit) Commit(message string) error {
+	worktree, err := goGit.worktree()
+	if err != nil {
+		return err
+	}
+
+	// NOTE: go-git only refuses to commit an empty index, but `git commit` also refuses when nothing is staged.
+	status, err := worktree.Status()
+	if err != nil {
+		return newGoGitError(err)
+	}
+
+	if !goGitHasStagedChanges(status) {
+		return newGoGitError(gogit.ErrEmptyCommit)
+	}
+
+	author, err := goGit.signatureFromEnv("AUTHOR")
+	if err != nil {
+		return err
+	}
+
+	committer, err := goGit.signatureFromEnv("COMMITTER")
+	if err != nil {
+		return err
+	}
+
+	_, err = worktree.Commit(
+		message,
+		&gogit.CommitOptions{
+			Author:    author,
+			Committer: committer,
+		},
+	)
+	if err != nil {
+		return newGoGitError(err)
+	}
+
+	return nil
+}
+
+// Push pushes `refspecs` to `remote`, which is either the name of a remote or a URL.
+// Without `refspecs`, the current branch is pushed to the branch with the same name.
+//
+// go-git does not report the outcome of every ref when the push fails,
+// so then all refs that were not up-to-date are reported as rejected.
+// Only a single lease is supported.
+func (goGit *GoGit) Push(remote string, refspecs []string, options *GitPushOptions) ([]*GitPushRefResult, error) {
+	if options == nil {
+		options = &GitPushOptions{}
+	}
+
+	if len(options.Leases) > 1 {
+		return nil, errors.New("go-git supports a single lease per push")
+	}
+
+	repository, err := goGit.open()
+	if err != nil {
+		return nil, err
+	}
+
+	pushOptions := &gogit.PushOptions{
+		RemoteName: remote,
+		Force:      options.Force,
+		Atomic:     options.Atomic,
+	}
+
+	remoteURL := remote
+	isNamedRemote := true
+
+	gitRemote, err := repository.Remote(remote)
+	if err == nil {
+		remoteURL = gitRemote.Config().URLs[0]
+	} else {
+		// NOTE: A URL is pushed to through an in-memory remote, so it works without an "origin" remote.
+		isNamedRemote = false
+		pushOptions.RemoteName = goGitAnonymousRemote
+		gitRemote = gogit.NewRemote(
+			repository.Storer,
+			&config.RemoteConfig{Name: goGitAnonymousRemote, URLs: []string{remote}},
+		)
+	}
+
+	pushOptions.Auth, err = goGit.authMethod(remoteURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.ForceWithLease {
+		pushOptions.ForceWithLease = &gogit.ForceWithLease{}
+	}
+
+	for _, lease := range options.Leases {
+		pushOptions.ForceWithLease = &gogit.ForceWithLease{
+			RefName: goGitExpandRefName(repository, lease.Ref),
+			Hash:    plumbing.NewHash(lease.Expected),
+		}
+	}
+
+	if len(options.PushOptions) > 0 {
+		pushOptions.Options = make(map[string]string)
+
+		for _, pushOption := range options.PushOptions {
+			parts := strings.SplitN(pushOption, "=", 2)
+			if len(parts) < 2 {
+				parts = append(parts, "")
+			}
+
+			pushOptions.Options[parts[0]] = parts[1]
+		}
+	}
+
+	if len(refspecs) < 1 {
+		head, err := repository.Head()
+		if err != nil {
+			return nil, newGoGitError(err)
+		}
+
+		refspecs = []string{head.Name().String()}
+	}
+
+	forced := make(map[string]bool)
+
+	for _, refspec := range refspecs {
+		refSpec := goGitRefSpec(repository, refspec)
+		pushOptions.RefSpecs = append(pushOptions.RefSpecs, refSpec)
+		forced[refSpec.Dst("").String()] = refSpec.IsForceUpdate() || options.Force || pushOptions.ForceWithLease != nil
+	}
+
+	results, expectedHashes, err := goGitPushResults(repository, gitRemote, pushOptions, forced)
+	if err != nil {
+		return nil, err
+	}
+
+	err = gitRemote.Push(pushOptions)
+	if err != nil && err != gogit.NoErrAlreadyUpToDate {
+		goGitRejectPushResults(gitRemote, pushOptions, results, expectedHashes, err)
+
+		return results, newGoGitError(err)
+	}
+
+	if options.SetUpstream && isNamedRemote {
+		err = goGitSetUpstream(repository, remote, results)
+		if err != nil {
+			return results, err
+		}
+	}
+
+	return results, nil
+}
+
+// ListTags returns the tags sorted by tagger date, with lightweight tags first.
+func (goGit *GoGit) ListTags() ([]string, error) {
+	repository, err := goGit.open()
+	if err != nil {
+		return nil, err
+	}
+
+	tagRefs, err := repository.Tags()
+	if err != nil {
+		return nil, newGoGitError(err)
+	}
+
+	type tag struct {
+		name string
+		date time.Time
+	}
+
+	tags := make([]*tag, 0)
+
+	err = tagRefs.ForEach(
+		func(ref *plumbing.Reference) error {
+			tagEntry := &tag{name: ref.Name().Short()}
+
+			tagObject, err := repository.TagObject(ref.Hash())
+			if err == nil {
+				tagEntry.date = tagObject.Tagger.When
+			}
+
+			tags = append(tags, tagEntry)
+
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, newGoGitError(err)
+	}
+
+	sort.Slice(tags, func(i, j int) bool { return tags[i].name < tags[j].name })
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].date.Before(tags[j].date) })
+
+	tagNames := make([]string, len(tags))
+	for i, tagEntry := range tags {
+		tagNames[i] = tagEntry.name
+	}
+
+	return tagNames, nil
+}
+
+func (goGit *GoGit) GetCurrentHash() (string, error) {
+	repository, err := goGit.open()
+	if err != nil {
+		return "", err
+	}
+
+	head, err := repository.Head()
+	if err != nil {
+		return "", newGoGitError(err)
+	}
+
+	return head.Hash().String(), nil
+}
+
+// GetCurrentHashForPath returns the hash of the last commit that changed `path`,
+// or an empty string when no commit changed it.
+// `path` can be an absolute or relative path.
+func (goGit *GoGit) GetCurrentHashForPath(path string) (string, error) {
+	repository, err := goGit.open()
+	if err != nil {
+		return "", err
+	}
+
+	relativePath, err := goGit.relativePath(path)
+	if err != nil {
+		return "", err
+	}
+
+	logOptions := &gogit.LogOptions{}
+
+	if relativePath != "." {
+		logOptions.PathFilter = func(changedPath string) bool {
+			return changedPath == relativePath || strings.HasPrefix(changedPath, relativePath+"/")
+		}
+	}
+
+	commits, err := repository.Log(logOptions)
+	if err != nil {
+		return "", newGoGitError(err)
+	}
+	defer commits.Close()
+
+	commit, err := commits.Next()
+	if err == io.EOF {
+		return "", nil
+	}
+
+	if err != nil {
+		return "", newGoGitError(err)
+	}
+
+	return commit.Hash.String(), nil
+}
+
+func (goGit *GoGit) open() (*gogit.Repository, error) {
+	repository, err := gogit.PlainOpen(goGit.dir)
+	if err != nil {
+		return nil, newGoGitError(err)
+	}
+
+	return repository, nil
+}
+
+func (goGit *GoGit) worktree() (*gogit.Worktree, error) {
+	repository, err := goGit.open()
+	if err != nil {
+		return nil, err
+	}
+
+	worktree, err := repository.Worktree()
+	if err != nil {
+		return nil, newGoGitError(err)
+	}
+
+	return worktree, nil
+}
+
+// relativePath returns `path` relative to the repository directory, using `/` as separator.
+func (goGit *GoGit) relativePath(path string) (string, error) {
+	if path == "" {
+		return ".", nil
+	}
+
+	if !filepath.IsAbs(path) {
+		return filepath.ToSlash(filepath.Clean(path)), nil
+	}
+
+	dir, err := filepath.Abs(goGit.dir)
+	if err != nil {
+		return "", err
+	}
+
+	relativePath, err := filepath.Rel(dir, path)
+	if err != nil {
+		return "", err
+	}
+
+	if strings.HasPrefix(relativePath, "..") {
+		return "", fmt.Errorf("path %s is outside of the repository %s", path, goGit.dir)
+	}
+
+	return filepath.ToSlash(relativePath), nil
+}
+
+// signatureFromEnv returns the signature of `GIT_<role>_NAME`, `GIT_<role>_EMAIL` and `GIT_<role>_DATE`,
+// or nil when the name or email are missing.
+func (goGit *GoGit) signatureFromEnv(role string) (*object.Signature, error) {
+	name := goGit.lookupEnv(fmt.Sprintf("GIT_%s_NAME", role))
+	email := goGit.lookupEnv(fmt.Sprintf("GIT_%s_EMAIL", role))
+
+	if name == "" || email == "" {
+		return nil, nil
+	}
+
+	signature := &object.Signature{
+		Name:  name,
+		Email: email,
+		When:  time.Now(),
+	}
+
+	date := goGit.lookupEnv(fmt.Sprintf("GIT_%s_DATE", role))
+	if date != "" {
+		when, err := time.Parse(time.RFC3339, date)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GIT_%s_DATE %s, expected RFC 3339: %s", role, date, err)
+		}
+
+		signature.When = when
+	}
+
+	return signature, nil
+}
+
+func (goGit *GoGit) lookupEnv(key string) string {
+	value := ""
+
+	for _, variable := range goGit.env {
+		if envKey(variable) == key {
+			value = strings.TrimPrefix(variable, key+"=")
+		}
+	}
+
+	return value
+}
+
+// authMethod converts the GitAuth to the go-git auth method of the transport of `url`.
+func (goGit *GoGit) authMethod(url string) (transport.AuthMethod, error) {
+	if goGit.auth == nil {
+		return nil, nil
+	}
+
+	switch auth := goGit.auth.(type) {
+	case *GitTokenAuth:
+		username := auth.Username
+		if username == "" {
+			username = defaultGitTokenUsername
+		}
+
+		return &gogithttp.BasicAuth{Username: username, Password: auth.Token}, nil
+	case *GitBasicAuth:
+		return &gogithttp.BasicAuth{Username: auth.Username, Password: auth.Password}, nil
+	case *GitSSHAuth:
+		return goGitSSHAuth(auth, url)
+	default:
+		return nil, fmt.Errorf("git auth %T is not supported by go-git", goGit.auth)
+	}
+}
+
+func goGitSSHAuth(auth *GitSSHAuth, url string) (transport.AuthMethod, error) {
+	user := "git"
+
+	endpoint, err := transport.NewEndpoint(url)
+	if err == nil && endpoint.User != "" {
+		user = endpoint.User
+	}
+
+	hostKeyCallback, err := goGitHostKeyCallback(auth)
+	if err != nil {
+		return nil, err
+	}
+
+	// NOTE: Like the CLI backend, the SSH agent is used when there's no private key.
+	if auth.PrivateKeyPath == "" {
+		agentAuth, err := gogitssh.NewSSHAgentAuth(user)
+		if err != nil {
+			return nil, err
+		}
+
+		agentAuth.HostKeyCallback = hostKeyCallback
+
+		return agentAuth, nil
+	}
+
+	publicKeys, err := gogitssh.NewPublicKeysFromFile(user, auth.PrivateKeyPath, "")
+	if err != nil {
+		return nil, err
+	}
+
+	publicKeys.HostKeyCallback = hostKeyCallback
+
+	return publicKeys, nil
+}
+
+// goGitHostKeyCallback returns the host key verification of `auth`, like the CLI backend's `StrictHostKeyChecking`.
+func goGitHostKeyCallback(auth *GitSSHAuth) (ssh.HostKeyCallback, error) {
+	if auth.InsecureIgnoreHostKey {
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+
+	knownHostsPaths := make([]string, 0)
+	if auth.KnownHostsPath != "" {
+		knownHostsPaths = append(knownHostsPaths, auth.KnownHostsPath)
+	}
+
+	hostKeyCallback, err := gogitssh.NewKnownHostsCallback(knownHostsPaths...)
+	if err != nil {
+		return nil, err
+	}
+
+	if auth.AcceptNewHostKeys {
+		// NOTE: Unlike OpenSSH, new host keys are trusted but not recorded in known_hosts.
+		strictHostKeyCallback := hostKeyCallback
+		hostKeyCallback = func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			err := strictHostKeyCallback(hostname, remote, key)
+
+			var keyErr *knownhosts.KeyError
+			if errors.As(err, &keyErr) && len(keyErr.Want) == 0 {
+				return nil
+			}
+
+			return err
+		}
+	}
+
+	return hostKeyCallback, nil
+}
+
+func goGitHasStagedChanges(status gogit.Status) bool {
+	for _, fileStatus := range status {
+		if fileStatus.Staging != gogit.Unmodified && fileStatus.Staging != gogit.Untracked {
+			return true
+		}
+	}
+
+	return false
+}
+
+// goGitRefSpec converts a `git push` refspec, where refs can be abbreviated, to a go-git refspec.
+func goGitRefSpec(repository *gogit.Repository, refspec string) config.RefSpec {
+	prefix := ""
+	if strings.HasPrefix(refspec, "+") {
+		prefix = "+"
+		refspec = refspec[1:]
+	}
+
+	parts := strings.SplitN(refspec, ":", 2)
+
+	src := ""
+	if parts[0] != "" {
+		src = goGitExpandRefName(repository, parts[0]).String()
+	}
+
+	dst := src
+	if len(parts) > 1 {
+		dst = parts[1]
+	}
+
+	if !strings.HasPrefix(dst, "refs/") {
+		namespace := "refs/heads/"
+		if strings.HasPrefix(src, "refs/tags/") {
+			namespace = "refs/tags/"
+		}
+
+		dst = namespace + dst
+	}
+
+	return config.RefSpec(fmt.Sprintf("%s%s:%s", prefix, src, dst))
+}
+
+// goGitExpandRefName expands an abbreviated branch or tag name to the full ref name.
+func goGitExpandRefName(repository *gogit.Repository, name string) plumbing.ReferenceName {
+	if strings.HasPrefix(name, "refs/") || name == plumbing.HEAD.String() {
+		return plumbing.ReferenceName(name)
+	}
+
+	for _, refName := range []plumbing.ReferenceName{
+		plumbing.NewBranchReferenceName(name),
+		plumbing.NewTagReferenceName(name),
+	} {
+		_, err := repository.Reference(refName, false)
+		if err == nil {
+			return refName
+		}
+	}
+
+	return plumbing.NewBranchReferenceName(name)
+}
+
+// goGitPushResults compares the local refs to the remote refs to predict the outcome of the push.
+// goGitPushResults predicts the results of pushing, since go-git does not report them.
+// It also returns the hash every remote ref is expected to have after pushing, the zero hash when deleted.
+func goGitPushResults(
+	repository *gogit.Repository,
+	remote *gogit.Remote,
+	pushOptions *gogit.PushOptions,
+	forced map[string]bool,
+) ([]*GitPushRefResult, map[string]plumbing.Hash, error) {
+	remoteHashes, err := goGitRemoteHashes(remote, pushOptions.Auth)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	results := make([]*GitPushRefResult, 0)
+	expectedHashes := make(map[string]plumbing.Hash)
+
+	for _, refSpec := range pushOptions.RefSpecs {
+		dst := refSpec.Dst("").String()
+		result := &GitPushRefResult{Remote: dst}
+		results = append(results, result)
+
+		remoteHash, existsOnRemote := remoteHashes[dst]
+
+		if refSpec.IsDelete() {
+			result.Status = GitPushStatusDeleted
+			expectedHashes[dst] = plumbing.ZeroHash
+			continue
+		}
+
+		result.Local = refSpec.Src()
+
+		localHash, err := repository.ResolveRevision(plumbing.Revision(result.Local))
+		if err != nil {
+			return nil, nil, newGoGitError(err)
+		}
+
+		expectedHashes[dst] = *localHash
+
+		switch {
+		case !existsOnRemote:
+			result.Status = GitPushStatusNew
+		case remoteHash == *localHash:
+			result.Status = GitPushStatusUpToDate
+		case forced[dst]:
+			result.Status = GitPushStatusForced
+		default:
+			result.Status = GitPushStatusFastForward
+		}
+	}
+
+	return results, expectedHashes, nil
+}
+
+// goGitRejectPushResults marks the results of the refs that were not updated by a failed push as rejected.
+// go-git only reports the first failure, so the remote is listed again to find the refs that were updated,
+// e.g when the remote rejected only some of them.
+func goGitRejectPushResults(
+	remote *gogit.Remote,
+	pushOptions *gogit.PushOptions,
+	results []*GitPushRefResult,
+	expectedHashes map[string]plumbing.Hash,
+	pushErr error,
+) {
+	failedRef, failedReason := goGitPushFailure(pushErr)
+
+	// NOTE: When listing fails too, every ref is assumed not to be updated.
+	remoteHashes, err := goGitRemoteHashes(remote, pushOptions.Auth)
+	if err != nil {
+		remoteHashes = nil
+	}
+
+	for _, result := range results {
+		if result.Status == GitPushStatusUpToDate {
+			continue
+		}
+
+		if result.Remote == failedRef {
+			result.Status = GitPushStatusRejected
+			result.Reason = failedReason
+
+			continue
+		}
+
+		if remoteHashes != nil && remoteHashes[result.Remote] == expectedHashes[result.Remote] {
+			continue
+		}
+
+		result.Status = GitPushStatusRejected
+		result.Reason = pushErr.Error()
+	}
+}
+
+var (
+	goGitCommandErrorRegex   = regexp.MustCompile(`^command error on (\S+): (.*)$`)
+	goGitNonFastForwardRegex = regexp.MustCompile(`^non-fast-forward update: (\S+)$`)
+)
+
+// goGitPushFailure returns the ref a push error is about and the reason, or empty strings for other errors.
+func goGitPushFailure(err error) (string, string) {
+	if match := goGitCommandErrorRegex.FindStringSubmatch(err.Error()); match != nil {
+		return match[1], match[2]
+	}
+
+	if match := goGitNonFastForwardRegex.FindStringSubmatch(err.Error()); match != nil {
+		return match[1], "non-fast-forward"
+	}
+
+	return "", ""
+}
+
+// goGitRemoteHashes returns the hashes of the remote refs by name. Missing refs have the zero hash.
+func goGitRemoteHashes(remote *gogit.Remote, auth transport.AuthMethod) (map[string]plumbing.Hash, error) {
+	remoteRefs, err := remote.List(&gogit.ListOptions{Auth: auth})
+	if err != nil && err != transport.ErrEmptyRemoteRepository {
+		return nil, newGoGitError(err)
+	}
+
+	remoteHashes := make(map[string]plumbing.Hash)
+	for _, remoteRef := range remoteRefs {
+		remoteHashes[remoteRef.Name().String()] = remoteRef.Hash()
+	}
+
+	return remoteHashes, nil
+}
+
+// goGitSetUpstream configures the pushed branches to track the remote branches.
+func goGitSetUpstream(repository *gogit.Repository, remote string, results []*GitPushRefResult) error {
+	repositoryConfig, err := repository.Config()
+	if err != nil {
+		return newGoGitError(err)
+	}
+
+	for _, result := range results {
+		localRefName := plumbing.ReferenceName(result.Local)
+		if !localRefName.IsBranch() {
+			continue
+		}
+
+		branchName := localRefName.Short()
+		repositoryConfig.Branches[branchName] = &config.Branch{
+			Name:   branchName,
+			Remote: remote,
+			Merge:  plumbing.ReferenceName(result.Remote),
+		}
+	}
+
+	err = repository.Storer.SetConfig(repositoryConfig)
+	if err != nil {
+		return newGoGitError(err)
+	}
+
+	return nil
+}
+
+// newGoGitError wraps a go-git error in a GitError, with the same failure kinds as the CLI backend.
+func newGoGitError(err error) error {
+	var kind error
+
+	var netErr net.Error
+
+	switch {
+	case errors.Is(err, transport.ErrAuthenticationRequired),
+		errors.Is(err, transport.ErrAuthorizationFailed),
+		errors.Is(err, transport.ErrInvalidAuthMethod):
+		kind = ErrAuthFailed
+	case errors.Is(err, transport.ErrRepositoryNotFound),
+		errors.Is(err, gogit.ErrRemoteNotFound):
+		kind = ErrRemoteNotFound
+	case errors.Is(err, gogit.ErrNonFastForwardUpdate),
+		errors.Is(err, gogit.ErrForceNeeded),
+		// NOTE: Rejected pushes are reported with an unexported error.
+		strings.HasPrefix(err.Error(), "non-fast-forward update"):
+		kind = ErrNonFastForward
+	case errors.Is(err, gogit.ErrEmptyCommit):
+		kind = ErrNothingToCommit
+	case errors.Is(err, plumbing.ErrReferenceNotFound),
+		errors.Is(err, plumbing.ErrObjectNotFound):
+		kind = ErrRefNotFound
+	case errors.As(err, &netErr):
+		kind = ErrRemoteUnreachable
+	default:
+		kind = gitErrorKind(err.Error(), -1)
+	}
+
+	return &GitError{
+		Kind:     kind,
+		ExitCode: -1,
+		err:      err,
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"errors"
+	"strings"
+)
+
+// GitLFSFilter limits LFS operations to the files matching Include and not matching Exclude.
+// Patterns follow the `lfs.fetchinclude` syntax, e.g `assets/**` or `*.bin`.
+type GitLFSFilter struct {
+	Include []string
+	Exclude []string
+}
+
+// UsesLFS reports whether any `.gitattributes` in the index tracks files with the LFS filter.
+func (git *Git) UsesLFS() (bool, error) {
+	stdout, stderr, err := git.commandExecutor.Execute(
+		git.binPath,
+		[]string{
+			"-C", git.dir, "grep", "--cached", "-l", "-e", "filter=lfs", "--", ":(glob)**/.gitattributes",
+		},
+		git.env,
+		"",
+	)
+	if err != nil {
+		// NOTE: `git grep` exits with 1 and no stderr when nothing matched.
+		if len(stderr) == 0 && len(stdout) == 0 {
+			return false, nil
+		}
+
+		return false, newGitError(err, nil, stderr)
+	}
+
+	return len(strings.TrimSpace(string(stdout))) > 0, nil
+}
+
+// LFSInstall sets up the LFS hooks and filters for the repository.
+// When `skipSmudge` is true, checkouts leave pointer files and objects are downloaded by LFSPull instead.
+func (git *Git) LFSInstall(skipSmudge bool) error {
+	args := []string{"-C", git.dir, "lfs", "install", "--local"}
+	if skipSmudge {
+		args = append(args, "--skip-smudge")
+	}
+
+	_, stderr, err := git.commandExecutor.Execute(git.binPath, args, git.env, "")
+	if err != nil {
+		return newGitError(err, nil, stderr)
+	}
+
+	return nil
+}
+
+// LFSFetch downloads the LFS objects of the checked out ref from `remote`, without updating the working tree.
+// An empty `remote` uses the default remote.
+func (git *Git) LFSFetch(remote string, filter *GitLFSFilter) error {
+	args := []string{"-C", git.dir, "lfs", "fetch"}
+	args = append(args, gitLFSFilterArgs(filter)...)
+
+	if remote != "" {
+		args = append(args, remote)
+	}
+
+	_, stderr, err := git.commandExecutor.Execute(git.binPath, args, git.env, "")
+	if err != nil {
+		return newGitError(err, nil, stderr)
+	}
+
+	return nil
+}
+
+// LFSPull downloads the LFS objects of the checked out ref from `remote` and replaces the pointer files.
+// An empty `remote` uses the default remote.
+// It returns the files matching `filter` that are still pointer files afterwards.
+func (git *Git) LFSPull(remote string, filter *GitLFSFilter) ([]string, error) {
+	args := []string{"-C", git.dir, "lfs", "pull"}
+	args = append(args, gitLFSFilterArgs(filter)...)
+
+	if remote != "" {
+		args = append(args, remote)
+	}
+
+	_, stderr, err := git.commandExecutor.Execute(git.binPath, args, git.env, "")
+	if err != nil {
+		return nil, newGitError(err, nil, stderr)
+	}
+
+	return git.LFSPointerFiles(filter)
+}
+
+// LFSPush uploads the LFS objects referenced by `refs` to `remote`, or the objects of all refs when `refs` is empty.
+// It should be called before Push when the remote does not run the LFS pre-push hook.
+// Unlike LFSFetch and LFSPull, `remote` is required, since `git lfs push` has no default remote.
+func (git *Git) LFSPush(remote string, refs []string) error {
+	if remote == "" {
+		return errors.New("git lfs push remote is required")
+	}
+
+	args := []string{"-C", git.dir, "lfs", "push"}
+
+	// NOTE: `git lfs push` requires either refs or `--all`.
+	if len(refs) == 0 {
+		args = append(args, "--all")
+	}
+
+	args = append(args, remote)
+	args = append(args, refs...)
+
+	_, stderr, err := git.commandExecutor.Execute(git.binPath, args, git.env, "")
+	if err != nil {
+		return newGitError(err, nil, stderr)
+	}
+
+	return nil
+}
+
+// LFSPointerFiles returns the LFS files matching `filter` that are pointer files in the working tree,
+// i.e whose objects were not downloaded.
+func (git *Git) LFSPointerFiles(filter *GitLFSFilter) ([]string, error) {
+	args := []string{"-C", git.dir, "lfs", "ls-files"}
+	args = append(args, gitLFSFilterArgs(filter)...)
+
+	stdout, stderr, err := git.commandExecutor.Execute(git.binPath, args, git.env, "")
+	if err != nil {
+		return nil, newGitError(err, nil, stderr)
+	}
+
+	pointerFiles := make([]string, 0)
+
+	for _, line := range splitLines(stdout) {
+		// NOTE: Every line is "<oid> <*|-> <path>", `-` marks a pointer file.
+		parts := strings.SplitN(line, " ", 3)
+		if len(parts) < 3 || parts[1] != "-" {
+			continue
+		}
+
+		pointerFiles = append(pointerFiles, parts[2])
+	}
+
+	return pointerFiles, nil
+}
+
+func gitLFSFilterArgs(filter *GitLFSFilter) []string {
+	args := make([]string, 0)
+	if filter == nil {
+		return args
+	}
+
+	if len(filter.Include) > 0 {
+		args = append(args, "--include", strings.Join(filter.Include, ","))
+	}
+
+	if len(filter.Exclude) > 0 {
+		args = append(args, "--exclude", strings.Join(filter.Exclude, ","))
+	}
+
+	return args
+}
@@ -0,0 +1,172 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sumup-oss/go-pkgs/os/ostest"
+)
+
+func TestGit_UsesLFS(t *testing.T) {
+	args := []string{
+		"-C", "/tmp/repo", "grep", "--cached", "-l", "-e", "filter=lfs", "--", ":(glob)**/.gitattributes",
+	}
+
+	t.Run("when a .gitattributes uses the lfs filter, it returns true", func(t *testing.T) {
+		t.Parallel()
+
+		osExecutor := ostest.NewFakeOsExecutor(t)
+		gitInstance := NewGit(osExecutor, "", "/tmp/repo", nil)
+
+		osExecutor.On("Execute", "git", args, cLocaleEnvArg, "").Return([]byte(".gitattributes\n"), []byte{}, nil)
+
+		actual, actualErr := gitInstance.UsesLFS()
+		require.Nil(t, actualErr)
+		assert.True(t, actual)
+	})
+
+	t.Run("when nothing matches, it returns false", func(t *testing.T) {
+		t.Parallel()
+
+		osExecutor := ostest.NewFakeOsExecutor(t)
+		gitInstance := NewGit(osExecutor, "", "/tmp/repo", nil)
+
+		osExecutor.On("Execute", "git", args, cLocaleEnvArg, "").Return([]byte{}, []byte{}, errors.New("exit status 1"))
+
+		actual, actualErr := gitInstance.UsesLFS()
+		require.Nil(t, actualErr)
+		assert.False(t, actual)
+	})
+}
+
+func TestGit_LFSPull(t *testing.T) {
+	t.Run("it passes the filter and returns the files left as pointers", func(t *testing.T) {
+		t.Parallel()
+
+		osExecutor := ostest.NewFakeOsExecutor(t)
+		envArg := []string{"GIT_TERMINAL_PROMPT=0"}
+		gitInstance := NewGit(osExecutor, "", "/tmp/repo", envArg)
+
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{
+				"-C", "/tmp/repo", "lfs", "pull", "--include", "assets/**,*.bin", "--exclude", "assets/raw/**", "origin",
+			},
+			[]string{"GIT_TERMINAL_PROMPT=0", "LC_ALL=C"},
+			"",
+		).Return([]byte{}, []byte{}, nil)
+
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{"-C", "/tmp/repo", "lfs", "ls-files", "--include", "assets/**,*.bin", "--exclude", "assets/raw/**"},
+			[]string{"GIT_TERMINAL_PROMPT=0", "LC_ALL=C"},
+			"",
+		).Return(
+			[]byte(
+				"3c4a7e2f10 * assets/logo.png\n"+
+					"9d1f0b3a22 - assets/video intro.mp4\n"+
+					"71be0c9e45 - firmware.bin\n",
+			),
+			[]byte{},
+			nil,
+		)
+
+		actual, actualErr := gitInstance.LFSPull(
+			"origin",
+			&GitLFSFilter{
+				Include: []string{"assets/**", "*.bin"},
+				Exclude: []string{"assets/raw/**"},
+			},
+		)
+		require.Nil(t, actualErr)
+		assert.Equal(t, []string{"assets/video intro.mp4", "firmware.bin"}, actual)
+	})
+
+	t.Run("when pulling fails, it returns typed error", func(t *testing.T) {
+		t.Parallel()
+
+		osExecutor := ostest.NewFakeOsExecutor(t)
+		gitInstance := NewGit(osExecutor, "", "/tmp/repo", nil)
+
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{"-C", "/tmp/repo", "lfs", "pull"},
+			cLocaleEnvArg,
+			"",
+		).Return([]byte{}, []byte("batch response: Authentication failed"), errors.New("exit status 2"))
+
+		actual, actualErr := gitInstance.LFSPull("", nil)
+		require.NotNil(t, actualErr)
+		assert.Nil(t, actual)
+		assert.True(t, errors.Is(actualErr, ErrAuthFailed))
+	})
+}
+
+func TestGit_LFSPush(t *testing.T) {
+	t.Run("it pushes the objects of the refs", func(t *testing.T) {
+		t.Parallel()
+
+		osExecutor := ostest.NewFakeOsExecutor(t)
+		gitInstance := NewGit(osExecutor, "", "/tmp/repo", nil)
+
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{"-C", "/tmp/repo", "lfs", "push", "origin", "main"},
+			cLocaleEnvArg,
+			"",
+		).Return([]byte{}, []byte{}, nil)
+
+		actualErr := gitInstance.LFSPush("origin", []string{"main"})
+		require.Nil(t, actualErr)
+	})
+
+	t.Run("without refs, it pushes the objects of all refs", func(t *testing.T) {
+		t.Parallel()
+
+		osExecutor := ostest.NewFakeOsExecutor(t)
+		gitInstance := NewGit(osExecutor, "", "/tmp/repo", nil)
+
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{"-C", "/tmp/repo", "lfs", "push", "--all", "origin"},
+			cLocaleEnvArg,
+			"",
+		).Return([]byte{}, []byte{}, nil)
+
+		actualErr := gitInstance.LFSPush("origin", nil)
+		require.Nil(t, actualErr)
+	})
+
+	t.Run("without remote, it returns error without pushing", func(t *testing.T) {
+		t.Parallel()
+
+		osExecutor := ostest.NewFakeOsExecutor(t)
+		gitInstance := NewGit(osExecutor, "", "/tmp/repo", nil)
+
+		actualErr := gitInstance.LFSPush("", []string{"main"})
+		require.NotNil(t, actualErr)
+		assert.Contains(t, actualErr.Error(), "remote is required")
+	})
+}
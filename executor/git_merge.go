@@ -0,0 +1,224 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"fmt"
+)
+
+type GitMergeOptions struct {
+	// NoFastForward always creates a merge commit (`--no-ff`).
+	NoFastForward bool
+	// FastForwardOnly refuses to merge unless the current HEAD can be fast-forwarded (`--ff-only`).
+	FastForwardOnly bool
+	Squash          bool
+	Message         string
+	Strategy        string
+	StrategyOptions []string
+}
+
+type GitRebaseOptions struct {
+	// Onto rebases onto a different base than `upstream` (`--onto`).
+	Onto      string
+	Autostash bool
+}
+
+type GitCherryPickOptions struct {
+	// RecordOrigin appends "(cherry picked from commit ...)" to the commit message (`-x`).
+	RecordOrigin bool
+	// Mainline is the parent number to use when cherry-picking merge commits (`--mainline`).
+	Mainline int
+}
+
+// GitMergeResult is the outcome of a merge, rebase or cherry-pick.
+// When ConflictedFiles is not empty, the operation stopped
+// and is waiting to be continued or aborted.
+type GitMergeResult struct {
+	// Hash is the HEAD commit after a successful operation.
+	Hash            string
+	ConflictedFiles []string
+}
+
+// HasConflicts returns whether the operation stopped because of conflicts.
+func (result *GitMergeResult) HasConflicts() bool {
+	return len(result.ConflictedFiles) > 0
+}
+
+// Merge merges `ref` into the current branch.
+// Conflicts are not reported as error, but as GitMergeResult.ConflictedFiles.
+func (git *Git) Merge(ref string, options *GitMergeOptions) (*GitMergeResult, error) {
+	args := []string{"-C", git.dir, "merge", "--no-edit"}
+
+	if options != nil {
+		if options.NoFastForward {
+			args = append(args, "--no-ff")
+		}
+
+		if options.FastForwardOnly {
+			args = append(args, "--ff-only")
+		}
+
+		if options.Squash {
+			args = append(args, "--squash")
+		}
+
+		if options.Message != "" {
+			args = append(args, "-m", options.Message)
+		}
+
+		if options.Strategy != "" {
+			args = append(args, fmt.Sprintf("--strategy=%s", options.Strategy))
+		}
+
+		for _, strategyOption := range options.StrategyOptions {
+			args = append(args, fmt.Sprintf("--strategy-option=%s", strategyOption))
+		}
+	}
+
+	args = append(args, ref)
+
+	return git.executeMergeCommand(args)
+}
+
+// MergeAbort aborts a merge that stopped because of conflicts.
+func (git *Git) MergeAbort() error {
+	return git.executeSequencerCommand("merge", "--abort")
+}
+
+// MergeContinue concludes a merge, after the conflicts have been resolved and added.
+func (git *Git) MergeContinue() (*GitMergeResult, error) {
+	return git.executeMergeCommand(git.sequencerArgs("merge", "--continue"))
+}
+
+// Rebase rebases the current branch on top of `upstream`.
+// Conflicts are not reported as error, but as GitMergeResult.ConflictedFiles.
+func (git *Git) Rebase(upstream string, options *GitRebaseOptions) (*GitMergeResult, error) {
+	args := []string{"-C", git.dir, "rebase"}
+
+	if options != nil {
+		if options.Onto != "" {
+			args = append(args, "--onto", options.Onto)
+		}
+
+		if options.Autostash {
+			args = append(args, "--autostash")
+		}
+	}
+
+	args = append(args, upstream)
+
+	return git.executeMergeCommand(args)
+}
+
+// RebaseAbort aborts a rebase that stopped because of conflicts,
+// returning the branch to its state before the rebase.
+func (git *Git) RebaseAbort() error {
+	return git.executeSequencerCommand("rebase", "--abort")
+}
+
+// RebaseContinue continues a rebase, after the conflicts have been resolved and added.
+// It can stop again because of conflicts in the following commits.
+func (git *Git) RebaseContinue() (*GitMergeResult, error) {
+	return git.executeMergeCommand(git.sequencerArgs("rebase", "--continue"))
+}
+
+// CherryPick applies `commits` in order on top of the current branch.
+// Conflicts are not reported as error, but as GitMergeResult.ConflictedFiles.
+func (git *Git) CherryPick(commits []string, options *GitCherryPickOptions) (*GitMergeResult, error) {
+	args := []string{"-C", git.dir, "cherry-pick"}
+
+	if options != nil {
+		if options.RecordOrigin {
+			args = append(args, "-x")
+		}
+
+		if options.Mainline > 0 {
+			args = append(args, "--mainline", fmt.Sprintf("%d", options.Mainline))
+		}
+	}
+
+	args = append(args, commits...)
+
+	return git.executeMergeCommand(args)
+}
+
+// CherryPickAbort aborts a cherry-pick that stopped because of conflicts.
+func (git *Git) CherryPickAbort() error {
+	return git.executeSequencerCommand("cherry-pick", "--abort")
+}
+
+// CherryPickContinue continues a cherry-pick, after the conflicts have been resolved and added.
+// It can stop again because of conflicts in the following commits.
+func (git *Git) CherryPickContinue() (*GitMergeResult, error) {
+	return git.executeMergeCommand(git.sequencerArgs("cherry-pick", "--continue"))
+}
+
+// ConflictedFiles returns the unmerged files of the working directory.
+func (git *Git) ConflictedFiles() ([]string, error) {
+	stdout, stderr, err := git.commandExecutor.Execute(
+		git.binPath,
+		[]string{"-C", git.dir, "diff", "--name-only", "--diff-filter=U"},
+		git.env,
+		"",
+	)
+	if err != nil {
+		return nil, newGitError(err, nil, stderr)
+	}
+
+	return splitLines(stdout), nil
+}
+
+func (git *Git) executeMergeCommand(args []string) (*GitMergeResult, error) {
+	stdout, stderr, err := git.commandExecutor.Execute(git.binPath, args, git.env, "")
+	if err != nil {
+		conflictedFiles, conflictedErr := git.ConflictedFiles()
+		if conflictedErr != nil || len(conflictedFiles) == 0 {
+			return nil, newGitError(err, stdout, stderr)
+		}
+
+		return &GitMergeResult{ConflictedFiles: conflictedFiles}, nil
+	}
+
+	hash, err := git.GetCurrentHash()
+	if err != nil {
+		return nil, err
+	}
+
+	return &GitMergeResult{
+		Hash:            hash,
+		ConflictedFiles: make([]string, 0),
+	}, nil
+}
+
+func (git *Git) executeSequencerCommand(command, action string) error {
+	_, stderr, err := git.commandExecutor.Execute(
+		git.binPath,
+		git.sequencerArgs(command, action),
+		git.env,
+		"",
+	)
+	if err != nil {
+		return newGitError(err, nil, stderr)
+	}
+
+	return nil
+}
+
+// sequencerArgs builds the arguments of `--continue`/`--abort` actions.
+// NOTE: `core.editor` is overridden, since continuing may ask for a commit message to be edited
+// and there is no terminal to edit it in.
+func (git *Git) sequencerArgs(command, action string) []string {
+	return []string{"-C", git.dir, "-c", "core.editor=true", command, action}
+}
@@ -0,0 +1,144 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sumup-oss/go-pkgs/os/ostest"
+)
+
+func TestGit_Merge(t *testing.T) {
+	t.Run("when merging succeeds, it returns result with the new HEAD hash", func(t *testing.T) {
+		t.Parallel()
+
+		osExecutor := ostest.NewFakeOsExecutor(t)
+		gitInstance := NewGit(osExecutor, "", "/tmp/repo", nil)
+
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{"-C", "/tmp/repo", "merge", "--no-edit", "--no-ff", "-m", "Merge feature", "origin/feature"},
+			cLocaleEnvArg,
+			"",
+		).Return([]byte{}, []byte{}, nil)
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{"-C", "/tmp/repo", "rev-parse", "HEAD"},
+			cLocaleEnvArg,
+			"",
+		).Return([]byte("abc123\n"), []byte{}, nil)
+
+		actual, actualErr := gitInstance.Merge(
+			"origin/feature",
+			&GitMergeOptions{NoFastForward: true, Message: "Merge feature"},
+		)
+		require.Nil(t, actualErr)
+		assert.Equal(t, "abc123", actual.Hash)
+		assert.False(t, actual.HasConflicts())
+	})
+
+	t.Run("when merging stops because of conflicts, it returns result with the conflicted files", func(t *testing.T) {
+		t.Parallel()
+
+		osExecutor := ostest.NewFakeOsExecutor(t)
+		gitInstance := NewGit(osExecutor, "", "/tmp/repo", nil)
+
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{"-C", "/tmp/repo", "merge", "--no-edit", "origin/feature"},
+			cLocaleEnvArg,
+			"",
+		).Return(
+			[]byte("CONFLICT (content): Merge conflict in values.yaml"),
+			[]byte{},
+			errors.New("exit status 1"),
+		)
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{"-C", "/tmp/repo", "diff", "--name-only", "--diff-filter=U"},
+			cLocaleEnvArg,
+			"",
+		).Return([]byte("values.yaml\nChart.yaml\n"), []byte{}, nil)
+
+		actual, actualErr := gitInstance.Merge("origin/feature", nil)
+		require.Nil(t, actualErr)
+		assert.True(t, actual.HasConflicts())
+		assert.Equal(t, []string{"values.yaml", "Chart.yaml"}, actual.ConflictedFiles)
+		assert.Equal(t, "", actual.Hash)
+	})
+
+	t.Run("when merging fails without conflicts, it returns error", func(t *testing.T) {
+		t.Parallel()
+
+		osExecutor := ostest.NewFakeOsExecutor(t)
+		gitInstance := NewGit(osExecutor, "", "/tmp/repo", nil)
+
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{"-C", "/tmp/repo", "merge", "--no-edit", "--ff-only", "origin/feature"},
+			cLocaleEnvArg,
+			"",
+		).Return([]byte{}, []byte("fatal: Not possible to fast-forward, aborting."), errors.New("exit status 128"))
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{"-C", "/tmp/repo", "diff", "--name-only", "--diff-filter=U"},
+			cLocaleEnvArg,
+			"",
+		).Return([]byte{}, []byte{}, nil)
+
+		actual, actualErr := gitInstance.Merge("origin/feature", &GitMergeOptions{FastForwardOnly: true})
+		require.NotNil(t, actualErr)
+		assert.Nil(t, actual)
+		assert.Contains(t, actualErr.Error(), "Not possible to fast-forward")
+	})
+}
+
+func TestGit_RebaseContinue(t *testing.T) {
+	t.Run("it continues without opening an editor", func(t *testing.T) {
+		t.Parallel()
+
+		osExecutor := ostest.NewFakeOsExecutor(t)
+		gitInstance := NewGit(osExecutor, "", "/tmp/repo", nil)
+
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{"-C", "/tmp/repo", "-c", "core.editor=true", "rebase", "--continue"},
+			cLocaleEnvArg,
+			"",
+		).Return([]byte{}, []byte{}, nil)
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{"-C", "/tmp/repo", "rev-parse", "HEAD"},
+			cLocaleEnvArg,
+			"",
+		).Return([]byte("abc123\n"), []byte{}, nil)
+
+		actual, actualErr := gitInstance.RebaseContinue()
+		require.Nil(t, actualErr)
+		assert.Equal(t, "abc123", actual.Hash)
+	})
+}
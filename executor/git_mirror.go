@@ -0,0 +1,431 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	stdOs "os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/palantir/stacktrace"
+
+	"github.com/sumup-oss/go-pkgs/os"
+)
+
+const (
+	gitMirrorSuffix          = ".git"
+	gitMirrorTmpSuffix       = ".tmp-"
+	gitMirrorLockSuffix      = ".lock"
+	gitMirrorLastUsedFile    = "mirror-last-used"
+	gitMirrorLastFetchedFile = "mirror-last-fetched"
+)
+
+var errGitMirrorLocked = errors.New("git mirror is locked")
+
+type GitMirrorCacheOptions struct {
+	// UpdateInterval is the minimum time between fetches of a mirror by Clone.
+	// Zero fetches on every Clone.
+	UpdateInterval time.Duration
+	// MaxAge evicts mirrors that were not used for longer. Zero disables age eviction.
+	MaxAge time.Duration
+	// MaxSize evicts the least recently used mirrors, until the cache takes at most MaxSize bytes.
+	// Zero disables size eviction.
+	MaxSize int64
+}
+
+type GitMirrorCloneOptions struct {
+	// Local clones from the mirror with hardlinked objects and then points `origin` to the URL,
+	// instead of cloning from the URL with `--reference` to the mirror.
+	Local bool
+	// Dissociate copies the borrowed objects with `--dissociate`,
+	// so that the clone keeps working when the mirror is evicted.
+	// Clones made without Dissociate or Local break when their mirror is evicted.
+	Dissociate bool
+}
+
+// GitMirrorCache keeps bare mirrors of repositories in `dir`, keyed by URL,
+// so that repeated clones only download what changed since the last clone.
+// It is safe for concurrent use across processes sharing `dir`, through file locks.
+type GitMirrorCache struct {
+	dir        string
+	env        []string
+	osExecutor os.OsExecutor
+	options    *GitMirrorCacheOptions
+}
+
+func NewGitMirrorCache(
+	executor os.OsExecutor,
+	dir string,
+	env []string,
+	options *GitMirrorCacheOptions,
+) *GitMirrorCache {
+	if options == nil {
+		options = &GitMirrorCacheOptions{}
+	}
+
+	return &GitMirrorCache{
+		dir:        dir,
+		env:        env,
+		osExecutor: executor,
+		options:    options,
+	}
+}
+
+// MirrorDir returns the directory of the mirror of `url`.
+func (cache *GitMirrorCache) MirrorDir(url string) string {
+	return filepath.Join(cache.dir, gitMirrorKey(url)+gitMirrorSuffix)
+}
+
+// UpdateMirror creates the mirror of `url`, or fetches it with `--prune` when it exists.
+func (cache *GitMirrorCache) UpdateMirror(url string) error {
+	unlock, err := cache.lock(url, true, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return cache.updateMirror(url)
+}
+
+// Clone clones `url` into `dir` using the mirror of `url`, which is created or updated first,
+// and returns a Git instance bound to `dir`.
+func (cache *GitMirrorCache) Clone(url, dir string, options *GitMirrorCloneOptions) (*Git, error) {
+	if options == nil {
+		options = &GitMirrorCloneOptions{}
+	}
+
+	unlock, err := cache.lockEnsuredMirror(url)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	mirrorDir := cache.MirrorDir(url)
+	git := NewGit(cache.osExecutor, url, dir, cache.env)
+
+	args := []string{"clone", "--reference", mirrorDir}
+	if options.Dissociate {
+		args = append(args, "--dissociate")
+	}
+
+	args = append(args, url, dir)
+
+	if options.Local {
+		args = []string{"clone", "--local", mirrorDir, dir}
+	}
+
+	_, stderr, err := cache.osExecutor.Execute(git.binPath, args, git.env, "")
+	if err != nil {
+		return nil, newGitError(err, nil, stderr)
+	}
+
+	if options.Local {
+		err = git.SetRemoteURL("origin", url)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = cache.touch(mirrorDir, gitMirrorLastUsedFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return git, nil
+}
+
+// Evict removes the mirrors that exceed MaxAge and then the least recently used mirrors beyond MaxSize.
+// Mirrors in use by other clones or updates are skipped.
+// It also removes the temporary dirs left behind by interrupted mirror clones.
+// It returns the directories of the removed mirrors.
+func (cache *GitMirrorCache) Evict() ([]string, error) {
+	evicted := make([]string, 0)
+
+	fileInfos, err := ioutil.ReadDir(cache.dir)
+	if err != nil {
+		if cache.osExecutor.IsNotExist(err) {
+			return evicted, nil
+		}
+
+		return nil, stacktrace.Propagate(err, "failed to list git mirror cache %s", cache.dir)
+	}
+
+	type mirror struct {
+		key      string
+		dir      string
+		lastUsed time.Time
+		size     int64
+	}
+
+	mirrors := make([]*mirror, 0)
+
+	var totalSize int64
+
+	for _, fileInfo := range fileInfos {
+		if !fileInfo.IsDir() {
+			continue
+		}
+
+		tmpIndex := strings.Index(fileInfo.Name(), gitMirrorSuffix+gitMirrorTmpSuffix)
+		if tmpIndex > 0 {
+			// NOTE: The temporary dir is only in use while the exclusive lock of its mirror is held.
+			_, err = cache.removeMirror(fileInfo.Name()[:tmpIndex], filepath.Join(cache.dir, fileInfo.Name()))
+			if err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		if !strings.HasSuffix(fileInfo.Name(), gitMirrorSuffix) {
+			continue
+		}
+
+		mirrorEntry := &mirror{
+			key:      strings.TrimSuffix(fileInfo.Name(), gitMirrorSuffix),
+			dir:      filepath.Join(cache.dir, fileInfo.Name()),
+			lastUsed: fileInfo.ModTime(),
+		}
+
+		lastUsedInfo, err := cache.osExecutor.Stat(filepath.Join(mirrorEntry.dir, gitMirrorLastUsedFile))
+		if err == nil {
+			mirrorEntry.lastUsed = lastUsedInfo.ModTime()
+		}
+
+		mirrorEntry.size, err = dirSize(mirrorEntry.dir)
+		if err != nil {
+			return nil, err
+		}
+
+		totalSize += mirrorEntry.size
+		mirrors = append(mirrors, mirrorEntry)
+	}
+
+	sort.Slice(mirrors, func(i, j int) bool { return mirrors[i].lastUsed.Before(mirrors[j].lastUsed) })
+
+	now := time.Now()
+
+	for _, mirrorEntry := range mirrors {
+		expired := cache.options.MaxAge > 0 && now.Sub(mirrorEntry.lastUsed) > cache.options.MaxAge
+		oversized := cache.options.MaxSize > 0 && totalSize > cache.options.MaxSize
+
+		if !expired && !oversized {
+			continue
+		}
+
+		removed, err := cache.removeMirror(mirrorEntry.key, mirrorEntry.dir)
+		if err != nil {
+			return evicted, err
+		}
+
+		if removed {
+			totalSize -= mirrorEntry.size
+			evicted = append(evicted, mirrorEntry.dir)
+		}
+	}
+
+	return evicted, nil
+}
+
+// ensureMirror creates the mirror of `url`, or fetches it when it was not fetched within UpdateInterval.
+func (cache *GitMirrorCache) ensureMirror(url string) error {
+	unlock, err := cache.lock(url, true, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	lastFetchedInfo, err := cache.osExecutor.Stat(filepath.Join(cache.MirrorDir(url), gitMirrorLastFetchedFile))
+	if err == nil && time.Since(lastFetchedInfo.ModTime()) < cache.options.UpdateInterval {
+		return nil
+	}
+
+	return cache.updateMirror(url)
+}
+
+// lockEnsuredMirror ensures the mirror of `url` and returns with its shared lock held.
+// The mirror is checked again under the shared lock,
+// since Evict may remove it between the release of the exclusive lock and the acquisition of the shared lock.
+func (cache *GitMirrorCache) lockEnsuredMirror(url string) (func(), error) {
+	mirrorDir := cache.MirrorDir(url)
+
+	for {
+		err := cache.ensureMirror(url)
+		if err != nil {
+			return nil, err
+		}
+
+		unlock, err := cache.lock(url, false, true)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = cache.osExecutor.Stat(mirrorDir)
+		if err == nil {
+			return unlock, nil
+		}
+
+		unlock()
+
+		if !cache.osExecutor.IsNotExist(err) {
+			return nil, stacktrace.Propagate(err, "failed to stat git mirror %s", mirrorDir)
+		}
+	}
+}
+
+// updateMirror expects the exclusive lock of the mirror to be held.
+func (cache *GitMirrorCache) updateMirror(url string) error {
+	mirrorDir := cache.MirrorDir(url)
+
+	_, err := cache.osExecutor.Stat(mirrorDir)
+	if err != nil && !cache.osExecutor.IsNotExist(err) {
+		return stacktrace.Propagate(err, "failed to stat git mirror %s", mirrorDir)
+	}
+
+	if err == nil {
+		err = NewGit(cache.osExecutor, url, mirrorDir, cache.env).Fetch()
+		if err != nil {
+			return err
+		}
+	} else {
+		// NOTE: Clone into a temporary dir, so that an interrupted clone never leaves a broken mirror.
+		tmpDir := fmt.Sprintf("%s%s%d", mirrorDir, gitMirrorTmpSuffix, stdOs.Getpid())
+
+		_, stderr, err := cache.osExecutor.Execute(
+			"git",
+			[]string{"clone", "--mirror", url, tmpDir},
+			gitLocaleEnv(cache.env),
+			"",
+		)
+		if err != nil {
+			_ = cache.osExecutor.RemoveAll(tmpDir)
+
+			return newGitError(err, nil, stderr)
+		}
+
+		err = stdOs.Rename(tmpDir, mirrorDir)
+		if err != nil {
+			_ = cache.osExecutor.RemoveAll(tmpDir)
+
+			return stacktrace.Propagate(err, "failed to move git mirror to %s", mirrorDir)
+		}
+	}
+
+	return cache.touch(mirrorDir, gitMirrorLastFetchedFile)
+}
+
+// removeMirror removes the mirror, or its temporary dir, unless it's locked.
+func (cache *GitMirrorCache) removeMirror(key, mirrorDir string) (bool, error) {
+	unlock, err := cache.lockKey(key, true, false)
+	if err == errGitMirrorLocked {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	err = cache.osExecutor.RemoveAll(mirrorDir)
+	if err != nil {
+		return false, stacktrace.Propagate(err, "failed to remove git mirror %s", mirrorDir)
+	}
+
+	return true, nil
+}
+
+func (cache *GitMirrorCache) lock(url string, exclusive, blocking bool) (func(), error) {
+	return cache.lockKey(gitMirrorKey(url), exclusive, blocking)
+}
+
+// lockKey locks the lock file of the mirror,
+// which lives next to the mirror, so that it outlives the mirror's eviction.
+func (cache *GitMirrorCache) lockKey(key string, exclusive, blocking bool) (func(), error) {
+	err := cache.osExecutor.MkdirAll(cache.dir, 0755)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "failed to create git mirror cache %s", cache.dir)
+	}
+
+	lockPath := filepath.Join(cache.dir, key+gitMirrorLockSuffix)
+
+	lockFile, err := cache.osExecutor.OpenFile(lockPath, stdOs.O_CREATE|stdOs.O_RDWR, 0644)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "failed to open git mirror lock %s", lockPath)
+	}
+
+	err = flockFile(lockFile, exclusive, blocking)
+	if err != nil {
+		_ = lockFile.Close()
+
+		if err == errGitMirrorLocked {
+			return nil, err
+		}
+
+		return nil, stacktrace.Propagate(err, "failed to lock git mirror lock %s", lockPath)
+	}
+
+	return func() { _ = lockFile.Close() }, nil
+}
+
+func (cache *GitMirrorCache) touch(mirrorDir, file string) error {
+	path := filepath.Join(mirrorDir, file)
+
+	err := cache.osExecutor.WriteFile(path, []byte(time.Now().UTC().Format(time.RFC3339)), 0644)
+	if err != nil {
+		return stacktrace.Propagate(err, "failed to write %s", path)
+	}
+
+	return nil
+}
+
+func gitMirrorKey(url string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(url)))[:32]
+}
+
+func dirSize(dir string) (int64, error) {
+	var size int64
+
+	err := filepath.Walk(
+		dir,
+		func(_ string, fileInfo stdOs.FileInfo, err error) error {
+			// NOTE: The walk doesn't hold the lock of the mirror,
+			// so a concurrent fetch may remove files, e.g packs replaced by `git gc --auto`.
+			if stdOs.IsNotExist(err) {
+				return nil
+			}
+
+			if err != nil {
+				return err
+			}
+
+			if !fileInfo.IsDir() {
+				size += fileInfo.Size()
+			}
+
+			return nil
+		},
+	)
+	if err != nil {
+		return 0, stacktrace.Propagate(err, "failed to compute size of %s", dir)
+	}
+
+	return size, nil
+}
@@ -0,0 +1,189 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"io/ioutil"
+	stdOs "os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sumup-oss/go-pkgs/os"
+)
+
+func TestGitMirrorCache_Clone_Integration(t *testing.T) {
+	osExecutor := &os.RealOsExecutor{}
+	if err := hasGitBinary(osExecutor); err != nil {
+		t.Skipf("No `git` binary found in $PATH. Error: %s\n", err)
+	}
+
+	testCases := []struct {
+		name    string
+		options *GitMirrorCloneOptions
+	}{
+		{name: "when cloning with reference, it clones at the remote's HEAD", options: nil},
+		{
+			name:    "when cloning with reference and dissociate, it clones at the remote's HEAD",
+			options: &GitMirrorCloneOptions{Dissociate: true},
+		},
+		{
+			name:    "when cloning locally, it clones at the remote's HEAD with origin pointing to the remote",
+			options: &GitMirrorCloneOptions{Local: true},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			tmpDir, err := ioutil.TempDir("", "git-mirror")
+			require.Nil(t, err)
+
+			defer stdOs.RemoveAll(tmpDir)
+
+			remoteDir := filepath.Join(tmpDir, "remote.git")
+			expectedHash := seedGitRemote(t, remoteDir, filepath.Join(tmpDir, "seed"))
+
+			cache := NewGitMirrorCache(osExecutor, filepath.Join(tmpDir, "cache"), nil, nil)
+
+			for _, cloneDir := range []string{"first-clone", "second-clone"} {
+				gitInstance, err := cache.Clone(remoteDir, filepath.Join(tmpDir, cloneDir), testCase.options)
+				require.Nil(t, err)
+
+				actualHash, err := gitInstance.GetCurrentHash()
+				require.Nil(t, err)
+				assert.Equal(t, expectedHash, actualHash)
+
+				actualRemotes, err := gitInstance.ListRemotes()
+				require.Nil(t, err)
+				require.Len(t, actualRemotes, 1)
+				assert.Equal(t, remoteDir, actualRemotes[0].FetchURL)
+			}
+
+			_, err = osExecutor.Stat(filepath.Join(cache.MirrorDir(remoteDir), "HEAD"))
+			assert.Nil(t, err)
+		})
+	}
+}
+
+func TestGitMirrorCache_Evict_Integration(t *testing.T) {
+	osExecutor := &os.RealOsExecutor{}
+	if err := hasGitBinary(osExecutor); err != nil {
+		t.Skipf("No `git` binary found in $PATH. Error: %s\n", err)
+	}
+
+	t.Run("it evicts mirrors unused for longer than MaxAge and skips locked mirrors", func(t *testing.T) {
+		t.Parallel()
+
+		tmpDir, err := ioutil.TempDir("", "git-mirror")
+		require.Nil(t, err)
+
+		defer stdOs.RemoveAll(tmpDir)
+
+		urls := []string{filepath.Join(tmpDir, "old.git"), filepath.Join(tmpDir, "locked.git"), filepath.Join(tmpDir, "new.git")}
+		cache := NewGitMirrorCache(osExecutor, filepath.Join(tmpDir, "cache"), nil, &GitMirrorCacheOptions{MaxAge: time.Hour})
+
+		for _, url := range urls {
+			seedGitRemote(t, url, url+"-seed")
+			require.Nil(t, cache.UpdateMirror(url))
+		}
+
+		lastUsed := time.Now().Add(-2 * time.Hour)
+		for _, url := range urls[:2] {
+			lastUsedPath := filepath.Join(cache.MirrorDir(url), gitMirrorLastUsedFile)
+			require.Nil(t, ioutil.WriteFile(lastUsedPath, []byte{}, 0644))
+			require.Nil(t, stdOs.Chtimes(lastUsedPath, lastUsed, lastUsed))
+		}
+
+		unlock, err := cache.lock(urls[1], false, true)
+		require.Nil(t, err)
+
+		defer unlock()
+
+		actual, err := cache.Evict()
+		require.Nil(t, err)
+		assert.Equal(t, []string{cache.MirrorDir(urls[0])}, actual)
+
+		_, err = osExecutor.Stat(cache.MirrorDir(urls[1]))
+		assert.Nil(t, err)
+		_, err = osExecutor.Stat(cache.MirrorDir(urls[2]))
+		assert.Nil(t, err)
+	})
+
+	t.Run("it evicts the least recently used mirrors beyond MaxSize", func(t *testing.T) {
+		t.Parallel()
+
+		tmpDir, err := ioutil.TempDir("", "git-mirror")
+		require.Nil(t, err)
+
+		defer stdOs.RemoveAll(tmpDir)
+
+		urls := []string{filepath.Join(tmpDir, "first.git"), filepath.Join(tmpDir, "second.git")}
+		cache := NewGitMirrorCache(osExecutor, filepath.Join(tmpDir, "cache"), nil, &GitMirrorCacheOptions{MaxSize: 1})
+
+		for i, url := range urls {
+			seedGitRemote(t, url, url+"-seed")
+			require.Nil(t, cache.UpdateMirror(url))
+
+			lastUsed := time.Now().Add(time.Duration(i-10) * time.Minute)
+			lastUsedPath := filepath.Join(cache.MirrorDir(url), gitMirrorLastUsedFile)
+			require.Nil(t, ioutil.WriteFile(lastUsedPath, []byte{}, 0644))
+			require.Nil(t, stdOs.Chtimes(lastUsedPath, lastUsed, lastUsed))
+		}
+
+		actual, err := cache.Evict()
+		require.Nil(t, err)
+		assert.Equal(t, []string{cache.MirrorDir(urls[0]), cache.MirrorDir(urls[1])}, actual)
+	})
+	t.Run("it removes the temporary dirs of interrupted mirror clones, unless their mirror is locked", func(t *testing.T) {
+		t.Parallel()
+
+		tmpDir, err := ioutil.TempDir("", "git-mirror")
+		require.Nil(t, err)
+
+		defer stdOs.RemoveAll(tmpDir)
+
+		urls := []string{filepath.Join(tmpDir, "interrupted.git"), filepath.Join(tmpDir, "cloning.git")}
+		cache := NewGitMirrorCache(osExecutor, filepath.Join(tmpDir, "cache"), nil, nil)
+
+		tmpDirs := make([]string, 0, len(urls))
+		for _, url := range urls {
+			mirrorTmpDir := cache.MirrorDir(url) + gitMirrorTmpSuffix + "4242"
+			require.Nil(t, stdOs.MkdirAll(filepath.Join(mirrorTmpDir, "objects"), 0755))
+
+			tmpDirs = append(tmpDirs, mirrorTmpDir)
+		}
+
+		unlock, err := cache.lock(urls[1], true, true)
+		require.Nil(t, err)
+
+		defer unlock()
+
+		actual, err := cache.Evict()
+		require.Nil(t, err)
+		assert.Equal(t, []string{}, actual)
+
+		_, err = osExecutor.Stat(tmpDirs[0])
+		assert.True(t, osExecutor.IsNotExist(err))
+		_, err = osExecutor.Stat(tmpDirs[1])
+		assert.Nil(t, err)
+	})
+}
@@ -0,0 +1,50 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !windows
+// +build !windows
+
+package executor
+
+import (
+	stdOs "os"
+	"syscall"
+)
+
+// flockFile locks `file` with `flock`, which is released when the file is closed,
+// including when the process dies.
+// It returns errGitMirrorLocked when `blocking` is false and the lock is held by someone else.
+func flockFile(file *stdOs.File, exclusive, blocking bool) error {
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+
+	if !blocking {
+		how |= syscall.LOCK_NB
+	}
+
+	for {
+		err := syscall.Flock(int(file.Fd()), how)
+		if err == syscall.EINTR {
+			continue
+		}
+
+		if err == syscall.EWOULDBLOCK {
+			return errGitMirrorLocked
+		}
+
+		return err
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"io/ioutil"
+	stdOs "os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirSize(t *testing.T) {
+	t.Run("it sums the size of the files", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "go-pkgs-dir-size")
+		require.Nil(t, err)
+
+		defer stdOs.RemoveAll(dir)
+
+		require.Nil(t, stdOs.MkdirAll(filepath.Join(dir, "objects"), 0755))
+		require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "HEAD"), []byte("ref: refs/heads/main\n"), 0644))
+		require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "objects", "pack"), make([]byte, 100), 0644))
+
+		actual, actualErr := dirSize(dir)
+		require.Nil(t, actualErr)
+		assert.Equal(t, int64(121), actual)
+	})
+
+	t.Run("when the dir was removed concurrently, it returns zero", func(t *testing.T) {
+		actual, actualErr := dirSize(filepath.Join(stdOs.TempDir(), "go-pkgs-dir-size-missing"))
+		require.Nil(t, actualErr)
+		assert.Equal(t, int64(0), actual)
+	})
+}
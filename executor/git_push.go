@@ -0,0 +1,161 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"fmt"
+	"strings"
+)
+
+type GitPushStatus string
+
+const (
+	GitPushStatusFastForward GitPushStatus = "fast-forward"
+	GitPushStatusForced      GitPushStatus = "forced"
+	GitPushStatusDeleted     GitPushStatus = "deleted"
+	GitPushStatusNew         GitPushStatus = "new"
+	GitPushStatusRejected    GitPushStatus = "rejected"
+	GitPushStatusUpToDate    GitPushStatus = "up-to-date"
+)
+
+var gitPushStatusFlags = map[string]GitPushStatus{
+	" ": GitPushStatusFastForward,
+	"+": GitPushStatusForced,
+	"-": GitPushStatusDeleted,
+	"*": GitPushStatusNew,
+	"!": GitPushStatusRejected,
+	"=": GitPushStatusUpToDate,
+}
+
+// GitPushLease is an explicit `--force-with-lease=<Ref>:<Expected>`.
+// The push of Ref is rejected, unless the remote ref currently points to Expected.
+type GitPushLease struct {
+	Ref      string
+	Expected string
+}
+
+type GitPushOptions struct {
+	Force bool
+	// ForceWithLease forces the push, unless the remote refs were updated since they were last fetched.
+	ForceWithLease bool
+	// Leases forces the push with explicit expected values of the remote refs.
+	Leases []*GitPushLease
+	// Atomic either updates all refs on the remote, or none of them.
+	Atomic      bool
+	SetUpstream bool
+	// PushOptions are transmitted to the server as `--push-option`.
+	PushOptions []string
+}
+
+// GitPushRefResult is the outcome of pushing a single ref.
+type GitPushRefResult struct {
+	// Local is the pushed local ref, empty when deleting.
+	Local string
+	// Remote is the updated remote ref.
+	Remote  string
+	Status  GitPushStatus
+	Summary string
+	// Reason explains a rejection, e.g "non-fast-forward" or "fetch first".
+	Reason string
+}
+
+// Push pushes `refspecs` to `remote`.
+// It returns the result of every ref, including when the push failed,
+// so that rejected refs can be told apart from refs that were pushed.
+func (git *Git) Push(remote string, refspecs []string, options *GitPushOptions) ([]*GitPushRefResult, error) {
+	args := []string{"-C", git.dir, "push", "--porcelain"}
+
+	if options != nil {
+		if options.Force {
+			args = append(args, "--force")
+		}
+
+		if options.ForceWithLease {
+			args = append(args, "--force-with-lease")
+		}
+
+		for _, lease := range options.Leases {
+			args = append(args, fmt.Sprintf("--force-with-lease=%s:%s", lease.Ref, lease.Expected))
+		}
+
+		if options.Atomic {
+			args = append(args, "--atomic")
+		}
+
+		if options.SetUpstream {
+			args = append(args, "--set-upstream")
+		}
+
+		for _, pushOption := range options.PushOptions {
+			args = append(args, fmt.Sprintf("--push-option=%s", pushOption))
+		}
+	}
+
+	args = append(args, remote)
+	args = append(args, refspecs...)
+
+	stdout, stderr, err := git.commandExecutor.Execute(git.binPath, args, git.env, "")
+
+	results := parseGitPushPorcelain(stdout)
+	if err != nil {
+		return results, newGitError(err, stdout, stderr)
+	}
+
+	return results, nil
+}
+
+// parseGitPushPorcelain parses the `git push --porcelain` output,
+// where every ref is reported as "<flag>\t<from>:<to>\t<summary> (<reason>)".
+func parseGitPushPorcelain(output []byte) []*GitPushRefResult {
+	results := make([]*GitPushRefResult, 0)
+
+	for _, line := range strings.Split(string(output), "\n") {
+		line = strings.TrimRight(line, "\r")
+
+		parts := strings.SplitN(line, "\t", 3)
+		if len(parts) < 3 {
+			continue
+		}
+
+		status, ok := gitPushStatusFlags[parts[0]]
+		if !ok {
+			continue
+		}
+
+		refs := strings.SplitN(parts[1], ":", 2)
+		if len(refs) < 2 {
+			continue
+		}
+
+		summary := parts[2]
+		reason := ""
+
+		reasonStart := strings.Index(summary, " (")
+		if reasonStart > -1 && strings.HasSuffix(summary, ")") {
+			reason = summary[reasonStart+2 : len(summary)-1]
+			summary = summary[:reasonStart]
+		}
+
+		results = append(results, &GitPushRefResult{
+			Local:   refs[0],
+			Remote:  refs[1],
+			Status:  status,
+			Summary: summary,
+			Reason:  reason,
+		})
+	}
+
+	return results
+}
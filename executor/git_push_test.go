@@ -0,0 +1,129 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sumup-oss/go-pkgs/os/ostest"
+)
+
+func TestGit_Push(t *testing.T) {
+	t.Run("when pushing succeeds, it returns the parsed result of every ref", func(t *testing.T) {
+		t.Parallel()
+
+		osExecutor := ostest.NewFakeOsExecutor(t)
+		gitInstance := NewGit(osExecutor, "", "/tmp/repo", nil)
+
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{
+				"-C", "/tmp/repo",
+				"push", "--porcelain",
+				"--force-with-lease=refs/heads/main:abc123",
+				"--atomic",
+				"--set-upstream",
+				"--push-option=ci.skip",
+				"origin",
+				"main",
+				"v1",
+				":refs/heads/old",
+			},
+			cLocaleEnvArg,
+			"",
+		).Return(
+			[]byte(
+				"To ssh://example.com/repo.git\n"+
+					"=\trefs/heads/main:refs/heads/main\t[up to date]\n"+
+					"*\trefs/tags/v1:refs/tags/v1\t[new tag]\n"+
+					"-\t:refs/heads/old\t[deleted]\n"+
+					"Done\n",
+			),
+			[]byte{},
+			nil,
+		)
+
+		actual, actualErr := gitInstance.Push(
+			"origin",
+			[]string{"main", "v1", ":refs/heads/old"},
+			&GitPushOptions{
+				Leases:      []*GitPushLease{{Ref: "refs/heads/main", Expected: "abc123"}},
+				Atomic:      true,
+				SetUpstream: true,
+				PushOptions: []string{"ci.skip"},
+			},
+		)
+		require.Nil(t, actualErr)
+
+		expected := []*GitPushRefResult{
+			{
+				Local:   "refs/heads/main",
+				Remote:  "refs/heads/main",
+				Status:  GitPushStatusUpToDate,
+				Summary: "[up to date]",
+			},
+			{
+				Local:   "refs/tags/v1",
+				Remote:  "refs/tags/v1",
+				Status:  GitPushStatusNew,
+				Summary: "[new tag]",
+			},
+			{
+				Local:   "",
+				Remote:  "refs/heads/old",
+				Status:  GitPushStatusDeleted,
+				Summary: "[deleted]",
+			},
+		}
+		assert.Equal(t, expected, actual)
+	})
+
+	t.Run("when the push is rejected, it returns error and the rejected refs with reason", func(t *testing.T) {
+		t.Parallel()
+
+		osExecutor := ostest.NewFakeOsExecutor(t)
+		gitInstance := NewGit(osExecutor, "", "/tmp/repo", nil)
+
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{"-C", "/tmp/repo", "push", "--porcelain", "--force-with-lease", "origin", "main"},
+			cLocaleEnvArg,
+			"",
+		).Return(
+			[]byte(
+				"To ssh://example.com/repo.git\n"+
+					"!\trefs/heads/main:refs/heads/main\t[rejected] (stale info)\n"+
+					"Done\n",
+			),
+			[]byte("error: failed to push some refs"),
+			errors.New("exit status 1"),
+		)
+
+		actual, actualErr := gitInstance.Push("origin", []string{"main"}, &GitPushOptions{ForceWithLease: true})
+		require.NotNil(t, actualErr)
+		assert.Contains(t, actualErr.Error(), "failed to push some refs")
+
+		require.Len(t, actual, 1)
+		assert.Equal(t, GitPushStatusRejected, actual[0].Status)
+		assert.Equal(t, "[rejected]", actual[0].Summary)
+		assert.Equal(t, "stale info", actual[0].Reason)
+	})
+}
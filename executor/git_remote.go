@@ -0,0 +1,182 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"strings"
+)
+
+const gitPeeledRefSuffix = "^{}"
+
+type GitRemote struct {
+	Name     string
+	FetchURL string
+	PushURL  string
+}
+
+// GitRef is a ref advertised by a remote.
+type GitRef struct {
+	Name string
+	Hash string
+	// PeeledHash is the hash of the commit an annotated tag points to.
+	// Empty for anything other than annotated tags.
+	PeeledHash string
+}
+
+// CommitHash returns the hash of the commit the ref points to.
+func (ref *GitRef) CommitHash() string {
+	if ref.PeeledHash != "" {
+		return ref.PeeledHash
+	}
+
+	return ref.Hash
+}
+
+func (git *Git) AddRemote(name, url string) error {
+	_, stderr, err := git.commandExecutor.Execute(
+		git.binPath,
+		[]string{"-C", git.dir, "remote", "add", name, url},
+		git.env,
+		"",
+	)
+	if err != nil {
+		return newGitError(err, nil, stderr)
+	}
+
+	return nil
+}
+
+func (git *Git) RemoveRemote(name string) error {
+	_, stderr, err := git.commandExecutor.Execute(
+		git.binPath,
+		[]string{"-C", git.dir, "remote", "remove", name},
+		git.env,
+		"",
+	)
+	if err != nil {
+		return newGitError(err, nil, stderr)
+	}
+
+	return nil
+}
+
+func (git *Git) SetRemoteURL(name, url string) error {
+	_, stderr, err := git.commandExecutor.Execute(
+		git.binPath,
+		[]string{"-C", git.dir, "remote", "set-url", name, url},
+		git.env,
+		"",
+	)
+	if err != nil {
+		return newGitError(err, nil, stderr)
+	}
+
+	return nil
+}
+
+// ListRemotes returns the configured remotes, in the order they are listed by `git remote -v`.
+func (git *Git) ListRemotes() ([]*GitRemote, error) {
+	stdout, stderr, err := git.commandExecutor.Execute(
+		git.binPath,
+		[]string{"-C", git.dir, "remote", "-v"},
+		git.env,
+		"",
+	)
+	if err != nil {
+		return nil, newGitError(err, nil, stderr)
+	}
+
+	remotes := make([]*GitRemote, 0)
+	remotesByName := make(map[string]*GitRemote)
+
+	for _, line := range splitLines(stdout) {
+		// NOTE: Every line is "<name>\t<url> (fetch|push)".
+		parts := strings.SplitN(line, "\t", 2)
+		if len(parts) < 2 {
+			continue
+		}
+
+		remote, ok := remotesByName[parts[0]]
+		if !ok {
+			remote = &GitRemote{Name: parts[0]}
+			remotesByName[parts[0]] = remote
+			remotes = append(remotes, remote)
+		}
+
+		switch {
+		case strings.HasSuffix(parts[1], " (fetch)"):
+			remote.FetchURL = strings.TrimSuffix(parts[1], " (fetch)")
+		case strings.HasSuffix(parts[1], " (push)"):
+			remote.PushURL = strings.TrimSuffix(parts[1], " (push)")
+		}
+	}
+
+	return remotes, nil
+}
+
+// LsRemote returns the refs of the remote at `url` matching `patterns`, without cloning it.
+// When `url` is empty, the url Git was created with is used.
+// When `patterns` is empty, all refs are returned.
+func (git *Git) LsRemote(url string, patterns []string) ([]*GitRef, error) {
+	if url == "" {
+		url = git.url
+	}
+
+	args := []string{"ls-remote", url}
+	args = append(args, patterns...)
+
+	// NOTE: No `-C`, since there is no need for a local repository.
+	stdout, stderr, err := git.commandExecutor.Execute(git.binPath, args, git.env, "")
+	if err != nil {
+		return nil, newGitError(err, nil, stderr)
+	}
+
+	return parseGitLsRemote(stdout), nil
+}
+
+// parseGitLsRemote parses "<hash>\t<ref>" lines.
+// The peeled "<tag>^{}" lines are folded into the tag they belong to.
+func parseGitLsRemote(output []byte) []*GitRef {
+	refs := make([]*GitRef, 0)
+	refsByName := make(map[string]*GitRef)
+
+	for _, line := range splitLines(output) {
+		parts := strings.SplitN(line, "\t", 2)
+		if len(parts) < 2 {
+			continue
+		}
+
+		hash := parts[0]
+		name := parts[1]
+
+		if strings.HasSuffix(name, gitPeeledRefSuffix) {
+			tag, ok := refsByName[strings.TrimSuffix(name, gitPeeledRefSuffix)]
+			if ok {
+				tag.PeeledHash = hash
+			}
+
+			continue
+		}
+
+		ref := &GitRef{
+			Name: name,
+			Hash: hash,
+		}
+		refsByName[name] = ref
+		refs = append(refs, ref)
+	}
+
+	return refs
+}
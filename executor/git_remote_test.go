@@ -0,0 +1,126 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sumup-oss/go-pkgs/os/ostest"
+)
+
+func TestGit_ListRemotes(t *testing.T) {
+	t.Run("it groups fetch and push urls by remote", func(t *testing.T) {
+		t.Parallel()
+
+		osExecutor := ostest.NewFakeOsExecutor(t)
+		gitInstance := NewGit(osExecutor, "", "/tmp/repo", nil)
+
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{"-C", "/tmp/repo", "remote", "-v"},
+			cLocaleEnvArg,
+			"",
+		).Return(
+			[]byte(
+				"origin\thttps://example.com/repo.git (fetch)\n"+
+					"origin\tssh://git@example.com/repo.git (push)\n"+
+					"upstream\thttps://example.com/upstream.git (fetch)\n"+
+					"upstream\thttps://example.com/upstream.git (push)\n",
+			),
+			[]byte{},
+			nil,
+		)
+
+		actual, actualErr := gitInstance.ListRemotes()
+		require.Nil(t, actualErr)
+
+		expected := []*GitRemote{
+			{
+				Name:     "origin",
+				FetchURL: "https://example.com/repo.git",
+				PushURL:  "ssh://git@example.com/repo.git",
+			},
+			{
+				Name:     "upstream",
+				FetchURL: "https://example.com/upstream.git",
+				PushURL:  "https://example.com/upstream.git",
+			},
+		}
+		assert.Equal(t, expected, actual)
+	})
+}
+
+func TestGit_LsRemote(t *testing.T) {
+	t.Run("with empty url, it queries the git url and folds peeled tags", func(t *testing.T) {
+		t.Parallel()
+
+		osExecutor := ostest.NewFakeOsExecutor(t)
+		gitInstance := NewGit(osExecutor, "https://example.com/repo.git", "/tmp/repo", nil)
+
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{"ls-remote", "https://example.com/repo.git", "refs/heads/main", "refs/tags/v1*"},
+			cLocaleEnvArg,
+			"",
+		).Return(
+			[]byte(
+				"aaa111\trefs/heads/main\n"+
+					"bbb222\trefs/tags/v1.0.0\n"+
+					"ccc333\trefs/tags/v1.0.0^{}\n"+
+					"ddd444\trefs/tags/v1.1.0\n",
+			),
+			[]byte{},
+			nil,
+		)
+
+		actual, actualErr := gitInstance.LsRemote("", []string{"refs/heads/main", "refs/tags/v1*"})
+		require.Nil(t, actualErr)
+
+		expected := []*GitRef{
+			{Name: "refs/heads/main", Hash: "aaa111"},
+			{Name: "refs/tags/v1.0.0", Hash: "bbb222", PeeledHash: "ccc333"},
+			{Name: "refs/tags/v1.1.0", Hash: "ddd444"},
+		}
+		assert.Equal(t, expected, actual)
+		assert.Equal(t, "ccc333", actual[1].CommitHash())
+		assert.Equal(t, "ddd444", actual[2].CommitHash())
+	})
+
+	t.Run("when ls-remote fails, it returns error", func(t *testing.T) {
+		t.Parallel()
+
+		osExecutor := ostest.NewFakeOsExecutor(t)
+		gitInstance := NewGit(osExecutor, "", "/tmp/repo", nil)
+
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{"ls-remote", "https://example.com/missing.git"},
+			cLocaleEnvArg,
+			"",
+		).Return([]byte{}, []byte("fatal: repository not found"), errors.New("exit status 128"))
+
+		actual, actualErr := gitInstance.LsRemote("https://example.com/missing.git", nil)
+		require.NotNil(t, actualErr)
+		assert.Nil(t, actual)
+		assert.Contains(t, actualErr.Error(), "repository not found")
+	})
+}
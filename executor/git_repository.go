@@ -0,0 +1,72 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"fmt"
+
+	"github.com/sumup-oss/go-pkgs/os"
+)
+
+type GitBackend string
+
+const (
+	// GitBackendCLI executes the `git` binary.
+	GitBackendCLI GitBackend = "cli"
+	// GitBackendGoGit uses the pure-Go go-git library and requires no `git` binary.
+	GitBackendGoGit GitBackend = "go-git"
+)
+
+// GitRepository is the set of git operations supported by every backend.
+// Use Git directly for operations only the CLI backend supports.
+type GitRepository interface {
+	GetURL() string
+	Clone() error
+	Fetch() error
+	HasDiff() (bool, error)
+	Add(file string) error
+	Commit(message string) error
+	Push(remote string, refspecs []string, options *GitPushOptions) ([]*GitPushRefResult, error)
+	ListTags() ([]string, error)
+	GetCurrentHash() (string, error)
+	GetCurrentHashForPath(path string) (string, error)
+}
+
+var _ GitRepository = (*Git)(nil)
+var _ GitRepository = (*GoGit)(nil)
+
+// NewGitRepository creates a GitRepository using `backend`.
+// `executor` is only used by GitBackendCLI. `auth` is optional.
+func NewGitRepository(
+	backend GitBackend,
+	executor os.CommandExecutor,
+	url,
+	dir string,
+	env []string,
+	auth GitAuth,
+) (GitRepository, error) {
+	switch backend {
+	case GitBackendCLI:
+		if auth == nil {
+			return NewGit(executor, url, dir, env), nil
+		}
+
+		return NewGitWithAuth(executor, url, dir, env, auth), nil
+	case GitBackendGoGit:
+		return NewGoGit(url, dir, env, auth), nil
+	default:
+		return nil, fmt.Errorf("unknown git backend %s", backend)
+	}
+}
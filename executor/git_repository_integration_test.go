@@ -0,0 +1,263 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"errors"
+	"io/ioutil"
+	stdOs "os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	"github.com/go-git/go-git/v5/plumbing/transport/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sumup-oss/go-pkgs/os"
+)
+
+var testGitRepositoryEnv = []string{
+	"GIT_AUTHOR_NAME=Test",
+	"GIT_AUTHOR_EMAIL=test@example.com",
+	"GIT_COMMITTER_NAME=Test",
+	"GIT_COMMITTER_EMAIL=test@example.com",
+}
+
+// serveGoGitFileRemotesInProcess makes go-git serve local remotes in-process until the test ends,
+// so that it does not need `git-upload-pack` and `git-receive-pack`.
+func serveGoGitFileRemotesInProcess(t *testing.T) {
+	t.Helper()
+
+	fileTransport := client.Protocols["file"]
+	client.InstallProtocol("file", server.DefaultServer)
+
+	t.Cleanup(func() {
+		client.InstallProtocol("file", fileTransport)
+	})
+}
+
+func hasGitBinary(executor os.OsExecutor) error {
+	_, _, err := executor.Execute("git", []string{"version"}, nil, "")
+	return err
+}
+
+func TestGitRepository_CLI_Integration(t *testing.T) {
+	osExecutor := &os.RealOsExecutor{}
+	if err := hasGitBinary(osExecutor); err != nil {
+		t.Skipf("No `git` binary found in $PATH. Error: %s\n", err)
+	}
+
+	testGitRepository(
+		t,
+		func(url, dir string) GitRepository {
+			repository, err := NewGitRepository(GitBackendCLI, osExecutor, url, dir, testGitRepositoryEnv, nil)
+			require.Nil(t, err)
+
+			return repository
+		},
+	)
+}
+
+func TestGitRepository_GoGit_Integration(t *testing.T) {
+	serveGoGitFileRemotesInProcess(t)
+
+	testGitRepository(
+		t,
+		func(url, dir string) GitRepository {
+			repository, err := NewGitRepository(GitBackendGoGit, nil, url, dir, testGitRepositoryEnv, nil)
+			require.Nil(t, err)
+
+			return repository
+		},
+	)
+}
+
+// testGitRepository is the test suite every GitRepository backend has to pass.
+func testGitRepository(t *testing.T, newRepository func(url, dir string) GitRepository) {
+	tmpDir, err := ioutil.TempDir("", "git-repository")
+	require.Nil(t, err)
+
+	defer stdOs.RemoveAll(tmpDir)
+
+	remoteDir := filepath.Join(tmpDir, "remote.git")
+	initialHash := seedGitRemote(t, remoteDir, filepath.Join(tmpDir, "seed"))
+
+	repository := newRepository(remoteDir, filepath.Join(tmpDir, "clone"))
+	otherRepository := newRepository(remoteDir, filepath.Join(tmpDir, "other-clone"))
+
+	require.Nil(t, repository.Clone())
+	require.Nil(t, otherRepository.Clone())
+
+	actualHash, err := repository.GetCurrentHash()
+	require.Nil(t, err)
+	assert.Equal(t, initialHash, actualHash)
+
+	actualTags, err := repository.ListTags()
+	require.Nil(t, err)
+	assert.Equal(t, []string{"v0.1.0", "v1.0.0"}, actualTags)
+
+	actualHasDiff, err := repository.HasDiff()
+	require.Nil(t, err)
+	assert.False(t, actualHasDiff)
+
+	err = ioutil.WriteFile(filepath.Join(tmpDir, "clone", "CHANGELOG.md"), []byte("# Changelog\n"), 0644)
+	require.Nil(t, err)
+
+	actualHasDiff, err = repository.HasDiff()
+	require.Nil(t, err)
+	assert.True(t, actualHasDiff)
+
+	require.Nil(t, repository.Add("CHANGELOG.md"))
+	require.Nil(t, repository.Commit("Add changelog"))
+
+	err = repository.Commit("Nothing")
+	require.NotNil(t, err)
+	assert.True(t, errors.Is(err, ErrNothingToCommit), "%s", err)
+
+	changelogHash, err := repository.GetCurrentHash()
+	require.Nil(t, err)
+	assert.NotEqual(t, initialHash, changelogHash)
+
+	actualHash, err = repository.GetCurrentHashForPath("charts")
+	require.Nil(t, err)
+	assert.Equal(t, initialHash, actualHash)
+
+	actualHash, err = repository.GetCurrentHashForPath(filepath.Join(tmpDir, "clone", "CHANGELOG.md"))
+	require.Nil(t, err)
+	assert.Equal(t, changelogHash, actualHash)
+
+	actualResults, err := repository.Push("origin", []string{"main", "main:refs/heads/release"}, nil)
+	require.Nil(t, err)
+	assert.Equal(
+		t,
+		[]*GitPushRefResult{
+			{Local: "refs/heads/main", Remote: "refs/heads/main", Status: GitPushStatusFastForward},
+			{Local: "refs/heads/main", Remote: "refs/heads/release", Status: GitPushStatusNew},
+		},
+		withoutPushSummaries(actualResults),
+	)
+
+	require.Nil(t, otherRepository.Fetch())
+
+	err = ioutil.WriteFile(filepath.Join(tmpDir, "other-clone", "README.md"), []byte("# Other\n"), 0644)
+	require.Nil(t, err)
+	require.Nil(t, otherRepository.Add("README.md"))
+	require.Nil(t, otherRepository.Commit("Update readme"))
+
+	actualResults, err = otherRepository.Push("origin", []string{"main"}, nil)
+	require.NotNil(t, err)
+	assert.True(t, errors.Is(err, ErrNonFastForward), "%s", err)
+	require.Len(t, actualResults, 1)
+	assert.Equal(t, GitPushStatusRejected, actualResults[0].Status)
+	assert.Equal(t, "non-fast-forward", actualResults[0].Reason)
+
+	actualResults, err = otherRepository.Push("origin", []string{"main:refs/heads/other"}, nil)
+	require.Nil(t, err)
+	require.Len(t, actualResults, 1)
+	assert.Equal(t, GitPushStatusNew, actualResults[0].Status)
+
+	actualResults, err = otherRepository.Push("origin", []string{"main"}, &GitPushOptions{Force: true})
+	require.Nil(t, err)
+	require.Len(t, actualResults, 1)
+	assert.Equal(t, GitPushStatusForced, actualResults[0].Status)
+
+	otherClone, err := gogit.PlainOpen(filepath.Join(tmpDir, "other-clone"))
+	require.Nil(t, err)
+	require.Nil(t, otherClone.DeleteRemote("origin"))
+
+	actualResults, err = otherRepository.Push(remoteDir, []string{"main:refs/heads/url"}, nil)
+	require.Nil(t, err)
+	require.Len(t, actualResults, 1)
+	assert.Equal(t, GitPushStatusNew, actualResults[0].Status)
+}
+
+// seedGitRemote creates the bare repository `remoteDir` with an initial commit on `main`,
+// a lightweight tag `v0.1.0` and an annotated tag `v1.0.0`. It returns the hash of the commit.
+func seedGitRemote(t *testing.T, remoteDir, seedDir string) string {
+	t.Helper()
+
+	initOptions := &gogit.PlainInitOptions{
+		InitOptions: gogit.InitOptions{DefaultBranch: plumbing.Main},
+		Bare:        true,
+	}
+
+	_, err := gogit.PlainInitWithOptions(remoteDir, initOptions)
+	require.Nil(t, err)
+
+	initOptions.Bare = false
+
+	seed, err := gogit.PlainInitWithOptions(seedDir, initOptions)
+	require.Nil(t, err)
+
+	err = stdOs.MkdirAll(filepath.Join(seedDir, "charts", "app"), 0755)
+	require.Nil(t, err)
+
+	for path, content := range map[string]string{
+		"README.md":                  "# Repository\n",
+		"charts/app/values.yaml":     "replicas: 1\n",
+		"charts/app/templates/.keep": "",
+	} {
+		err = stdOs.MkdirAll(filepath.Dir(filepath.Join(seedDir, path)), 0755)
+		require.Nil(t, err)
+
+		err = ioutil.WriteFile(filepath.Join(seedDir, path), []byte(content), 0644)
+		require.Nil(t, err)
+	}
+
+	worktree, err := seed.Worktree()
+	require.Nil(t, err)
+
+	_, err = worktree.Add(".")
+	require.Nil(t, err)
+
+	signature := &object.Signature{Name: "Seed", Email: "seed@example.com", When: time.Now()}
+
+	hash, err := worktree.Commit("Initial commit", &gogit.CommitOptions{Author: signature})
+	require.Nil(t, err)
+
+	_, err = seed.CreateTag("v0.1.0", hash, nil)
+	require.Nil(t, err)
+
+	_, err = seed.CreateTag("v1.0.0", hash, &gogit.CreateTagOptions{Tagger: signature, Message: "Release"})
+	require.Nil(t, err)
+
+	_, err = seed.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{remoteDir}})
+	require.Nil(t, err)
+
+	err = seed.Push(
+		&gogit.PushOptions{
+			RemoteName: "origin",
+			RefSpecs:   []config.RefSpec{"refs/heads/*:refs/heads/*", "refs/tags/*:refs/tags/*"},
+		},
+	)
+	require.Nil(t, err)
+
+	return hash.String()
+}
+
+func withoutPushSummaries(results []*GitPushRefResult) []*GitPushRefResult {
+	for _, result := range results {
+		result.Summary = ""
+		result.Reason = ""
+	}
+
+	return results
+}
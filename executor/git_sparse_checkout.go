@@ -0,0 +1,349 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"fmt"
+	"io/ioutil"
+	stdOs "os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sumup-oss/go-pkgs/os"
+)
+
+const sparseCheckoutFilePerm = 0644
+
+// SparseCheckoutInit enables sparse checkout of the repository.
+// When `cone` is true, the restricted cone mode patterns are used,
+// where every path is a directory that is checked out recursively.
+//
+// NOTE: git versions older than 2.25 have no `sparse-checkout` command.
+// For them `core.sparseCheckout` is enabled directly, the initial patterns of `git sparse-checkout init` are written
+// and `core.sparseCheckoutCone` is recorded, so that later calls know which pattern format to write.
+func (git *Git) SparseCheckoutInit(cone bool) error {
+	args := []string{"-C", git.dir, "sparse-checkout", "init"}
+	if cone {
+		args = append(args, "--cone")
+	}
+
+	_, stderr, err := git.commandExecutor.Execute(git.binPath, args, git.env, "")
+	if err == nil {
+		return nil
+	}
+
+	if !isUnknownGitCommand(stderr) {
+		return newGitError(err, nil, stderr)
+	}
+
+	err = git.setConfig("core.sparseCheckoutCone", fmt.Sprintf("%t", cone))
+	if err != nil {
+		return err
+	}
+
+	err = git.EnableSparseCheckout()
+	if err != nil {
+		return err
+	}
+
+	// NOTE: In both modes, `git sparse-checkout init` starts with only the top-level files checked out.
+	return git.writeSparseCheckoutPatterns(sparseCheckoutConePatterns(nil))
+}
+
+// SparseCheckoutSet replaces the sparse checkout paths and updates the working directory to match them.
+func (git *Git) SparseCheckoutSet(paths []string) error {
+	args := []string{"-C", git.dir, "sparse-checkout", "set"}
+	args = append(args, paths...)
+
+	_, stderr, err := git.commandExecutor.Execute(git.binPath, args, git.env, "")
+	if err == nil {
+		return nil
+	}
+
+	if !isUnknownGitCommand(stderr) {
+		return newGitError(err, nil, stderr)
+	}
+
+	return git.setSparseCheckoutPathsLegacy(paths)
+}
+
+// SparseCheckoutAdd adds paths to the existing sparse checkout paths
+// and updates the working directory to match them.
+func (git *Git) SparseCheckoutAdd(paths []string) error {
+	args := []string{"-C", git.dir, "sparse-checkout", "add"}
+	args = append(args, paths...)
+
+	_, stderr, err := git.commandExecutor.Execute(git.binPath, args, git.env, "")
+	if err == nil {
+		return nil
+	}
+
+	if !isUnknownGitCommand(stderr) {
+		return newGitError(err, nil, stderr)
+	}
+
+	existingPaths, err := git.listSparseCheckoutPathsLegacy()
+	if err != nil {
+		return err
+	}
+
+	return git.setSparseCheckoutPathsLegacy(append(existingPaths, paths...))
+}
+
+// SparseCheckoutList returns the sparse checkout paths.
+// In cone mode these are the directories that are checked out recursively,
+// otherwise these are the raw sparse checkout patterns.
+func (git *Git) SparseCheckoutList() ([]string, error) {
+	stdout, stderr, err := git.commandExecutor.Execute(
+		git.binPath,
+		[]string{"-C", git.dir, "sparse-checkout", "list"},
+		git.env,
+		"",
+	)
+	if err == nil {
+		return splitLines(stdout), nil
+	}
+
+	if !isUnknownGitCommand(stderr) {
+		return nil, newGitError(err, nil, stderr)
+	}
+
+	return git.listSparseCheckoutPathsLegacy()
+}
+
+func (git *Git) setSparseCheckoutPathsLegacy(paths []string) error {
+	cone, err := git.isSparseCheckoutCone()
+	if err != nil {
+		return err
+	}
+
+	patterns := paths
+	if cone {
+		patterns = sparseCheckoutConePatterns(paths)
+	}
+
+	err = git.writeSparseCheckoutPatterns(patterns)
+	if err != nil {
+		return err
+	}
+
+	_, stderr, err := git.commandExecutor.Execute(
+		git.binPath,
+		[]string{"-C", git.dir, "read-tree", "-mu", "HEAD"},
+		git.env,
+		"",
+	)
+	if err != nil {
+		return newGitError(err, nil, stderr)
+	}
+
+	return nil
+}
+
+func (git *Git) listSparseCheckoutPathsLegacy() ([]string, error) {
+	fileReadWriter := git.fileReadWriter()
+
+	sparseCheckoutFilePath, err := git.sparseCheckoutFilePath()
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := fileReadWriter.ReadFile(sparseCheckoutFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading sparse checkout config file. Error: %s", err.Error())
+	}
+
+	patterns := splitLines(content)
+
+	cone, err := git.isSparseCheckoutCone()
+	if err != nil {
+		return nil, err
+	}
+
+	if !cone {
+		return patterns, nil
+	}
+
+	return sparseCheckoutConePaths(patterns), nil
+}
+
+func (git *Git) writeSparseCheckoutPatterns(patterns []string) error {
+	fileReadWriter := git.fileReadWriter()
+
+	sparseCheckoutFilePath, err := git.sparseCheckoutFilePath()
+	if err != nil {
+		return err
+	}
+
+	content := strings.Join(patterns, "\n") + "\n"
+
+	err = fileReadWriter.WriteFile(sparseCheckoutFilePath, []byte(content), sparseCheckoutFilePerm)
+	if err != nil {
+		return fmt.Errorf("error writing to sparse checkout config file. Error: %s", err.Error())
+	}
+
+	return nil
+}
+
+// sparseCheckoutFilePath resolves the sparse checkout config file through git,
+// since the git directory is not always `.git` inside the working directory, e.g for worktrees.
+func (git *Git) sparseCheckoutFilePath() (string, error) {
+	stdout, stderr, err := git.commandExecutor.Execute(
+		git.binPath,
+		[]string{"-C", git.dir, "rev-parse", "--git-path", "info/sparse-checkout"},
+		git.env,
+		"",
+	)
+	if err != nil {
+		return "", newGitError(err, nil, stderr)
+	}
+
+	sparseCheckoutFilePath := strings.Trim(string(stdout), "\n\r ")
+	if filepath.IsAbs(sparseCheckoutFilePath) {
+		return sparseCheckoutFilePath, nil
+	}
+
+	return filepath.Join(git.dir, sparseCheckoutFilePath), nil
+}
+
+func (git *Git) isSparseCheckoutCone() (bool, error) {
+	stdout, stderr, err := git.commandExecutor.Execute(
+		git.binPath,
+		[]string{"-C", git.dir, "config", "--bool", "core.sparseCheckoutCone"},
+		git.env,
+		"",
+	)
+	if err != nil {
+		// NOTE: `git config` exits with 1 when the key is not set, without writing to stderr.
+		if len(stderr) == 0 {
+			return false, nil
+		}
+
+		return false, newGitError(err, nil, stderr)
+	}
+
+	return strings.Trim(string(stdout), "\n\r ") == "true", nil
+}
+
+func (git *Git) setConfig(key, value string) error {
+	_, stderr, err := git.commandExecutor.Execute(
+		git.binPath,
+		[]string{"-C", git.dir, "config", key, value},
+		git.env,
+		"",
+	)
+	if err != nil {
+		return newGitError(err, nil, stderr)
+	}
+
+	return nil
+}
+
+// fileReadWriter returns the command executor when it supports reading and writing files,
+// otherwise the standard library, like SetSparseCheckoutPaths.
+func (git *Git) fileReadWriter() os.FileReadWriter {
+	fileReadWriter, ok := git.commandExecutor.(os.FileReadWriter)
+	if !ok {
+		return stdFileReadWriter{}
+	}
+
+	return fileReadWriter
+}
+
+type stdFileReadWriter struct{}
+
+func (stdFileReadWriter) ReadFile(filename string) ([]byte, error) {
+	return ioutil.ReadFile(filename)
+}
+
+func (stdFileReadWriter) WriteFile(path string, data []byte, perm stdOs.FileMode) error {
+	return ioutil.WriteFile(path, data, perm)
+}
+
+// sparseCheckoutConePatterns converts directories to the patterns `git sparse-checkout` writes in cone mode.
+// Top-level files are always included, as well as the files, but not the subdirectories, of every parent.
+func sparseCheckoutConePatterns(dirs []string) []string {
+	patterns := []string{"/*", "!/*/"}
+	seenParents := make(map[string]bool)
+
+	for _, dir := range dirs {
+		dir = strings.Trim(filepath.ToSlash(dir), "/")
+		if dir == "" {
+			continue
+		}
+
+		parts := strings.Split(dir, "/")
+		for i := 1; i < len(parts); i++ {
+			parent := "/" + strings.Join(parts[:i], "/") + "/"
+			if seenParents[parent] {
+				continue
+			}
+
+			seenParents[parent] = true
+			patterns = append(patterns, parent, "!"+parent+"*/")
+		}
+
+		patterns = append(patterns, "/"+dir+"/")
+	}
+
+	return patterns
+}
+
+// sparseCheckoutConePaths is the reverse of sparseCheckoutConePatterns.
+// It returns only the recursively included directories.
+func sparseCheckoutConePaths(patterns []string) []string {
+	excludedChildren := make(map[string]bool)
+
+	for _, pattern := range patterns {
+		if strings.HasPrefix(pattern, "!") && strings.HasSuffix(pattern, "/*/") {
+			excludedChildren[strings.TrimSuffix(strings.TrimPrefix(pattern, "!"), "*/")] = true
+		}
+	}
+
+	paths := make([]string, 0)
+
+	for _, pattern := range patterns {
+		if pattern == "/*" || strings.HasPrefix(pattern, "!") || !strings.HasSuffix(pattern, "/") {
+			continue
+		}
+
+		if excludedChildren[pattern] {
+			continue
+		}
+
+		paths = append(paths, strings.Trim(pattern, "/"))
+	}
+
+	return paths
+}
+
+func isUnknownGitCommand(stderr []byte) bool {
+	return strings.Contains(string(stderr), "is not a git command")
+}
+
+func splitLines(output []byte) []string {
+	lines := make([]string, 0)
+
+	for _, line := range strings.Split(string(output), "\n") {
+		line = strings.Trim(line, "\r ")
+		if line == "" {
+			continue
+		}
+
+		lines = append(lines, line)
+	}
+
+	return lines
+}
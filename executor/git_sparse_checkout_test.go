@@ -0,0 +1,309 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"errors"
+	stdOs "os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sumup-oss/go-pkgs/os"
+	"github.com/sumup-oss/go-pkgs/os/ostest"
+)
+
+var fakeUnknownSparseCheckoutStderr = []byte(
+	"git: 'sparse-checkout' is not a git command. See 'git --help'.",
+)
+
+func TestGit_SparseCheckoutInit(t *testing.T) {
+	t.Run("when `git sparse-checkout` is supported, it runs `sparse-checkout init --cone`", func(t *testing.T) {
+		t.Parallel()
+
+		osExecutor := ostest.NewFakeOsExecutor(t)
+		gitInstance := NewGit(osExecutor, "", "/tmp/repo", nil)
+
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{"-C", "/tmp/repo", "sparse-checkout", "init", "--cone"},
+			cLocaleEnvArg,
+			"",
+		).Return([]byte{}, []byte{}, nil)
+
+		actualErr := gitInstance.SparseCheckoutInit(true)
+		require.Nil(t, actualErr)
+
+		osExecutor.AssertExpectations(t)
+	})
+
+	t.Run(
+		"when `git sparse-checkout` is not supported and cone is requested, "+
+			"it enables sparse checkout and writes the cone top-level patterns through the executor",
+		func(t *testing.T) {
+			t.Parallel()
+
+			osExecutor := ostest.NewFakeOsExecutor(t)
+			gitInstance := NewGit(osExecutor, "", "/tmp/repo", nil)
+
+			osExecutor.On(
+				"Execute",
+				"git",
+				[]string{"-C", "/tmp/repo", "sparse-checkout", "init", "--cone"},
+				cLocaleEnvArg,
+				"",
+			).Return([]byte{}, fakeUnknownSparseCheckoutStderr, errors.New("exit status 1"))
+			osExecutor.On(
+				"Execute",
+				"git",
+				[]string{"-C", "/tmp/repo", "config", "core.sparseCheckoutCone", "true"},
+				cLocaleEnvArg,
+				"",
+			).Return([]byte{}, []byte{}, nil)
+			osExecutor.On(
+				"Execute",
+				"git",
+				[]string{"-C", "/tmp/repo", "config", "core.sparseCheckout", "true"},
+				cLocaleEnvArg,
+				"",
+			).Return([]byte{}, []byte{}, nil)
+			osExecutor.On(
+				"Execute",
+				"git",
+				[]string{"-C", "/tmp/repo", "rev-parse", "--git-path", "info/sparse-checkout"},
+				cLocaleEnvArg,
+				"",
+			).Return([]byte(".git/info/sparse-checkout\n"), []byte{}, nil)
+			osExecutor.On(
+				"WriteFile",
+				"/tmp/repo/.git/info/sparse-checkout",
+				[]byte("/*\n!/*/\n"),
+				stdOs.FileMode(0644),
+			).Return(nil)
+
+			actualErr := gitInstance.SparseCheckoutInit(true)
+			require.Nil(t, actualErr)
+
+			osExecutor.AssertExpectations(t)
+		},
+	)
+
+	t.Run(
+		"when `git sparse-checkout` is not supported and the command executor cannot read and write files, "+
+			"it writes the initial patterns with the standard library",
+		func(t *testing.T) {
+			t.Parallel()
+
+			repoDir := t.TempDir()
+			require.Nil(t, stdOs.MkdirAll(filepath.Join(repoDir, ".git", "info"), 0755))
+
+			osExecutor := ostest.NewFakeOsExecutor(t)
+			osExecutor.On(
+				"Execute",
+				"git",
+				[]string{"-C", repoDir, "sparse-checkout", "init"},
+				cLocaleEnvArg,
+				"",
+			).Return([]byte{}, fakeUnknownSparseCheckoutStderr, errors.New("exit status 1"))
+			osExecutor.On(
+				"Execute",
+				"git",
+				[]string{"-C", repoDir, "config", "core.sparseCheckoutCone", "false"},
+				cLocaleEnvArg,
+				"",
+			).Return([]byte{}, []byte{}, nil)
+			osExecutor.On(
+				"Execute",
+				"git",
+				[]string{"-C", repoDir, "config", "core.sparseCheckout", "true"},
+				cLocaleEnvArg,
+				"",
+			).Return([]byte{}, []byte{}, nil)
+			osExecutor.On(
+				"Execute",
+				"git",
+				[]string{"-C", repoDir, "rev-parse", "--git-path", "info/sparse-checkout"},
+				cLocaleEnvArg,
+				"",
+			).Return([]byte(".git/info/sparse-checkout\n"), []byte{}, nil)
+
+			commandExecutor := struct{ os.CommandExecutor }{osExecutor}
+			gitInstance := NewGit(commandExecutor, "", repoDir, nil)
+
+			actualErr := gitInstance.SparseCheckoutInit(false)
+			require.Nil(t, actualErr)
+
+			actual, err := stdOs.ReadFile(filepath.Join(repoDir, ".git", "info", "sparse-checkout"))
+			require.Nil(t, err)
+			assert.Equal(t, "/*\n!/*/\n", string(actual))
+		},
+	)
+
+	t.Run("when `git sparse-checkout init` fails for other reasons, it returns error", func(t *testing.T) {
+		t.Parallel()
+
+		osExecutor := ostest.NewFakeOsExecutor(t)
+		gitInstance := NewGit(osExecutor, "", "/tmp/repo", nil)
+
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{"-C", "/tmp/repo", "sparse-checkout", "init"},
+			cLocaleEnvArg,
+			"",
+		).Return([]byte{}, []byte("fatal: not a git repository"), errors.New("exit status 128"))
+
+		actualErr := gitInstance.SparseCheckoutInit(false)
+		require.NotNil(t, actualErr)
+		assert.Contains(t, actualErr.Error(), "not a git repository")
+	})
+}
+
+func TestGit_SparseCheckoutSet(t *testing.T) {
+	t.Run("when `git sparse-checkout` is supported, it runs `sparse-checkout set`", func(t *testing.T) {
+		t.Parallel()
+
+		osExecutor := ostest.NewFakeOsExecutor(t)
+		gitInstance := NewGit(osExecutor, "", "/tmp/repo", nil)
+
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{"-C", "/tmp/repo", "sparse-checkout", "set", "services/api", "libs"},
+			cLocaleEnvArg,
+			"",
+		).Return([]byte{}, []byte{}, nil)
+
+		actualErr := gitInstance.SparseCheckoutSet([]string{"services/api", "libs"})
+		require.Nil(t, actualErr)
+
+		osExecutor.AssertExpectations(t)
+	})
+
+	t.Run(
+		"when `git sparse-checkout` is not supported, "+
+			"it writes the cone patterns through the executor and updates the working directory",
+		func(t *testing.T) {
+			t.Parallel()
+
+			osExecutor := ostest.NewFakeOsExecutor(t)
+			gitInstance := NewGit(osExecutor, "", "/tmp/repo", nil)
+
+			osExecutor.On(
+				"Execute",
+				"git",
+				[]string{"-C", "/tmp/repo", "sparse-checkout", "set", "services/api"},
+				cLocaleEnvArg,
+				"",
+			).Return([]byte{}, fakeUnknownSparseCheckoutStderr, errors.New("exit status 1"))
+			osExecutor.On(
+				"Execute",
+				"git",
+				[]string{"-C", "/tmp/repo", "config", "--bool", "core.sparseCheckoutCone"},
+				cLocaleEnvArg,
+				"",
+			).Return([]byte("true\n"), []byte{}, nil)
+			osExecutor.On(
+				"Execute",
+				"git",
+				[]string{"-C", "/tmp/repo", "rev-parse", "--git-path", "info/sparse-checkout"},
+				cLocaleEnvArg,
+				"",
+			).Return([]byte(".git/info/sparse-checkout\n"), []byte{}, nil)
+			osExecutor.On(
+				"WriteFile",
+				"/tmp/repo/.git/info/sparse-checkout",
+				[]byte("/*\n!/*/\n/services/\n!/services/*/\n/services/api/\n"),
+				stdOs.FileMode(0644),
+			).Return(nil)
+			osExecutor.On(
+				"Execute",
+				"git",
+				[]string{"-C", "/tmp/repo", "read-tree", "-mu", "HEAD"},
+				cLocaleEnvArg,
+				"",
+			).Return([]byte{}, []byte{}, nil)
+
+			actualErr := gitInstance.SparseCheckoutSet([]string{"services/api"})
+			require.Nil(t, actualErr)
+
+			osExecutor.AssertExpectations(t)
+		},
+	)
+}
+
+func TestGit_SparseCheckoutList(t *testing.T) {
+	t.Run("when `git sparse-checkout` is supported, it returns the listed paths", func(t *testing.T) {
+		t.Parallel()
+
+		osExecutor := ostest.NewFakeOsExecutor(t)
+		gitInstance := NewGit(osExecutor, "", "/tmp/repo", nil)
+
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{"-C", "/tmp/repo", "sparse-checkout", "list"},
+			cLocaleEnvArg,
+			"",
+		).Return([]byte("libs\nservices/api\n"), []byte{}, nil)
+
+		actual, actualErr := gitInstance.SparseCheckoutList()
+		require.Nil(t, actualErr)
+		assert.Equal(t, []string{"libs", "services/api"}, actual)
+	})
+}
+
+func TestGit_SetSparseCheckoutPaths(t *testing.T) {
+	t.Run("it writes the raw patterns to the sparse checkout config file without running git", func(t *testing.T) {
+		t.Parallel()
+
+		repoDir := t.TempDir()
+		require.Nil(t, stdOs.MkdirAll(filepath.Join(repoDir, ".git", "info"), 0755))
+
+		osExecutor := ostest.NewFakeOsExecutor(t)
+		gitInstance := NewGit(osExecutor, "", repoDir, nil)
+
+		actualErr := gitInstance.SetSparseCheckoutPaths([]string{"services/api/", "libs/"})
+		require.Nil(t, actualErr)
+
+		actual, err := stdOs.ReadFile(filepath.Join(repoDir, ".git", "info", "sparse-checkout"))
+		require.Nil(t, err)
+		assert.Equal(t, "services/api/\nlibs/", string(actual))
+	})
+}
+
+func TestSparseCheckoutConePatterns(t *testing.T) {
+	t.Run("it includes every parent without its subdirectories and is reversible", func(t *testing.T) {
+		t.Parallel()
+
+		actual := sparseCheckoutConePatterns([]string{"a/b/c", "a/d", "e/"})
+		expected := []string{
+			"/*",
+			"!/*/",
+			"/a/",
+			"!/a/*/",
+			"/a/b/",
+			"!/a/b/*/",
+			"/a/b/c/",
+			"/a/d/",
+			"/e/",
+		}
+		assert.Equal(t, expected, actual)
+		assert.Equal(t, []string{"a/b/c", "a/d", "e"}, sparseCheckoutConePaths(actual))
+	})
+}
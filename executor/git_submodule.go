@@ -0,0 +1,189 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"fmt"
+	"strings"
+)
+
+type GitSubmoduleState string
+
+const (
+	GitSubmoduleStateUpToDate       GitSubmoduleState = "up-to-date"
+	GitSubmoduleStateNotInitialized GitSubmoduleState = "not-initialized"
+	GitSubmoduleStateModified       GitSubmoduleState = "modified"
+	GitSubmoduleStateConflict       GitSubmoduleState = "conflict"
+)
+
+var gitSubmoduleStatePrefixes = map[byte]GitSubmoduleState{
+	' ': GitSubmoduleStateUpToDate,
+	'-': GitSubmoduleStateNotInitialized,
+	'+': GitSubmoduleStateModified,
+	'U': GitSubmoduleStateConflict,
+}
+
+type GitSubmoduleUpdateOptions struct {
+	// Init initializes the submodules that are not initialized yet.
+	Init      bool
+	Recursive bool
+	// Remote updates to the latest commit of the submodule's remote tracking branch,
+	// instead of the commit recorded in the superproject.
+	Remote bool
+	// Depth creates shallow clones with history truncated to the given number of commits, when positive.
+	Depth int
+	// Paths limits the update to the given submodules.
+	Paths []string
+}
+
+// GitSubmodule is a submodule as reported by `git submodule status`.
+type GitSubmodule struct {
+	Path string
+	// Hash is the commit checked out in the submodule,
+	// or the commit recorded in the superproject when not initialized.
+	Hash  string
+	State GitSubmoduleState
+	// Describe is the `git describe` output for Hash, empty when not initialized.
+	Describe string
+}
+
+// SubmoduleInit registers the submodules in `paths`, or all submodules when empty, in the repository config.
+func (git *Git) SubmoduleInit(paths []string) error {
+	args := []string{"-C", git.dir, "submodule", "init"}
+	if len(paths) > 0 {
+		args = append(args, "--")
+		args = append(args, paths...)
+	}
+
+	_, stderr, err := git.commandExecutor.Execute(git.binPath, args, git.env, "")
+	if err != nil {
+		return newGitError(err, nil, stderr)
+	}
+
+	return nil
+}
+
+// SubmoduleUpdate clones missing submodules and checks out the expected commits.
+// Submodules are fetched with the env of Git, so they use the same authentication.
+func (git *Git) SubmoduleUpdate(options *GitSubmoduleUpdateOptions) error {
+	args := []string{"-C", git.dir, "submodule", "update"}
+
+	paths := make([]string, 0)
+
+	if options != nil {
+		if options.Init {
+			args = append(args, "--init")
+		}
+
+		if options.Recursive {
+			args = append(args, "--recursive")
+		}
+
+		if options.Remote {
+			args = append(args, "--remote")
+		}
+
+		if options.Depth > 0 {
+			args = append(args, "--depth", fmt.Sprintf("%d", options.Depth))
+		}
+
+		paths = options.Paths
+	}
+
+	if len(paths) > 0 {
+		args = append(args, "--")
+		args = append(args, paths...)
+	}
+
+	stdout, stderr, err := git.commandExecutor.Execute(git.binPath, args, git.env, "")
+	if err != nil {
+		return newGitError(err, stdout, stderr)
+	}
+
+	return nil
+}
+
+// SubmoduleStatus returns the submodules and whether they're checked out at the recorded commits.
+func (git *Git) SubmoduleStatus(recursive bool) ([]*GitSubmodule, error) {
+	args := []string{"-C", git.dir, "submodule", "status"}
+	if recursive {
+		args = append(args, "--recursive")
+	}
+
+	stdout, stderr, err := git.commandExecutor.Execute(git.binPath, args, git.env, "")
+	if err != nil {
+		return nil, newGitError(err, nil, stderr)
+	}
+
+	submodules := make([]*GitSubmodule, 0)
+
+	for _, line := range strings.Split(string(stdout), "\n") {
+		// NOTE: Every line is "<state><hash> <path>[ (<describe>)]".
+		line = strings.TrimRight(line, "\r")
+		if len(line) < 2 {
+			continue
+		}
+
+		state, ok := gitSubmoduleStatePrefixes[line[0]]
+		if !ok {
+			continue
+		}
+
+		parts := strings.SplitN(line[1:], " ", 2)
+		if len(parts) < 2 {
+			continue
+		}
+
+		submodule := &GitSubmodule{
+			Hash:  parts[0],
+			Path:  parts[1],
+			State: state,
+		}
+
+		describeStart := strings.LastIndex(parts[1], " (")
+		if describeStart > -1 && strings.HasSuffix(parts[1], ")") {
+			submodule.Path = parts[1][:describeStart]
+			submodule.Describe = parts[1][describeStart+2 : len(parts[1])-1]
+		}
+
+		submodules = append(submodules, submodule)
+	}
+
+	return submodules, nil
+}
+
+// SubmoduleForeach runs the shell `command` in every checked out submodule and returns its output.
+// The command has access to `$name`, `$sm_path`, `$displaypath`, `$sha1` and `$toplevel`.
+func (git *Git) SubmoduleForeach(command string, recursive bool) (string, error) {
+	args := []string{"-C", git.dir, "submodule", "foreach"}
+	if recursive {
+		args = append(args, "--recursive")
+	}
+
+	args = append(args, command)
+
+	stdout, stderr, err := git.commandExecutor.Execute(git.binPath, args, git.env, "")
+	if err != nil {
+		return "", newGitError(err, stdout, stderr)
+	}
+
+	return string(stdout), nil
+}
+
+// Submodule returns a Git instance bound to the submodule in `path`,
+// sharing the executor and env, so that existing methods can be used on the submodule.
+func (git *Git) Submodule(path string) *Git {
+	return git.withDir(path)
+}
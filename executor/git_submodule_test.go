@@ -0,0 +1,154 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sumup-oss/go-pkgs/os/ostest"
+)
+
+func TestGit_SubmoduleUpdate(t *testing.T) {
+	t.Run("it passes the options and the env of git", func(t *testing.T) {
+		t.Parallel()
+
+		osExecutor := ostest.NewFakeOsExecutor(t)
+		envArg := []string{"GIT_SSH_COMMAND=ssh -i /tmp/id_rsa"}
+		gitInstance := NewGit(osExecutor, "", "/tmp/repo", envArg)
+
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{
+				"-C", "/tmp/repo", "submodule", "update", "--init", "--recursive", "--remote", "--depth", "1",
+				"--", "charts/common",
+			},
+			[]string{"GIT_SSH_COMMAND=ssh -i /tmp/id_rsa", "LC_ALL=C"},
+			"",
+		).Return([]byte{}, []byte{}, nil)
+
+		actualErr := gitInstance.SubmoduleUpdate(
+			&GitSubmoduleUpdateOptions{
+				Init:      true,
+				Recursive: true,
+				Remote:    true,
+				Depth:     1,
+				Paths:     []string{"charts/common"},
+			},
+		)
+		require.Nil(t, actualErr)
+	})
+
+	t.Run("when updating fails, it returns typed error", func(t *testing.T) {
+		t.Parallel()
+
+		osExecutor := ostest.NewFakeOsExecutor(t)
+		gitInstance := NewGit(osExecutor, "", "/tmp/repo", nil)
+
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{"-C", "/tmp/repo", "submodule", "update"},
+			cLocaleEnvArg,
+			"",
+		).Return(
+			[]byte{},
+			[]byte("remote: Repository not found.\nfatal: clone of 'https://example.com/common.git' failed"),
+			errors.New("exit status 1"),
+		)
+
+		actualErr := gitInstance.SubmoduleUpdate(nil)
+		require.NotNil(t, actualErr)
+		assert.True(t, errors.Is(actualErr, ErrRemoteNotFound))
+	})
+}
+
+func TestGit_SubmoduleStatus(t *testing.T) {
+	t.Run("it parses the state, hash, path and describe of submodules", func(t *testing.T) {
+		t.Parallel()
+
+		osExecutor := ostest.NewFakeOsExecutor(t)
+		gitInstance := NewGit(osExecutor, "", "/tmp/repo", nil)
+
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{"-C", "/tmp/repo", "submodule", "status", "--recursive"},
+			cLocaleEnvArg,
+			"",
+		).Return(
+			[]byte(
+				" abc123 charts/common (v1.0.0)\n"+
+					"-def456 charts/legacy\n"+
+					"+fed789 vendor/lib (heads/main)\n"+
+					"U000000 vendor/conflicted\n",
+			),
+			[]byte{},
+			nil,
+		)
+
+		actual, actualErr := gitInstance.SubmoduleStatus(true)
+		require.Nil(t, actualErr)
+		assert.Equal(
+			t,
+			[]*GitSubmodule{
+				{Path: "charts/common", Hash: "abc123", State: GitSubmoduleStateUpToDate, Describe: "v1.0.0"},
+				{Path: "charts/legacy", Hash: "def456", State: GitSubmoduleStateNotInitialized},
+				{Path: "vendor/lib", Hash: "fed789", State: GitSubmoduleStateModified, Describe: "heads/main"},
+				{Path: "vendor/conflicted", Hash: "000000", State: GitSubmoduleStateConflict},
+			},
+			actual,
+		)
+	})
+}
+
+func TestGit_SubmoduleForeach(t *testing.T) {
+	t.Run("it returns the output of the command", func(t *testing.T) {
+		t.Parallel()
+
+		osExecutor := ostest.NewFakeOsExecutor(t)
+		gitInstance := NewGit(osExecutor, "", "/tmp/repo", nil)
+
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{"-C", "/tmp/repo", "submodule", "foreach", "--recursive", "git clean -fdx"},
+			cLocaleEnvArg,
+			"",
+		).Return([]byte("Entering 'charts/common'\n"), []byte{}, nil)
+
+		actual, actualErr := gitInstance.SubmoduleForeach("git clean -fdx", true)
+		require.Nil(t, actualErr)
+		assert.Equal(t, "Entering 'charts/common'\n", actual)
+	})
+}
+
+func TestGit_Submodule(t *testing.T) {
+	t.Run("it returns git bound to the submodule dir with the same env", func(t *testing.T) {
+		t.Parallel()
+
+		osExecutor := ostest.NewFakeOsExecutor(t)
+		envArg := []string{"GIT_TERMINAL_PROMPT=0"}
+		gitInstance := NewGit(osExecutor, "", "/tmp/repo", envArg)
+
+		actual := gitInstance.Submodule("charts/common")
+		assert.Equal(t, "/tmp/repo/charts/common", actual.dir)
+		assert.Equal(t, []string{"GIT_TERMINAL_PROMPT=0", "LC_ALL=C"}, actual.env)
+	})
+}
@@ -0,0 +1,176 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"strings"
+)
+
+type GitWorktreeOptions struct {
+	// Commitish is checked out in the worktree. Defaults to HEAD.
+	Commitish string
+	// Branch is created at Commitish and checked out in the worktree.
+	Branch string
+	// Detach checks out Commitish with detached HEAD, instead of a branch.
+	Detach bool
+	// Force allows checking out a branch that is already checked out in another worktree.
+	Force bool
+}
+
+// GitWorktree is a working directory attached to the repository.
+type GitWorktree struct {
+	Path string
+	Head string
+	// Branch is the full ref name of the checked out branch, empty when detached or bare.
+	Branch   string
+	Bare     bool
+	Detached bool
+	Locked   bool
+	Prunable bool
+	// Git is bound to the worktree directory, sharing the executor and env of the repository's Git.
+	Git *Git
+}
+
+// AddWorktree creates a worktree in `dir` and returns a Git instance bound to it.
+// A relative `dir` is relative to the repository directory.
+func (git *Git) AddWorktree(dir string, options *GitWorktreeOptions) (*Git, error) {
+	args := []string{"-C", git.dir, "worktree", "add"}
+
+	commitish := ""
+
+	if options != nil {
+		if options.Force {
+			args = append(args, "--force")
+		}
+
+		if options.Detach {
+			args = append(args, "--detach")
+		}
+
+		if options.Branch != "" {
+			args = append(args, "-b", options.Branch)
+		}
+
+		commitish = options.Commitish
+	}
+
+	args = append(args, dir)
+
+	if commitish != "" {
+		args = append(args, commitish)
+	}
+
+	_, stderr, err := git.commandExecutor.Execute(git.binPath, args, git.env, "")
+	if err != nil {
+		return nil, newGitError(err, nil, stderr)
+	}
+
+	return git.withDir(dir), nil
+}
+
+// ListWorktrees returns the main working directory and all linked worktrees.
+func (git *Git) ListWorktrees() ([]*GitWorktree, error) {
+	stdout, stderr, err := git.commandExecutor.Execute(
+		git.binPath,
+		[]string{"-C", git.dir, "worktree", "list", "--porcelain"},
+		git.env,
+		"",
+	)
+	if err != nil {
+		return nil, newGitError(err, nil, stderr)
+	}
+
+	worktrees := make([]*GitWorktree, 0)
+
+	var worktree *GitWorktree
+
+	for _, line := range strings.Split(string(stdout), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" {
+			worktree = nil
+			continue
+		}
+
+		parts := strings.SplitN(line, " ", 2)
+		value := ""
+		if len(parts) > 1 {
+			value = parts[1]
+		}
+
+		if parts[0] == "worktree" {
+			worktree = &GitWorktree{
+				Path: value,
+				Git:  git.withDir(value),
+			}
+			worktrees = append(worktrees, worktree)
+
+			continue
+		}
+
+		if worktree == nil {
+			continue
+		}
+
+		switch parts[0] {
+		case "HEAD":
+			worktree.Head = value
+		case "branch":
+			worktree.Branch = value
+		case "bare":
+			worktree.Bare = true
+		case "detached":
+			worktree.Detached = true
+		case "locked":
+			worktree.Locked = true
+		case "prunable":
+			worktree.Prunable = true
+		}
+	}
+
+	return worktrees, nil
+}
+
+// RemoveWorktree removes the worktree in `dir`.
+// Unless `force` is true, worktrees with uncommitted changes are not removed.
+func (git *Git) RemoveWorktree(dir string, force bool) error {
+	args := []string{"-C", git.dir, "worktree", "remove"}
+	if force {
+		args = append(args, "--force")
+	}
+
+	args = append(args, dir)
+
+	_, stderr, err := git.commandExecutor.Execute(git.binPath, args, git.env, "")
+	if err != nil {
+		return newGitError(err, nil, stderr)
+	}
+
+	return nil
+}
+
+// PruneWorktrees removes the administrative files of worktrees whose directories were deleted.
+func (git *Git) PruneWorktrees() error {
+	_, stderr, err := git.commandExecutor.Execute(
+		git.binPath,
+		[]string{"-C", git.dir, "worktree", "prune"},
+		git.env,
+		"",
+	)
+	if err != nil {
+		return newGitError(err, nil, stderr)
+	}
+
+	return nil
+}
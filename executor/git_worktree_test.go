@@ -0,0 +1,123 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sumup-oss/go-pkgs/os/ostest"
+)
+
+func TestGit_AddWorktree(t *testing.T) {
+	t.Run("it returns git bound to the worktree dir with the same env", func(t *testing.T) {
+		t.Parallel()
+
+		osExecutor := ostest.NewFakeOsExecutor(t)
+		envArg := []string{"GIT_SSH_COMMAND=ssh -i /tmp/id_rsa"}
+		gitInstance := NewGit(osExecutor, "ssh://example.com/repo.git", "/tmp/repo", envArg)
+
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{"-C", "/tmp/repo", "worktree", "add", "-b", "build-v2", "../repo-v2", "origin/v2"},
+			[]string{"GIT_SSH_COMMAND=ssh -i /tmp/id_rsa", "LC_ALL=C"},
+			"",
+		).Return([]byte{}, []byte{}, nil)
+
+		actual, actualErr := gitInstance.AddWorktree(
+			"../repo-v2",
+			&GitWorktreeOptions{Commitish: "origin/v2", Branch: "build-v2"},
+		)
+		require.Nil(t, actualErr)
+		assert.Equal(t, "/tmp/repo-v2", actual.dir)
+		assert.Equal(t, []string{"GIT_SSH_COMMAND=ssh -i /tmp/id_rsa", "LC_ALL=C"}, actual.env)
+		assert.Equal(t, "ssh://example.com/repo.git", actual.GetURL())
+		assert.Equal(t, osExecutor, actual.commandExecutor)
+	})
+
+	t.Run("when adding fails, it returns error", func(t *testing.T) {
+		t.Parallel()
+
+		osExecutor := ostest.NewFakeOsExecutor(t)
+		gitInstance := NewGit(osExecutor, "", "/tmp/repo", nil)
+
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{"-C", "/tmp/repo", "worktree", "add", "/tmp/repo-v2"},
+			cLocaleEnvArg,
+			"",
+		).Return([]byte{}, []byte("fatal: '/tmp/repo-v2' already exists"), errors.New("exit status 128"))
+
+		actual, actualErr := gitInstance.AddWorktree("/tmp/repo-v2", nil)
+		require.NotNil(t, actualErr)
+		assert.Nil(t, actual)
+		assert.Contains(t, actualErr.Error(), "already exists")
+	})
+}
+
+func TestGit_ListWorktrees(t *testing.T) {
+	t.Run("it parses the porcelain output", func(t *testing.T) {
+		t.Parallel()
+
+		osExecutor := ostest.NewFakeOsExecutor(t)
+		gitInstance := NewGit(osExecutor, "", "/tmp/repo", nil)
+
+		osExecutor.On(
+			"Execute",
+			"git",
+			[]string{"-C", "/tmp/repo", "worktree", "list", "--porcelain"},
+			cLocaleEnvArg,
+			"",
+		).Return(
+			[]byte(
+				"worktree /tmp/repo\n"+
+					"HEAD abc123\n"+
+					"branch refs/heads/main\n"+
+					"\n"+
+					"worktree /tmp/repo-v2\n"+
+					"HEAD def456\n"+
+					"detached\n"+
+					"locked building\n"+
+					"prunable gitdir file points to non-existent location\n"+
+					"\n",
+			),
+			[]byte{},
+			nil,
+		)
+
+		actual, actualErr := gitInstance.ListWorktrees()
+		require.Nil(t, actualErr)
+		require.Len(t, actual, 2)
+
+		assert.Equal(t, "/tmp/repo", actual[0].Path)
+		assert.Equal(t, "abc123", actual[0].Head)
+		assert.Equal(t, "refs/heads/main", actual[0].Branch)
+		assert.False(t, actual[0].Detached)
+		assert.Equal(t, "/tmp/repo", actual[0].Git.dir)
+
+		assert.Equal(t, "/tmp/repo-v2", actual[1].Path)
+		assert.Equal(t, "def456", actual[1].Head)
+		assert.Equal(t, "", actual[1].Branch)
+		assert.True(t, actual[1].Detached)
+		assert.True(t, actual[1].Locked)
+		assert.True(t, actual[1].Prunable)
+		assert.Equal(t, "/tmp/repo-v2", actual[1].Git.dir)
+	})
+}
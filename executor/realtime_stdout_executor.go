@@ -18,10 +18,13 @@ import (
 	"bytes"
 	"io"
 
+	"github.com/palantir/stacktrace"
+
 	"github.com/sumup-oss/go-pkgs/os"
 )
 
 var _ os.OsExecutor = (*RealtimeStdoutExecutor)(nil)
+var _ os.StdinExecutor = (*RealtimeStdoutExecutor)(nil)
 
 // RealtimeStdoutExecutor is os.OsExecutor decorator, that decorates the Execute method by writing
 // executed commands stdout and stderr to executor's Stdout and Stderr.
@@ -51,6 +54,28 @@ func (executor *RealtimeStdoutExecutor) Execute(
 	return stdout.Bytes(), stderr.Bytes(), err
 }
 
+// ExecuteWithStdin executes a command with `stdin` as its standard input.
+// The output is written to executor's Stdout and Stderr once the command exits.
+func (executor *RealtimeStdoutExecutor) ExecuteWithStdin(
+	cmd string,
+	arg []string,
+	env []string,
+	dir string,
+	stdin io.Reader,
+) ([]byte, []byte, error) {
+	stdinExecutor, ok := executor.OsExecutor.(os.StdinExecutor)
+	if !ok {
+		return nil, nil, stacktrace.NewError("os executor does not support stdin")
+	}
+
+	stdout, stderr, err := stdinExecutor.ExecuteWithStdin(cmd, arg, env, dir, stdin)
+
+	_, _ = executor.Stdout().Write(stdout)
+	_, _ = executor.Stderr().Write(stderr)
+
+	return stdout, stderr, err
+}
+
 // BufferedWriter is a writer that decorates an writer, by buffering a copy of all written bytes.
 type BufferedWriter struct {
 	writer io.Writer
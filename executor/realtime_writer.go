@@ -50,7 +50,7 @@ func (writer *RealtimeWriter) Write(p []byte) (n int, err error) {
 func (writer *RealtimeWriter) log(p []byte) {
 	for _, b := range p {
 		if b == '\n' {
-			writer.logger.Logf(writer.logLevel, writer.logBuffer.String())
+			writer.logger.Logf(writer.logLevel, "%s", writer.logBuffer.String())
 			writer.logBuffer.Reset()
 			continue
 		}
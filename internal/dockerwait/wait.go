@@ -0,0 +1,105 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// NOTE: Shared by executor and testutils, which can't import executor without an import cycle.
+package dockerwait
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os/exec"
+	"time"
+
+	"github.com/sumup-oss/go-pkgs/task"
+)
+
+const DefaultLogsTail = 50
+
+// LogsFunc returns the stdout and stderr of the last `tail` lines of the logs of `container`.
+type LogsFunc func(container string, tail int) (string, string, error)
+
+type Options struct {
+	Timeout  time.Duration
+	Interval time.Duration
+	// LogsTail is the number of lines of the logs returned when the container is not healthy,
+	// DefaultLogsTail when zero.
+	LogsTail int
+	// Logs reads the logs of the container.
+	Logs LogsFunc
+}
+
+// Wait calls `check` every `options.Interval` until it returns nil or an error that's not retryable,
+// or `options.Timeout` elapses, in which case the error is a *task.DeadlineRetryError.
+// `check` returns errors made with task.NewRetryableError while the container may still become healthy.
+// When the container is not healthy, Wait also returns the last lines of its logs.
+func Wait(container string, options *Options, check func() error) (string, error) {
+	err := task.RetryWithDeadline(
+		options.Timeout,
+		options.Interval,
+		func(cancel <-chan struct{}) error {
+			return check()
+		},
+	)(nil)
+	if err == nil {
+		return "", nil
+	}
+
+	return lastLogs(container, options), err
+}
+
+// Dial returns a retryable error while `address` does not accept TCP connections.
+func Dial(address string, timeout time.Duration) error {
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return task.NewRetryableError(err)
+	}
+
+	return conn.Close()
+}
+
+// CommandLogs returns a LogsFunc executing `docker logs` with `env`, e.g to select the daemon with DOCKER_HOST.
+func CommandLogs(env []string) LogsFunc {
+	return func(container string, tail int) (string, string, error) {
+		var stdout, stderr bytes.Buffer
+
+		cmd := exec.Command("docker", "logs", fmt.Sprintf("--tail=%d", tail), container)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		cmd.Env = env
+
+		err := cmd.Run()
+		return stdout.String(), stderr.String(), err
+	}
+}
+
+// lastLogs returns the last lines of the container's logs,
+// or the reason they could not be read, since they're only used for error messages.
+func lastLogs(container string, options *Options) string {
+	if options.Logs == nil {
+		return ""
+	}
+
+	tail := options.LogsTail
+	if tail == 0 {
+		tail = DefaultLogsTail
+	}
+
+	stdout, stderr, err := options.Logs(container, tail)
+	if err != nil {
+		return fmt.Sprintf("<failed to read logs: %s>", err)
+	}
+
+	return stdout + stderr
+}
@@ -0,0 +1,124 @@
+// Copyright 2019 SumUp Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dockerwait
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sumup-oss/go-pkgs/task"
+)
+
+func TestWait(t *testing.T) {
+	t.Run("when the check passes, it returns nil without reading the logs", func(t *testing.T) {
+		checkCalls := 0
+
+		actualLogs, err := Wait(
+			"git-server",
+			&Options{
+				Timeout:  time.Second,
+				Interval: time.Millisecond,
+				Logs: func(container string, tail int) (string, string, error) {
+					t.Errorf("unexpected logs of %s", container)
+					return "", "", nil
+				},
+			},
+			func() error {
+				checkCalls++
+				if checkCalls < 3 {
+					return task.NewRetryableError(errors.New("not ready"))
+				}
+
+				return nil
+			},
+		)
+		require.Nil(t, err)
+		assert.Equal(t, "", actualLogs)
+		assert.Equal(t, 3, checkCalls)
+	})
+
+	t.Run("when the check does not pass within the timeout, it returns deadline error and the logs", func(t *testing.T) {
+		actualLogs, err := Wait(
+			"git-server",
+			&Options{
+				Timeout:  20 * time.Millisecond,
+				Interval: time.Millisecond,
+				Logs: func(container string, tail int) (string, string, error) {
+					assert.Equal(t, "git-server", container)
+					assert.Equal(t, DefaultLogsTail, tail)
+
+					return "starting sshd\n", "sshd: no hostkeys available\n", nil
+				},
+			},
+			func() error {
+				return task.NewRetryableError(errors.New("not ready"))
+			},
+		)
+		require.NotNil(t, err)
+
+		var deadlineErr *task.DeadlineRetryError
+		require.True(t, errors.As(err, &deadlineErr))
+		assert.Equal(t, "starting sshd\nsshd: no hostkeys available\n", actualLogs)
+	})
+
+	t.Run("when the logs can't be read, it returns the reason instead", func(t *testing.T) {
+		actualLogs, err := Wait(
+			"git-server",
+			&Options{
+				Timeout:  time.Second,
+				Interval: time.Millisecond,
+				LogsTail: 5,
+				Logs: func(container string, tail int) (string, string, error) {
+					assert.Equal(t, 5, tail)
+
+					return "", "", errors.New("no such container")
+				},
+			},
+			func() error {
+				return errors.New("exited")
+			},
+		)
+		require.NotNil(t, err)
+		assert.Equal(t, "<failed to read logs: no such container>", actualLogs)
+	})
+}
+
+func TestDial(t *testing.T) {
+	t.Run("when the address accepts connections, it returns nil", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.Nil(t, err)
+		defer listener.Close()
+
+		err = Dial(listener.Addr().String(), time.Second)
+		assert.Nil(t, err)
+	})
+
+	t.Run("when the address does not accept connections, it returns retryable error", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.Nil(t, err)
+
+		address := listener.Addr().String()
+		require.Nil(t, listener.Close())
+
+		err = Dial(address, time.Second)
+		require.NotNil(t, err)
+		assert.True(t, task.IsRetryableError(err))
+	})
+}
@@ -31,6 +31,9 @@ import (
 // Compile-time proof of interfaces implementation.
 var _ OsExecutor = (*RealOsExecutor)(nil)
 var _ CommandExecutor = (*RealOsExecutor)(nil)
+var _ StdinExecutor = (*RealOsExecutor)(nil)
+var _ StreamsExecutor = (*RealOsExecutor)(nil)
+var _ FileReadWriter = (*RealOsExecutor)(nil)
 var _ EnvProvider = (*RealOsExecutor)(nil)
 var _ IOStreamsProvider = (*RealOsExecutor)(nil)
 
@@ -119,6 +122,31 @@ func (ex *RealOsExecutor) ExecuteWithStreams(
 	dir string,
 	stdout io.Writer,
 	stderr io.Writer,
+) error {
+	return ex.run(cmd, arg, env, dir, nil, stdout, stderr)
+}
+
+func (ex *RealOsExecutor) ExecuteWithStdin(
+	cmd string,
+	arg []string,
+	env []string,
+	dir string,
+	stdin io.Reader,
+) ([]byte, []byte, error) {
+	var stdout, stderr bytes.Buffer
+	err := ex.run(cmd, arg, env, dir, stdin, &stdout, &stderr)
+
+	return stdout.Bytes(), stderr.Bytes(), err
+}
+
+func (ex *RealOsExecutor) run(
+	cmd string,
+	arg []string,
+	env []string,
+	dir string,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
 ) error {
 	command := execCommand(cmd, arg...)
 
@@ -126,6 +154,7 @@ func (ex *RealOsExecutor) ExecuteWithStreams(
 		command.Env = env
 	}
 
+	command.Stdin = stdin
 	command.Stdout = stdout
 	command.Stderr = stderr
 	command.Dir = dir
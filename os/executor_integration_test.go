@@ -21,6 +21,7 @@ import (
 	"github.com/sumup-oss/go-pkgs/testutils"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 )
 
@@ -150,3 +151,27 @@ func TestRealOsExecutor_IsDir(t *testing.T) {
 		},
 	)
 }
+
+func TestRealOsExecutor_ExecuteWithStdin(t *testing.T) {
+	t.Run(
+		"it passes stdin to the command",
+		func(t *testing.T) {
+			if runtime.GOOS == "windows" {
+				t.Skip("Not supported OS")
+			}
+
+			osExecutor := &RealOsExecutor{}
+
+			actualStdout, actualStderr, actualErr := osExecutor.ExecuteWithStdin(
+				"cat",
+				nil,
+				nil,
+				"",
+				strings.NewReader("secret"),
+			)
+			require.Nil(t, actualErr)
+			assert.Equal(t, "secret", string(actualStdout))
+			assert.Equal(t, "", string(actualStderr))
+		},
+	)
+}
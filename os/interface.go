@@ -60,6 +60,22 @@ type CommandExecutor interface {
 	Execute(cmd string, arg []string, env []string, dir string) ([]byte, []byte, error)
 }
 
+// StreamsExecutor executes commands writing their stdout and stderr to `stdout` and `stderr` as they run.
+type StreamsExecutor interface {
+	ExecuteWithStreams(cmd string, arg []string, env []string, dir string, stdout io.Writer, stderr io.Writer) error
+}
+
+// StdinExecutor executes commands with `stdin` as their standard input,
+// e.g to pass secrets without exposing them in the command's arguments.
+type StdinExecutor interface {
+	ExecuteWithStdin(cmd string, arg []string, env []string, dir string, stdin io.Reader) ([]byte, []byte, error)
+}
+
+type FileReadWriter interface {
+	ReadFile(filename string) ([]byte, error)
+	WriteFile(path string, data []byte, perm os.FileMode) error
+}
+
 type EnvProvider interface {
 	Getenv(key string) string
 	GetOS() string
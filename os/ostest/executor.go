@@ -16,6 +16,7 @@ package ostest
 
 import (
 	"io"
+	"io/ioutil"
 	stdOs "os"
 	"os/user"
 	"testing"
@@ -26,6 +27,7 @@ import (
 )
 
 var _ os.OsExecutor = (*FakeOsExecutor)(nil)
+var _ os.StdinExecutor = (*FakeOsExecutor)(nil)
 
 type FakeOsExecutor struct {
 	mock.Mock
@@ -75,6 +77,40 @@ func (f *FakeOsExecutor) Execute(
 	return returnStdout, returnStderr, returnErr
 }
 
+// ExecuteWithStdin reads `stdin` and matches its content as a string, instead of the reader.
+func (f *FakeOsExecutor) ExecuteWithStdin(
+	cmd string,
+	arg []string,
+	env []string,
+	dir string,
+	stdin io.Reader,
+) ([]byte, []byte, error) {
+	var stdinContent string
+	if stdin != nil {
+		content, err := ioutil.ReadAll(stdin)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		stdinContent = string(content)
+	}
+
+	args := f.Called(cmd, arg, env, dir, stdinContent)
+	rawStdout := args.Get(0)
+	rawStderr := args.Get(1)
+	returnErr := args.Error(2)
+
+	var returnStdout, returnStderr []byte
+	if rawStdout != nil {
+		returnStdout = rawStdout.([]byte)
+	}
+	if rawStderr != nil {
+		returnStderr = rawStderr.([]byte)
+	}
+
+	return returnStdout, returnStderr, returnErr
+}
+
 func (f *FakeOsExecutor) MkdirAll(dirname string, perm stdOs.FileMode) error {
 	args := f.Called(dirname, perm)
 	return args.Error(0)
@@ -32,6 +32,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"golang.org/x/crypto/ssh"
 
+	"github.com/sumup-oss/go-pkgs/internal/dockerwait"
 	"github.com/sumup-oss/go-pkgs/template"
 
 	"github.com/palantir/stacktrace"
@@ -422,21 +423,19 @@ func (s *FakeGitServer) PrepareGitServerWithArgs() error {
 		return err
 	}
 
-	// NOTE: Check 15 times with interval 1 second,
-	// for healthiness of previously started git server.
-	isHealthy := false
 	log.Printf("Waiting for GIT %s to be healthy\n", DockerImage)
 
-	for i := 0; i < 15; i++ {
-		if s.IsGitHealthy() {
-			isHealthy = true
-			break
-		}
-		time.Sleep(1 * time.Second)
-	}
-
-	if !isHealthy {
-		return fmt.Errorf("GIT %s still not healthy after 15 attempts", DockerImage)
+	// NOTE: Check every second for 15 seconds,
+	// for healthiness of previously started git server.
+	logs, err := dockerwait.Wait(
+		s.dockerContainerName,
+		&dockerwait.Options{Timeout: 15 * time.Second, Interval: time.Second, Logs: dockerwait.CommandLogs(s.dockerEnv)},
+		func() error {
+			return dockerwait.Dial(s.address(), time.Second)
+		},
+	)
+	if err != nil {
+		return stacktrace.Propagate(err, "GIT %s not healthy. Logs: %s", DockerImage, logs)
 	}
 
 	log.Printf("GIT %s is healthy\n", DockerImage)
@@ -453,11 +452,9 @@ func (s *FakeGitServer) StopGitServer() error {
 }
 
 func (s *FakeGitServer) IsGitHealthy() bool {
-	conn, err := net.Dial("tcp", fmt.Sprintf("%s:%d", s.Host, s.Port))
-	if err != nil {
-		return false
-	}
-	defer conn.Close()
+	return dockerwait.Dial(s.address(), time.Second) == nil
+}
 
-	return true
+func (s *FakeGitServer) address() string {
+	return net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
 }